@@ -15,9 +15,13 @@ func main() {
 		ServerAddress: ":8080",
 		LoaderFilePath: "docs/db/vehicles_100.json",
 	}
-	app := application.NewServerChi(cfg)
+	app, err := application.NewServerChi(cfg)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
 	// - run
-	if err := app.Run(); err != nil {
+	if err = app.Run(); err != nil {
 		fmt.Println(err)
 		return
 	}
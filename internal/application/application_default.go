@@ -1,29 +1,90 @@
 package application
 
 import (
+	"app/internal"
 	"app/internal/handler"
+	"app/internal/importer"
 	"app/internal/loader"
 	"app/internal/repository"
 	"app/internal/service"
+	"app/internal/validator"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
 
+var (
+	// ErrStorerUnsupported is returned when the vehicles must be written back to a file whose loader cannot store them
+	ErrStorerUnsupported = errors.New("application: the loader of LoaderFilePath cannot store vehicles")
+	// ErrUnknownStorerMode is returned when StorerMode is not one of the storer modes
+	ErrUnknownStorerMode = errors.New("application: unknown storer mode")
+	// ErrSourceChanged is returned when the vehicles would be stored over a source that changed since it was last loaded
+	ErrSourceChanged = errors.New("application: the source changed since it was loaded, it is not overwritten")
+)
+
+const (
+	// StorerModeNone never writes the vehicles back to the file
+	StorerModeNone = ""
+	// StorerModeWrite writes the vehicles back to the file after every write
+	StorerModeWrite = "write"
+	// StorerModePeriodic writes the vehicles back to the file every StorerInterval and on shutdown
+	StorerModePeriodic = "periodic"
+	// StorerModeShutdown writes the vehicles back to the file on shutdown
+	StorerModeShutdown = "shutdown"
+)
+
 // ConfigServerChi is a struct that represents the configuration for ServerChi
 type ConfigServerChi struct {
 	// ServerAddress is the address where the server will be listening
 	ServerAddress string
-	// LoaderFilePath is the path to the file that contains the vehicles
+	// LoaderFilePath is the path to the file, directory or uri that contains the vehicles
+	// the loader is chosen by its extension or scheme, see loader.New
 	LoaderFilePath string
+	// CSVFormat is the delimiter and the header mapping of the CSV files loaded, commas and CSVColumns by default
+	CSVFormat loader.CSVFormat
+	// StorerMode is the moment the vehicles are written back to LoaderFilePath
+	StorerMode string
+	// StorerInterval is the interval between writes when StorerMode is StorerModePeriodic
+	StorerInterval time.Duration
+	// LogFilePath is the path to the operation log replayed on top of LoaderFilePath, empty disables the log
+	LogFilePath string
+	// LogMaxSize is the size in bytes of the operation log that triggers its compaction into LoaderFilePath
+	LogMaxSize int64
+	// DuplicatePolicy is what happens to the loaded vehicles with duplicate ids or registrations
+	DuplicatePolicy loader.DuplicatePolicy
+	// ValidationMode is what happens to the loaded vehicles with errors, see the validator modes
+	ValidationMode string
+	// ValidationRules are the rules the loaded vehicles are validated with, nil uses validator.DefaultRules
+	ValidationRules []validator.Rule
+	// ValidationReportPath is the path where the validation report is written as JSON, empty skips it
+	ValidationReportPath string
+	// QuarantineFilePath is the path where the quarantined vehicles are written as JSON, empty skips it
+	QuarantineFilePath string
+	// ReloadInterval is the interval between two checks of LoaderFilePath for changes, zero disables the checks
+	// the vehicles can always be reloaded through POST /admin/reload
+	ReloadInterval time.Duration
 }
 
 // NewServerChi is a function that returns a new instance of ServerChi
-func NewServerChi(cfg *ConfigServerChi) *ServerChi {
+// it fails when the storer mode, the validation mode, the duplicate policy or the CSV format of cfg are not valid
+func NewServerChi(cfg *ConfigServerChi) (srv *ServerChi, err error) {
 	// default values
 	defaultConfig := &ConfigServerChi{
-		ServerAddress: ":8080",
+		ServerAddress:  ":8080",
+		StorerMode:     StorerModeNone,
+		StorerInterval: time.Minute,
+		LogMaxSize:     1 << 20,
+		ValidationMode: validator.ModeAccept,
 	}
 	if cfg != nil {
 		if cfg.ServerAddress != "" {
@@ -32,12 +93,72 @@ func NewServerChi(cfg *ConfigServerChi) *ServerChi {
 		if cfg.LoaderFilePath != "" {
 			defaultConfig.LoaderFilePath = cfg.LoaderFilePath
 		}
+		if cfg.StorerMode != "" {
+			defaultConfig.StorerMode = cfg.StorerMode
+		}
+		if cfg.StorerInterval > 0 {
+			defaultConfig.StorerInterval = cfg.StorerInterval
+		}
+		if cfg.LogFilePath != "" {
+			defaultConfig.LogFilePath = cfg.LogFilePath
+		}
+		if cfg.LogMaxSize > 0 {
+			defaultConfig.LogMaxSize = cfg.LogMaxSize
+		}
+		defaultConfig.CSVFormat = cfg.CSVFormat
+		defaultConfig.DuplicatePolicy = cfg.DuplicatePolicy
+		if cfg.ValidationMode != "" {
+			defaultConfig.ValidationMode = cfg.ValidationMode
+		}
+		if cfg.ValidationRules != nil {
+			defaultConfig.ValidationRules = cfg.ValidationRules
+		}
+		if cfg.ValidationReportPath != "" {
+			defaultConfig.ValidationReportPath = cfg.ValidationReportPath
+		}
+		if cfg.QuarantineFilePath != "" {
+			defaultConfig.QuarantineFilePath = cfg.QuarantineFilePath
+		}
+		if cfg.ReloadInterval > 0 {
+			defaultConfig.ReloadInterval = cfg.ReloadInterval
+		}
+	}
+
+	// configuration
+	switch defaultConfig.StorerMode {
+	case StorerModeNone, StorerModeWrite, StorerModePeriodic, StorerModeShutdown:
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownStorerMode, defaultConfig.StorerMode)
+	}
+	err = validator.ValidateMode(defaultConfig.ValidationMode)
+	if err != nil {
+		return
+	}
+	err = defaultConfig.DuplicatePolicy.Validate()
+	if err != nil {
+		return
+	}
+	err = defaultConfig.CSVFormat.Validate()
+	if err != nil {
+		return
 	}
 
-	return &ServerChi{
+	srv = &ServerChi{
 		serverAddress:  defaultConfig.ServerAddress,
 		loaderFilePath: defaultConfig.LoaderFilePath,
+		csvFormat:      defaultConfig.CSVFormat,
+		storerMode:     defaultConfig.StorerMode,
+		storerInterval: defaultConfig.StorerInterval,
+		logFilePath:    defaultConfig.LogFilePath,
+		logMaxSize:     defaultConfig.LogMaxSize,
+		duplicates:     defaultConfig.DuplicatePolicy,
+		validationMode: defaultConfig.ValidationMode,
+		validator:      validator.NewVehicleRules(defaultConfig.ValidationRules),
+		reportPath:     defaultConfig.ValidationReportPath,
+		quarantinePath: defaultConfig.QuarantineFilePath,
+		reloadInterval: defaultConfig.ReloadInterval,
 	}
+	return
 }
 
 // ServerChi is a struct that implements the Application interface
@@ -46,25 +167,136 @@ type ServerChi struct {
 	serverAddress string
 	// loaderFilePath is the path to the file that contains the vehicles
 	loaderFilePath string
+	// csvFormat is the delimiter and the header mapping of the CSV files loaded
+	csvFormat loader.CSVFormat
+	// storerMode is the moment the vehicles are written back to loaderFilePath
+	storerMode string
+	// storerInterval is the interval between writes when storerMode is StorerModePeriodic
+	storerInterval time.Duration
+	// logFilePath is the path to the operation log, empty disables the log
+	logFilePath string
+	// logMaxSize is the size in bytes of the operation log that triggers its compaction
+	logMaxSize int64
+	// duplicates is what happens to the loaded vehicles with duplicate ids or registrations
+	duplicates loader.DuplicatePolicy
+	// validationMode is what happens to the loaded vehicles with errors
+	validationMode string
+	// validator is the validator the loaded vehicles are validated with
+	validator internal.VehicleValidator
+	// reportPath is the path where the validation report is written
+	reportPath string
+	// quarantinePath is the path where the quarantined vehicles are written
+	quarantinePath string
+	// reloadInterval is the interval between two checks of loaderFilePath for changes
+	reloadInterval time.Duration
 }
 
 // Run is a method that runs the application
 func (a *ServerChi) Run() (err error) {
 	// dependencies
 	// - loader
-	ld := loader.NewVehicleJSONFile(a.loaderFilePath)
-	db, err := ld.Load()
+	err = loader.SetCSVFormat(a.csvFormat)
+	if err != nil {
+		return
+	}
+	ld, err := loader.New(a.loaderFilePath)
+	if err != nil {
+		return
+	}
+	if pr, ok := ld.(loader.ProgressReporter); ok {
+		pr.OnProgress(func(p loader.Progress) {
+			log.Println("load vehicles:", p)
+		})
+	}
+	// - storer
+	st, ok := ld.(internal.VehicleStorer)
+	if !ok && (a.storerMode != StorerModeNone || a.logFilePath != "") {
+		err = ErrStorerUnsupported
+		return
+	}
+	// - source
+	src := newVehicleSource(a.loaderFilePath, func() (map[int]internal.Vehicle, error) {
+		return a.load(ld)
+	}, st)
+	db, err := src.load()
 	if err != nil {
 		return
 	}
+	// - log
+	var lg *loader.VehicleLogFile
+	if a.logFilePath != "" {
+		lg = loader.NewVehicleLogFile(a.logFilePath)
+		defer lg.Close()
+		err = lg.Replay(db)
+		if err != nil {
+			return
+		}
+	}
 	// - repository
-	rp := repository.NewVehicleMap(db)
+	var rp internal.VehicleRepository = repository.NewVehicleMap(db)
+	if lg != nil {
+		rp = repository.NewVehicleLogged(rp, lg, src, a.logMaxSize)
+	}
+	if a.storerMode == StorerModeWrite {
+		rp = repository.NewVehicleStored(rp, src)
+	}
+	src.rp = rp
+	// - signals, the server and the imports stop once ctx is done
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 	// - service
-	sv := service.NewVehicleDefault(rp)
+	sv := service.NewVehicleDefault(rp, a.validator)
 	// - handler
 	hd := handler.NewVehicleDefault(sv)
+	ad := handler.NewAdminDefault(src)
+	ih := handler.NewImportDefault(importer.NewImportDefault(ctx, sv))
 	// router
-	rt := chi.NewRouter()
+	rt := routes(hd, ad, ih)
+
+	// run server
+	// - periodic stores, stopped is closed once the last one is done
+	stopped := make(chan struct{})
+	if a.storerMode == StorerModePeriodic {
+		go func() {
+			defer close(stopped)
+			a.storePeriodically(ctx, rp, src)
+		}()
+	} else {
+		close(stopped)
+	}
+	if a.reloadInterval > 0 {
+		go src.Watch(ctx, a.reloadInterval)
+	}
+
+	// - shutdown, drained is closed once every request in flight has finished
+	srv := &http.Server{Addr: a.serverAddress, Handler: rt}
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		<-ctx.Done()
+		if err := srv.Shutdown(context.Background()); err != nil {
+			log.Println("shutdown server:", err)
+		}
+	}()
+
+	err = srv.ListenAndServe()
+	if !errors.Is(err, http.ErrServerClosed) {
+		return
+	}
+	err = nil
+	<-drained
+	<-stopped
+
+	// store on shutdown, no write can happen anymore
+	if a.storerMode == StorerModePeriodic || a.storerMode == StorerModeShutdown {
+		err = store(rp, src)
+	}
+	return
+}
+
+// routes is a function that returns the router of the endpoints of the handlers
+func routes(hd *handler.VehicleDefault, ad *handler.AdminDefault, ih *handler.ImportDefault) (rt *chi.Mux) {
+	rt = chi.NewRouter()
 	// - middlewares
 	rt.Use(middleware.Logger)
 	rt.Use(middleware.Recoverer)
@@ -77,8 +309,13 @@ func (a *ServerChi) Run() (err error) {
 		rt.Get("/brand/{brand}/between/{start_year}/{end_year}", hd.GetByBrandAndYearInterval())
 		rt.Get("/average_speed/brand/{brand}", hd.GetAverageSpeedByBrand())
 		rt.Post("/batch", hd.CreateSome())
+		rt.Post("/bulk", hd.Bulk())
+		rt.Get("/export", hd.Export())
 		rt.Put("/{id}/update_speed", hd.UpdateSpeed())
 		rt.Get("/fuel_type/{type}", hd.GetByFuelType())
+		rt.Get("/{id}", hd.GetById())
+		rt.Put("/{id}", hd.Replace())
+		rt.Patch("/{id}", hd.Patch())
 		rt.Delete("/{id}", hd.DeleteById())
 		rt.Get("/transmission/{type}", hd.GetByTransmissionType())
 		rt.Put("/{id}/update_fuel", hd.UpdateFuel())
@@ -91,7 +328,96 @@ func (a *ServerChi) Run() (err error) {
 		rt.Get("/", hd.GetByColorAndYear())
 	})
 
-	// run server
-	err = http.ListenAndServe(a.serverAddress, rt)
+	rt.Route("/imports", func(rt chi.Router) {
+		rt.Post("/", ih.Create())
+		rt.Get("/{id}", ih.Get())
+		rt.Delete("/{id}", ih.Cancel())
+	})
+
+	rt.Route("/admin", func(rt chi.Router) {
+		rt.Post("/reload", ad.Reload())
+	})
+
+	return
+}
+
+// load is a method that loads the vehicles of ld, deduplicating and validating them
+func (a *ServerChi) load(ld internal.VehicleLoader) (db map[int]internal.Vehicle, err error) {
+	dd := loader.NewVehicleDeduplicated(ld, a.duplicates)
+	db, err = dd.Load()
+	for _, d := range dd.Report() {
+		log.Println("duplicate vehicle:", d)
+	}
+	if rr, ok := dd.Unwrap().(loader.RowReporter); ok {
+		for _, e := range rr.Report() {
+			log.Println("invalid row:", e)
+		}
+	}
+	if err != nil {
+		return
+	}
+
+	db, err = a.validate(db)
+	return
+}
+
+// validate is a method that validates the loaded vehicles, returning the ones to keep
+// the report and the quarantined vehicles are written to their files when configured
+func (a *ServerChi) validate(db map[int]internal.Vehicle) (valid map[int]internal.Vehicle, err error) {
+	valid, quarantined, rp, err := validator.Check(a.validator, db, a.validationMode)
+	log.Println("validate vehicles:", rp)
+
+	// report
+	if a.reportPath != "" {
+		data, errReport := json.MarshalIndent(rp, "", "  ")
+		if errReport == nil {
+			errReport = os.WriteFile(a.reportPath, data, 0644)
+		}
+		if errReport != nil && err == nil {
+			err = errReport
+		}
+	}
+	if err != nil {
+		return
+	}
+
+	// quarantine
+	if a.quarantinePath != "" && len(quarantined) > 0 {
+		err = loader.NewVehicleJSONFile(a.quarantinePath).Store(quarantined)
+	}
+	return
+}
+
+// storePeriodically is a method that stores the vehicles every storerInterval until ctx is done
+func (a *ServerChi) storePeriodically(ctx context.Context, rp internal.VehicleRepository, st internal.VehicleStorer) {
+	ticker := time.NewTicker(a.storerInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// a changed source is already reported by the source itself
+			if err := store(rp, st); err != nil && !errors.Is(err, ErrSourceChanged) {
+				log.Println("store vehicles:", err)
+			}
+		}
+	}
+}
+
+// store is a function that stores the current state of the repository
+// repositories that store their own vehicles take the snapshot themselves, under the lock of their writes,
+// which also truncates the operation log
+func store(rp internal.VehicleRepository, st internal.VehicleStorer) (err error) {
+	if sn, ok := rp.(internal.VehicleSnapshotter); ok {
+		return sn.Snapshot()
+	}
+
+	v, err := rp.FindAll()
+	if err != nil {
+		return
+	}
+	err = st.Store(v)
 	return
 }
@@ -0,0 +1,291 @@
+package application
+
+import (
+	"app/internal"
+	"app/internal/handler"
+	"app/internal/importer"
+	"app/internal/loader"
+	"app/internal/repository"
+	"app/internal/service"
+	"app/internal/validator"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// reloaderReset is a reloader that puts back a fixed set of vehicles
+type reloaderReset struct {
+	rp internal.VehicleRepository
+	v  map[int]internal.Vehicle
+}
+
+func (r *reloaderReset) Reload() (n int, err error) {
+	return len(r.v), r.rp.Reset(r.v)
+}
+
+// newTestServer is a function that returns a server with the endpoints of the application over n vehicles
+func newTestServer(t *testing.T, n int) (srv *httptest.Server, rp internal.VehicleRepository) {
+	t.Helper()
+
+	db := make(map[int]internal.Vehicle)
+	for id := 1; id <= n; id++ {
+		db[id] = internal.Vehicle{Id: id, VehicleAttributes: testAttributes(id)}
+	}
+	seed := make(map[int]internal.Vehicle)
+	for id, vh := range db {
+		seed[id] = vh
+	}
+	rp = repository.NewVehicleMap(db)
+	sv := service.NewVehicleDefault(rp, validator.NewVehicleRules(nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	hd := handler.NewVehicleDefault(sv)
+	ad := handler.NewAdminDefault(&reloaderReset{rp: rp, v: seed})
+	ih := handler.NewImportDefault(importer.NewImportDefault(ctx, sv))
+
+	srv = httptest.NewServer(routes(hd, ad, ih))
+	t.Cleanup(srv.Close)
+	return
+}
+
+// testAttributes is a function that returns valid attributes, told apart by i
+func testAttributes(i int) internal.VehicleAttributes {
+	return internal.VehicleAttributes{
+		Brand:           []string{"Ford", "Fiat", "Honda"}[i%3],
+		Model:           "Model " + fmt.Sprint(i),
+		Registration:    fmt.Sprintf("REG%06d", i),
+		Color:           "Blue",
+		FabricationYear: 2000 + i%20,
+		Capacity:        1 + i%5,
+		MaxSpeed:        float64(100 + i%100),
+		FuelType:        []string{"gasoline", "diesel"}[i%2],
+		Transmission:    "manual",
+		Weight:          1000,
+		Dimensions:      internal.Dimensions{Height: 150, Length: 400, Width: 180},
+	}
+}
+
+// testVehicleJSON is a function that returns the JSON representation of valid attributes, told apart by i
+func testVehicleJSON(i int) string {
+	a := testAttributes(i)
+	return fmt.Sprintf(`{"brand":%q,"model":%q,"registration":%q,"color":%q,"year":%d,"passengers":%d,"max_speed":%g,"fuel_type":%q,"transmission":%q,"weight":%g,"height":%g,"length":%g,"width":%g}`,
+		a.Brand, a.Model, a.Registration, a.Color, a.FabricationYear, a.Capacity, a.MaxSpeed, a.FuelType, a.Transmission, a.Weight, a.Height, a.Length, a.Width)
+}
+
+// do is a function that sends a request and returns its status and body
+func do(t *testing.T, method, url, contentType, body string, header ...string) (status int, data []byte) {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer res.Body.Close()
+	data, err = io.ReadAll(res.Body)
+	if err != nil {
+		t.Error(err)
+	}
+	return res.StatusCode, data
+}
+
+// TestRoutes_Parallel hammers every endpoint from many goroutines at once; run it with -race
+func TestRoutes_Parallel(t *testing.T) {
+	srv, rp := newTestServer(t, 50)
+
+	const workers, rounds = 16, 10
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				n := w*rounds + i + 1000
+
+				// the vehicle of the worker, which may be deleted or reset by the others
+				status, data := do(t, http.MethodPost, srv.URL+"/vehicles", "application/json", testVehicleJSON(n))
+				if status != http.StatusCreated {
+					t.Errorf("create: status %d: %s", status, data)
+					return
+				}
+				var created struct {
+					Data handler.VehicleJSON `json:"data"`
+				}
+				if err := json.Unmarshal(data, &created); err != nil {
+					t.Errorf("create: %v", err)
+					return
+				}
+				id := created.Data.ID
+				shared := 1 + n%50
+
+				requests := []struct {
+					method, path, contentType, body string
+				}{
+					{http.MethodGet, "/vehicles/%d", "", ""},
+					{http.MethodPut, "/vehicles/%d/update_speed", "application/json", `{"speed": 150}`},
+					{http.MethodPut, "/vehicles/%d/update_fuel", "application/json", `{"fuel_type": "diesel"}`},
+					{http.MethodPatch, "/vehicles/%d", "application/merge-patch+json", `{"color": "Red"}`},
+					{http.MethodPatch, "/vehicles/%d", "application/json-patch+json", `[{"op": "replace", "path": "/color", "value": "Green"}]`},
+					{http.MethodPut, "/vehicles/%d", "application/json", testVehicleJSON(n)},
+					{http.MethodGet, "/vehicles?sort=-max_speed&limit=5", "", ""},
+					{http.MethodGet, "/vehicles?brand=Ford&year[gte]=2005", "", ""},
+					{http.MethodGet, "/vehicles/export?format=csv", "", ""},
+					{http.MethodGet, "/vehicles/average_speed/brand/Ford", "", ""},
+					{http.MethodGet, "/vehicles/average_capacity/brand/Fiat", "", ""},
+					{http.MethodGet, "/vehicles/brand/Honda/between/2000/2020", "", ""},
+					{http.MethodGet, "/vehicles/fuel_type/gasoline", "", ""},
+					{http.MethodGet, "/vehicles/transmission/manual", "", ""},
+					{http.MethodGet, "/vehicles/dimensions?length=0-1000&width=0-1000", "", ""},
+					{http.MethodGet, "/vehicles/weight?min=0&max=5000", "", ""},
+					{http.MethodGet, "/vehiclesc?color=Blue&year=2010", "", ""},
+					{http.MethodPost, "/vehicles/batch", "application/json", "[" + testVehicleJSON(n+100000) + "," + testVehicleJSON(n+200000) + "]"},
+					{http.MethodPost, "/vehicles/bulk", "application/json", fmt.Sprintf(`{"operations": [{"op": "create", "vehicle": %s}, {"op": "patch", "id": %d, "patch": {"max_speed": 120}}]}`, testVehicleJSON(n+300000), shared)},
+					{http.MethodPost, "/imports", "application/json", "[" + testVehicleJSON(n+400000) + "]"},
+					{http.MethodDelete, "/vehicles/%d", "", ""},
+				}
+				if i == rounds/2 {
+					requests = append(requests, struct{ method, path, contentType, body string }{http.MethodPost, "/admin/reload", "", ""})
+				}
+				for _, rq := range requests {
+					path := rq.path
+					if strings.Contains(path, "%d") {
+						path = fmt.Sprintf(path, id)
+					}
+					status, data := do(t, rq.method, srv.URL+path, rq.contentType, rq.body)
+					// the other workers may delete or reload the vehicles in between, anything else is a failure
+					if status >= http.StatusInternalServerError {
+						t.Errorf("%s %s: status %d: %s", rq.method, path, status, data)
+					}
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	// the repository is left consistent: every vehicle is stored under its own id
+	v, err := rp.FindAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, vh := range v {
+		if vh.Id != id || vh.Version < 1 {
+			t.Errorf("vehicle stored under %d: id %d version %d", id, vh.Id, vh.Version)
+		}
+	}
+}
+
+// TestRoutes_ParallelIfMatch checks that concurrent conditional writes on one vehicle never both succeed at a version
+func TestRoutes_ParallelIfMatch(t *testing.T) {
+	srv, rp := newTestServer(t, 1)
+
+	const writers = 20
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			status, data := do(t, http.MethodPut, srv.URL+"/vehicles/1/update_speed", "application/json",
+				fmt.Sprintf(`{"speed": %d}`, 100+w), "If-Match", `"1"`)
+			switch status {
+			case http.StatusOK:
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			case http.StatusPreconditionFailed:
+			default:
+				t.Errorf("status %d: %s", status, data)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if succeeded != 1 {
+		t.Errorf("%d writes succeeded at version 1, want 1", succeeded)
+	}
+	vh, err := rp.FindById(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vh.Version != 2 {
+		t.Errorf("version %d, want 2", vh.Version)
+	}
+}
+
+func TestNewServerChi(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *ConfigServerChi
+		err  error
+	}{
+		{"defaults", nil, nil},
+		{"every mode", &ConfigServerChi{StorerMode: StorerModePeriodic, ValidationMode: validator.ModeQuarantine}, nil},
+		{"unknown storer mode", &ConfigServerChi{StorerMode: "always"}, ErrUnknownStorerMode},
+		{"unknown validation mode", &ConfigServerChi{ValidationMode: "strict"}, validator.ErrUnknownMode},
+		{"unknown duplicate policy", &ConfigServerChi{DuplicatePolicy: loader.DuplicatePolicy{Id: "skip"}}, loader.ErrUnknownPolicy},
+		{"invalid csv format", &ConfigServerChi{CSVFormat: loader.CSVFormat{Delimiter: '"'}}, loader.ErrInvalidCSVFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, err := NewServerChi(tt.cfg)
+
+			if !errors.Is(err, tt.err) || (err == nil) != (srv != nil) {
+				t.Errorf("server %v error %v, want error %v", srv != nil, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestServerChi_Load(t *testing.T) {
+	// the rows skipped by a CSV file of a directory are logged with the name of the file
+	dir := t.TempDir()
+	data := "id,brand,year\n1,Ford,2001\n2,Fiat,two\n"
+	if err := os.WriteFile(filepath.Join(dir, "bad.csv"), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	srv, err := NewServerChi(&ConfigServerChi{LoaderFilePath: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ld, err := loader.New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var logs bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&logs)
+
+	db, err := srv.load(ld)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(db) != 1 || db[1].Brand != "Ford" {
+		t.Errorf("got %v", db)
+	}
+	if !strings.Contains(logs.String(), "invalid row: bad.csv: line 3: column year") {
+		t.Errorf("log %q", logs.String())
+	}
+}
@@ -0,0 +1,162 @@
+package application
+
+import (
+	"app/internal"
+	"app/internal/loader"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// newVehicleSource is a function that returns a new instance of vehicleSource
+// path is resolved as by the loader registry; the sources that are not on disk have no path and are not watched
+func newVehicleSource(path string, load func() (map[int]internal.Vehicle, error), st internal.VehicleStorer) *vehicleSource {
+	path, _ = loader.LocalPath(path)
+	return &vehicleSource{
+		path:      path,
+		load:      load,
+		st:        st,
+		signature: signature(path),
+	}
+}
+
+// vehicleSource is a struct that represents the source the vehicles are loaded from and stored to
+// it implements the VehicleReloader and VehicleStorer interfaces; the signature of the source is refreshed
+// after every store so that the writes of the server itself are not mistaken for a new file by Watch
+type vehicleSource struct {
+	// path is the path on disk of the source, empty when it is not on disk
+	path string
+	// load is the function that loads, deduplicates and validates the vehicles of the source
+	load func() (map[int]internal.Vehicle, error)
+	// st is the storer of the source, nil when the source cannot be stored
+	st internal.VehicleStorer
+	// rp is the repository the vehicles are reloaded into
+	rp internal.VehicleRepository
+
+	// reloading serializes the reloads
+	reloading sync.Mutex
+	// mu guards signature, failed and stores
+	mu sync.Mutex
+	// signature is the signature of the source as last loaded or stored
+	signature string
+	// failed is the signature of the source as last failed to reload, Watch retries once it changes again
+	failed string
+	// stores is the number of stores made, so a reload tells whether one refreshed the signature while it loaded
+	stores int
+}
+
+// Reload is a method that loads the vehicles of the source and swaps them into the repository
+// the repository keeps its vehicles when the source cannot be loaded or is rejected by the validation
+func (s *vehicleSource) Reload() (n int, err error) {
+	s.reloading.Lock()
+	defer s.reloading.Unlock()
+
+	// the signature is taken first so a change made while loading triggers another reload
+	s.mu.Lock()
+	sig, stores := signature(s.path), s.stores
+	s.mu.Unlock()
+	db, err := s.load()
+	if err == nil {
+		err = s.rp.Reset(db)
+	}
+	if err != nil {
+		s.mu.Lock()
+		s.failed = sig
+		s.mu.Unlock()
+		return
+	}
+
+	// a store made while loading already refreshed the signature after the file it wrote, which is newer
+	s.mu.Lock()
+	if s.stores == stores {
+		s.signature = sig
+	}
+	s.mu.Unlock()
+
+	n = len(db)
+	return
+}
+
+// Store is a method that stores the vehicles to the source
+// it refuses to overwrite a source that changed since it was last loaded or stored, which Watch is yet to reload
+func (s *vehicleSource) Store(v map[int]internal.Vehicle) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if signature(s.path) != s.signature {
+		err = fmt.Errorf("%w: %s", ErrSourceChanged, s.path)
+		log.Println("store vehicles:", err)
+		return
+	}
+	err = s.st.Store(v)
+	if err != nil {
+		return
+	}
+	s.signature = signature(s.path)
+	s.stores++
+	return
+}
+
+// Watch is a method that polls the source every interval, reloading it when it changes, until ctx is done
+// a source that failed to reload is only tried again once it changes; sources whose path cannot be read are not watched
+func (s *vehicleSource) Watch(ctx context.Context, interval time.Duration) {
+	if s.path == "" {
+		log.Println("watch vehicles: the source is not on disk, it is not watched")
+		return
+	}
+	if _, err := os.Stat(s.path); err != nil {
+		log.Println("watch vehicles: the source is not watched:", err)
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			sig := signature(s.path)
+			changed := sig != s.signature && sig != s.failed
+			s.mu.Unlock()
+			if !changed {
+				continue
+			}
+
+			n, err := s.Reload()
+			if err != nil {
+				log.Println("reload vehicles:", err)
+				continue
+			}
+			log.Println("reload vehicles:", n, "vehicles loaded")
+		}
+	}
+}
+
+// signature is a function that returns a value that changes whenever the file or directory at path changes
+// it is empty when path cannot be read
+func signature(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	if !info.IsDir() {
+		return fmt.Sprintf("%d:%d", info.Size(), info.ModTime().UnixNano())
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return ""
+	}
+	sig := ""
+	for _, entry := range entries {
+		sig += fmt.Sprintf("%s:%s;", entry.Name(), signature(filepath.Join(path, entry.Name())))
+	}
+	return sig
+}
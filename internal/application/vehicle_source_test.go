@@ -0,0 +1,313 @@
+package application
+
+import (
+	"app/internal"
+	"app/internal/loader"
+	"app/internal/repository"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestSource is a function that returns a source over a JSON file with the vehicles of data, and its repository
+func newTestSource(t *testing.T, data string) (src *vehicleSource, rp internal.VehicleRepository, path string) {
+	t.Helper()
+	path = filepath.Join(t.TempDir(), "vehicles.json")
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ld := loader.NewVehicleJSONFile(path)
+	src = newVehicleSource(path, ld.Load, ld)
+	db, err := src.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rp = repository.NewVehicleMap(db)
+	src.rp = rp
+	return
+}
+
+func TestVehicleSource_Reload(t *testing.T) {
+	t.Run("swaps the vehicles of the source in", func(t *testing.T) {
+		src, rp, path := newTestSource(t, `[{"id":1,"brand":"Ford"}]`)
+		if err := os.WriteFile(path, []byte(`[{"id":2,"brand":"Fiat"},{"id":3,"brand":"Kia"}]`), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		n, err := src.Reload()
+
+		if err != nil || n != 2 {
+			t.Fatalf("reloaded %d, %v; want 2", n, err)
+		}
+		v, _ := rp.FindAll()
+		if _, ok := v[1]; len(v) != 2 || ok || v[2].Brand != "Fiat" {
+			t.Errorf("got %v", v)
+		}
+		// ids keep growing from the ones assigned before
+		if vh, err := rp.Create(internal.VehicleAttributes{}); err != nil || vh.Id != 4 {
+			t.Errorf("created %d, %v; want 4", vh.Id, err)
+		}
+	})
+
+	t.Run("keeps the signature of a store made while loading", func(t *testing.T) {
+		src, _, path := newTestSource(t, `[{"id":1,"brand":"Ford"}]`)
+		load := src.load
+		src.load = func() (map[int]internal.Vehicle, error) {
+			db, err := load()
+			if err == nil {
+				err = src.Store(map[int]internal.Vehicle{1: db[1], 2: {Id: 2, Version: 1}})
+			}
+			return db, err
+		}
+
+		if _, err := src.Reload(); err != nil {
+			t.Fatal(err)
+		}
+
+		// Watch would take the file the server wrote for a change
+		if sig := signature(path); src.signature != sig {
+			t.Errorf("signature %q, want the one of the stored file %q", src.signature, sig)
+		}
+	})
+
+	t.Run("keeps the vehicles when the source cannot be loaded", func(t *testing.T) {
+		src, rp, path := newTestSource(t, `[{"id":1,"brand":"Ford"}]`)
+		if err := os.WriteFile(path, []byte(`[{"id":`), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := src.Reload()
+
+		if err == nil {
+			t.Errorf("malformed source reloaded")
+		}
+		if v, _ := rp.FindAll(); len(v) != 1 || v[1].Brand != "Ford" {
+			t.Errorf("got %v, want the vehicles of before", v)
+		}
+	})
+
+	t.Run("keeps the vehicles when the load is rejected", func(t *testing.T) {
+		_, rp, path := newTestSource(t, `[{"id":1,"brand":"Ford"}]`)
+		errRejected := errors.New("rejected")
+		src := newVehicleSource(path, func() (map[int]internal.Vehicle, error) { return nil, errRejected }, nil)
+		src.rp = rp
+
+		_, err := src.Reload()
+
+		if !errors.Is(err, errRejected) {
+			t.Errorf("error %v, want %v", err, errRejected)
+		}
+		if v, _ := rp.FindAll(); len(v) != 1 {
+			t.Errorf("got %v, want the vehicles of before", v)
+		}
+	})
+}
+
+func TestVehicleSource_Store(t *testing.T) {
+	t.Run("stores over the source as loaded", func(t *testing.T) {
+		src, _, path := newTestSource(t, `[{"id":1,"brand":"Ford"}]`)
+
+		err := src.Store(map[int]internal.Vehicle{2: {Id: 2, Version: 1}})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sig := signature(path); src.signature != sig {
+			t.Errorf("signature %q, want the one of the stored file %q", src.signature, sig)
+		}
+	})
+
+	t.Run("refuses to store over a changed source", func(t *testing.T) {
+		src, _, path := newTestSource(t, `[{"id":1,"brand":"Ford"}]`)
+		fleet := []byte(`[{"id":7,"brand":"Kia"},{"id":8,"brand":"Fiat"}]`)
+		if err := os.WriteFile(path, fleet, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		err := src.Store(map[int]internal.Vehicle{1: {Id: 1, Version: 1}})
+
+		if !errors.Is(err, ErrSourceChanged) {
+			t.Errorf("error %v, want %v", err, ErrSourceChanged)
+		}
+		if b, _ := os.ReadFile(path); string(b) != string(fleet) {
+			t.Errorf("source overwritten: %s", b)
+		}
+		// the new file is still taken for a change by Watch
+		if signature(path) == src.signature {
+			t.Errorf("signature refreshed after a refused store")
+		}
+	})
+}
+
+// eventually is a function that fails the test unless cond holds within a second
+func eventually(t *testing.T, cond func() bool, msg string) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if cond() {
+			return
+		}
+	}
+	t.Error(msg)
+}
+
+func TestVehicleSource_Watch(t *testing.T) {
+	src, rp, path := newTestSource(t, `[{"id":1,"brand":"Ford"}]`)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		src.Watch(ctx, 5*time.Millisecond)
+	}()
+
+	// a change of the file is reloaded
+	if err := os.WriteFile(path, []byte(`[{"id":1,"brand":"Ford"},{"id":2,"brand":"Fiat"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, func() bool {
+		v, _ := rp.FindAll()
+		return len(v) == 2
+	}, "change of the file not reloaded")
+
+	// the stores of the server itself are not changes: a reload would bring vehicle 2 back
+	if err := rp.DeleteById(2, 0); err != nil {
+		t.Fatal(err)
+	}
+	v, _ := rp.FindAll()
+	if err := src.Store(map[int]internal.Vehicle{1: v[1], 3: {Id: 3, Version: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if v, _ := rp.FindAll(); len(v) != 1 {
+		t.Errorf("store reloaded as a change: %v", v)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Errorf("watch not stopped with its context")
+	}
+}
+
+func TestVehicleSource_WatchFailure(t *testing.T) {
+	src, rp, path := newTestSource(t, `[{"id":1,"brand":"Ford"}]`)
+	var mu sync.Mutex
+	loads := 0
+	load := src.load
+	src.load = func() (map[int]internal.Vehicle, error) {
+		mu.Lock()
+		loads++
+		mu.Unlock()
+		return load()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go src.Watch(ctx, 5*time.Millisecond)
+
+	// a malformed file is tried once, not on every tick
+	if err := os.WriteFile(path, []byte(`[{"id":`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	mu.Lock()
+	if loads != 1 {
+		t.Errorf("%d loads of the malformed file, want 1", loads)
+	}
+	mu.Unlock()
+
+	// it is tried again once it changes
+	if err := os.WriteFile(path, []byte(`[{"id":1,"brand":"Ford"},{"id":2,"brand":"Fiat"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, func() bool {
+		v, _ := rp.FindAll()
+		return len(v) == 2
+	}, "fixed file not reloaded")
+}
+
+func TestVehicleSource_ReloadDiscardsLog(t *testing.T) {
+	dir := t.TempDir()
+	source := map[int]internal.Vehicle{
+		1: {Id: 1, VehicleAttributes: internal.VehicleAttributes{Brand: "Ford", MaxSpeed: 100}},
+	}
+	src := newVehicleSource(filepath.Join(dir, "vehicles.json"), func() (map[int]internal.Vehicle, error) {
+		v := make(map[int]internal.Vehicle, len(source))
+		for id, vh := range source {
+			v[id] = vh
+		}
+		return v, nil
+	}, nil)
+	lg := loader.NewVehicleLogFile(filepath.Join(dir, "vehicles.log"))
+	defer lg.Close()
+	db, _ := src.load()
+	rp := repository.NewVehicleLogged(repository.NewVehicleMap(db), lg, src, 0)
+	src.rp = rp
+
+	if _, err := rp.Create(internal.VehicleAttributes{Brand: "Honda"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := rp.DeleteById(1, 0); err != nil {
+		t.Fatal(err)
+	}
+	// the source is replaced by another fleet that reuses the ids of the logged writes
+	source = map[int]internal.Vehicle{
+		1: {Id: 1, VehicleAttributes: internal.VehicleAttributes{Brand: "Kia"}},
+		2: {Id: 2, VehicleAttributes: internal.VehicleAttributes{Brand: "Fiat"}},
+	}
+
+	_, err := src.Reload()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, _ := rp.FindAll()
+	if len(v) != 2 || v[1].Brand != "Kia" || v[2].Brand != "Fiat" {
+		t.Errorf("got %v, want the vehicles of the new fleet", v)
+	}
+
+	// the log starts over, so a restart does not replay the writes over the new fleet either
+	v = map[int]internal.Vehicle{}
+	if err = loader.NewVehicleLogFile(filepath.Join(dir, "vehicles.log")).Replay(v); err != nil {
+		t.Fatal(err)
+	}
+	if len(v) != 0 {
+		t.Errorf("replayed %v, want no operations", v)
+	}
+}
+
+func TestVehicleSource_Signature(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vehicles.json")
+	if err := os.WriteFile(path, []byte(`[]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	load := func() (map[int]internal.Vehicle, error) { return nil, nil }
+
+	for _, uri := range []string{path, "file://" + path, "FILE://" + path} {
+		if sig := newVehicleSource(uri, load, nil).signature; sig == "" || sig != signature(path) {
+			t.Errorf("signature of %s %q, want the one of the file", uri, sig)
+		}
+	}
+}
+
+func TestVehicleSource_WatchUnreadable(t *testing.T) {
+	load := func() (map[int]internal.Vehicle, error) { return nil, nil }
+
+	for _, uri := range []string{"s3://bucket/vehicles.json", filepath.Join(t.TempDir(), "missing.json")} {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			newVehicleSource(uri, load, nil).Watch(context.Background(), time.Millisecond)
+		}()
+
+		// a source that cannot be read is not polled, so the watch returns at once
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Errorf("%s watched", uri)
+		}
+	}
+}
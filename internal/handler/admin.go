@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"app/internal"
+	"log"
+	"net/http"
+
+	"github.com/bootcamp-go/web/response"
+)
+
+// NewAdminDefault is a function that returns a new instance of AdminDefault
+func NewAdminDefault(rl internal.VehicleReloader) *AdminDefault {
+	return &AdminDefault{rl: rl}
+}
+
+// AdminDefault is a struct with methods that represent handlers for the administration of the server
+type AdminDefault struct {
+	// rl is the reloader of the vehicles
+	rl internal.VehicleReloader
+}
+
+// Reload is a method that returns a handler for the route POST /admin/reload
+func (h *AdminDefault) Reload() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// process
+		// - reload vehicles
+		n, err := h.rl.Reload()
+		if err != nil {
+			// the cause may reveal paths of the server, so it is only logged
+			log.Println("reload vehicles:", err)
+			writeProblem(w, r, &internal.Error{Kind: errReloadFailed, Code: "reload_failed"})
+			return
+		}
+
+		// response
+		response.JSON(w, http.StatusOK, map[string]any{
+			"message": "success",
+			"data": map[string]any{
+				"vehicles": n,
+			},
+		})
+	}
+}
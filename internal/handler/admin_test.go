@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// reloaderStub is a reloader that returns n and err
+type reloaderStub struct {
+	n   int
+	err error
+}
+
+func (r reloaderStub) Reload() (n int, err error) {
+	return r.n, r.err
+}
+
+func TestAdminDefault_Reload(t *testing.T) {
+	t.Run("reloaded", func(t *testing.T) {
+		hd := NewAdminDefault(reloaderStub{n: 7})
+
+		res := serve(hd.Reload(), "/admin/reload", http.MethodPost, "/admin/reload", "")
+
+		var body struct {
+			Data struct {
+				Vehicles int `json:"vehicles"`
+			} `json:"data"`
+		}
+		decodeBody(t, res.Body, &body)
+		if res.Code != http.StatusOK || body.Data.Vehicles != 7 {
+			t.Errorf("status %d vehicles %d, want 200 and 7", res.Code, body.Data.Vehicles)
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		hd := NewAdminDefault(reloaderStub{err: errors.New("open /srv/secret/vehicles.json: permission denied")})
+
+		res := serve(hd.Reload(), "/admin/reload", http.MethodPost, "/admin/reload", "", "Accept-Language", "en")
+
+		var p Problem
+		decodeBody(t, res.Body, &p)
+		if res.Code != http.StatusInternalServerError || p.Type != "/problems/reload-failed" {
+			t.Errorf("status %d type %s, want 500 and /problems/reload-failed", res.Code, p.Type)
+		}
+		// the cause stays on the server
+		if p.Detail != localize(LanguageEnglish, "reload_failed") || strings.Contains(p.Detail, "secret") {
+			t.Errorf("detail %q", p.Detail)
+		}
+	})
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"app/internal"
+	"errors"
+)
+
+const (
+	// batchCreated is the status of the items of a batch that were stored
+	batchCreated = "created"
+	// batchInvalid is the status of the items of a batch that are invalid
+	batchInvalid = "invalid"
+	// batchSkipped is the status of the valid items of a batch not stored because others are invalid
+	batchSkipped = "skipped"
+)
+
+// BatchItemJSON is a struct that represents the outcome of an item of a batch in JSON format
+type BatchItemJSON struct {
+	Index   int            `json:"index"`
+	Status  string         `json:"status"`
+	Id      int            `json:"id,omitempty"`
+	Vehicle *VehicleJSON   `json:"vehicle,omitempty"`
+	Detail  string         `json:"detail,omitempty"`
+	Errors  []FieldProblem `json:"errors,omitempty"`
+}
+
+// batchError is a struct that represents the error of a batch that created no vehicle because of its invalid items
+// it is rendered as a problem with the outcome of every item of the batch
+type batchError struct {
+	// Err is the error of the batch as a whole
+	Err error
+	// Results are the outcomes of the items of the batch
+	Results []internal.BatchResult
+}
+
+// invalidBatch is a function that returns the error of a batch that created no vehicle, with the outcomes of its items
+func invalidBatch(results []internal.BatchResult) error {
+	return &batchError{Err: internal.Invalid("batch_invalid"), Results: results}
+}
+
+// Error is a method that returns the error message
+func (e *batchError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap is a method that returns the error of the batch as a whole
+func (e *batchError) Unwrap() error {
+	return e.Err
+}
+
+// serializeBatch is a function that converts the results of a batch into their JSON representation, in the language lang
+func serializeBatch(results []internal.BatchResult, lang string) []BatchItemJSON {
+	items := make([]BatchItemJSON, 0, len(results))
+	for _, rs := range results {
+		item := BatchItemJSON{Index: rs.Index, Status: batchSkipped}
+		switch {
+		case rs.Created:
+			vh := toVehicleJSON(rs.Vehicle)
+			item.Status = batchCreated
+			item.Id = rs.Vehicle.Id
+			item.Vehicle = &vh
+		case rs.Err != nil:
+			item.Status = batchInvalid
+			var ve *internal.ValidationError
+			if errors.As(rs.Err, &ve) {
+				item.Detail = localize(lang, "validation_failed")
+				item.Errors = fieldProblems(ve.Issues, lang)
+			}
+		}
+		items = append(items, item)
+	}
+	return items
+}
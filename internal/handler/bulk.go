@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"app/internal"
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// BulkOperationJSON is a struct that represents an operation of a bulk in JSON format
+// create takes a vehicle; patch and delete take the id of a vehicle, optionally with its version,
+// or a where filter with the syntax of the query of GET /vehicles, e.g. {"fuel_type": "gasoline", "year[lt]": "2000"};
+// the patch is a JSON Merge Patch object or a JSON Patch array
+type BulkOperationJSON struct {
+	Op      string            `json:"op"`
+	Id      int               `json:"id"`
+	Version int               `json:"version"`
+	Where   map[string]string `json:"where"`
+	Vehicle *VehicleJSON      `json:"vehicle"`
+	Patch   json.RawMessage   `json:"patch"`
+}
+
+// BulkResultJSON is a struct that represents the outcome of a bulk in JSON format
+type BulkResultJSON struct {
+	Created []int `json:"created"`
+	Updated []int `json:"updated"`
+	Deleted []int `json:"deleted"`
+}
+
+// deserializeBulk is a function that converts the JSON representation of the operations of a bulk into operations
+// the error of an operation is an internal.OperationError
+func deserializeBulk(opsJSON []BulkOperationJSON) (ops []internal.BulkOperation, err error) {
+	for i, opJSON := range opsJSON {
+		op, err := deserializeBulkOperation(opJSON)
+		if err != nil {
+			return nil, &internal.OperationError{Index: i, Err: err}
+		}
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+// deserializeBulkOperation is a function that converts the JSON representation of an operation of a bulk into an operation
+func deserializeBulkOperation(opJSON BulkOperationJSON) (op internal.BulkOperation, err error) {
+	switch opJSON.Op {
+	case "create":
+		if opJSON.Vehicle == nil {
+			return internal.BulkOperation{}, malformed("malformed_vehicle")
+		}
+		return internal.BulkOperation{Type: internal.OperationCreate, Attributes: fromVehicleJSON(*opJSON.Vehicle)}, nil
+	case "patch":
+		op.Type = internal.OperationUpdate
+		op.Update, err = deserializePatch(opJSON.Patch)
+		if err != nil {
+			return internal.BulkOperation{}, err
+		}
+	case "delete":
+		op.Type = internal.OperationDelete
+	default:
+		return internal.BulkOperation{}, malformed("unknown_bulk_operation", opJSON.Op)
+	}
+
+	// vehicles of the operation
+	switch {
+	case len(opJSON.Where) > 0 && opJSON.Id == 0:
+		query := make(url.Values, len(opJSON.Where))
+		for key, value := range opJSON.Where {
+			// the parameters of the page and the fields parameter shape a response, a where has none
+			if field, _, _ := strings.Cut(key, "["); slices.Contains(pageParams, field) || field == fieldsParam {
+				return internal.BulkOperation{}, malformed("unknown_filter_field", field)
+			}
+			query.Set(key, value)
+		}
+		f, err := parseFilter(query)
+		if err != nil {
+			return internal.BulkOperation{}, err
+		}
+		// a where without conditions would match every vehicle
+		if len(f) == 0 {
+			return internal.BulkOperation{}, malformed("bulk_target_required")
+		}
+		op.Filter = f
+	case len(opJSON.Where) == 0 && opJSON.Id > 0:
+		op.Id, op.Version = opJSON.Id, opJSON.Version
+	default:
+		return internal.BulkOperation{}, malformed("bulk_target_required")
+	}
+	return op, nil
+}
+
+// deserializePatch is a function that returns the update of a JSON Merge Patch object or a JSON Patch array
+func deserializePatch(raw json.RawMessage) (update func(v internal.Vehicle) (internal.Vehicle, error), err error) {
+	var patch func(doc any) (any, error)
+	switch raw = bytes.TrimSpace(raw); {
+	case bytes.HasPrefix(raw, []byte("{")):
+		var p map[string]any
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return nil, malformed("malformed_patch")
+		}
+		patch = func(doc any) (any, error) {
+			return mergePatch(doc, p), nil
+		}
+	case bytes.HasPrefix(raw, []byte("[")):
+		var ops []patchOperation
+		if err := json.Unmarshal(raw, &ops); err != nil {
+			return nil, malformed("malformed_patch")
+		}
+		patch = func(doc any) (any, error) {
+			return jsonPatch(doc, ops)
+		}
+	default:
+		return nil, malformed("malformed_patch")
+	}
+
+	return func(v internal.Vehicle) (internal.Vehicle, error) {
+		return patchVehicle(v, patch)
+	}, nil
+}
+
+// serializeBulk is a function that converts the outcome of a bulk into its JSON representation
+func serializeBulk(rs internal.BulkResult) BulkResultJSON {
+	rsJSON := BulkResultJSON{Created: []int{}, Updated: []int{}, Deleted: []int{}}
+	rsJSON.Created = append(rsJSON.Created, rs.Created...)
+	rsJSON.Updated = append(rsJSON.Updated, rs.Updated...)
+	rsJSON.Deleted = append(rsJSON.Deleted, rs.Deleted...)
+	return rsJSON
+}
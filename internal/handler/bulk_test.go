@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestVehicleDefault_BulkWhere(t *testing.T) {
+	tests := []struct {
+		name, body, detail string
+	}{
+		{"limit", `{"operations":[{"op":"delete","where":{"limit":"1"}}]}`, localize(LanguagePortuguese, "unknown_filter_field", "limit")},
+		{"offset", `{"operations":[{"op":"delete","where":{"brand":"Ford","offset":"1"}}]}`, localize(LanguagePortuguese, "unknown_filter_field", "offset")},
+		{"cursor", `{"operations":[{"op":"delete","where":{"cursor":"abc"}}]}`, localize(LanguagePortuguese, "unknown_filter_field", "cursor")},
+		{"sort", `{"operations":[{"op":"patch","where":{"sort":"brand"},"patch":{"color":"Red"}}]}`, localize(LanguagePortuguese, "unknown_filter_field", "sort")},
+		{"fields", `{"operations":[{"op":"delete","where":{"fields[in]":"id"}}]}`, localize(LanguagePortuguese, "unknown_filter_field", "fields")},
+		{"no conditions", `{"operations":[{"op":"delete","where":{}}]}`, localize(LanguagePortuguese, "bulk_target_required")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the operations are rejected before the service is reached
+			hd := NewVehicleDefault(nil)
+			req := httptest.NewRequest(http.MethodPost, "/vehicles/bulk", strings.NewReader(tt.body))
+			res := httptest.NewRecorder()
+
+			hd.Bulk()(res, req)
+
+			var p Problem
+			if err := json.NewDecoder(res.Body).Decode(&p); err != nil {
+				t.Fatal(err)
+			}
+			if res.Code != http.StatusBadRequest || p.Detail != tt.detail {
+				t.Errorf("status %d detail %q, want 400 and %q", res.Code, p.Detail, tt.detail)
+			}
+			if p.Operation == nil || *p.Operation != 0 {
+				t.Errorf("operation %v, want 0", p.Operation)
+			}
+		})
+	}
+}
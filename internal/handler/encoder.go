@@ -0,0 +1,384 @@
+package handler
+
+import (
+	"app/internal"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// encoder is a struct that represents a format the responses can be written in
+type encoder struct {
+	// mediaTypes are the media types of the format, the first one is the Content-Type of the responses
+	mediaTypes []string
+	// encode writes the body of a response
+	encode func(w io.Writer, body any) (err error)
+}
+
+// encoders is the registry of the formats of the responses, in order of preference; the first one is the default
+// every format shares the names of the JSON representation of the body; XML and CSV are derived from it
+var encoders = []encoder{
+	{[]string{"application/json"}, encodeJSON},
+	{[]string{"application/xml", "text/xml"}, encodeXML},
+	{[]string{"text/csv"}, encodeCSV},
+	{[]string{"application/msgpack", "application/vnd.msgpack", "application/x-msgpack"}, encodeMsgPack},
+}
+
+// mediaRange is a struct that represents a media range of the Accept header, e.g. text/* or application/json;q=0.5
+type mediaRange struct {
+	typ, subtype string
+	q            float64
+}
+
+// parseAccept is a function that returns the media ranges of an Accept header, in order
+// the ranges with q=0 are kept, they exclude the media types they match
+func parseAccept(header string) (ranges []mediaRange) {
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		typ, subtype, _ := strings.Cut(mediaType, "/")
+		mr := mediaRange{typ: typ, subtype: subtype, q: 1}
+		if q, ok := params["q"]; ok {
+			mr.q, err = strconv.ParseFloat(q, 64)
+			if err != nil || mr.q < 0 || mr.q > 1 {
+				continue
+			}
+		}
+		ranges = append(ranges, mr)
+	}
+	return
+}
+
+// quality is a function that returns the quality the ranges give to a media type, the one of the most specific range
+// that matches it, with the specificity of that range; q is 0 when no range matches it
+func quality(ranges []mediaRange, mediaType string) (q float64, specificity int) {
+	specificity = -1
+	for _, mr := range ranges {
+		if mr.matches(mediaType) && mr.specificity() > specificity {
+			q, specificity = mr.q, mr.specificity()
+		}
+	}
+	return
+}
+
+// specificity is a method that returns 0 for */*, 1 for type/* and 2 for a full media type
+func (mr mediaRange) specificity() int {
+	switch {
+	case mr.typ == "*":
+		return 0
+	case mr.subtype == "*":
+		return 1
+	}
+	return 2
+}
+
+// matches is a method that returns true when the media type is in the range
+func (mr mediaRange) matches(mediaType string) bool {
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+	return (mr.typ == "*" || mr.typ == typ) && (mr.subtype == "*" || mr.subtype == subtype)
+}
+
+// negotiate is a function that returns the encoder and the media type of the format of the response to a request,
+// as its Accept header asks; JSON when the header is missing
+func negotiate(r *http.Request) (enc encoder, mediaType string, err error) {
+	header := r.Header.Get("Accept")
+	if strings.TrimSpace(header) == "" {
+		return encoders[0], encoders[0].mediaTypes[0], nil
+	}
+
+	// the media type with the highest quality wins, then the one matched by the most specific range,
+	// then the first one of the registry
+	ranges := parseAccept(header)
+	bestQ, bestSpecificity := 0.0, -1
+	for _, e := range encoders {
+		for _, mt := range e.mediaTypes {
+			q, specificity := quality(ranges, mt)
+			if q > bestQ || (q == bestQ && q > 0 && specificity > bestSpecificity) {
+				enc, mediaType, bestQ, bestSpecificity = e, mt, q, specificity
+			}
+		}
+	}
+	if bestQ > 0 {
+		return enc, mediaType, nil
+	}
+
+	var supported []string
+	for _, enc := range encoders {
+		supported = append(supported, enc.mediaTypes[0])
+	}
+	return encoder{}, "", &internal.Error{Kind: errNotAcceptable, Code: "not_acceptable", Args: []any{strings.Join(supported, ", ")}}
+}
+
+// writeData is a function that writes the body of the response to a read request in the format its Accept header
+// asks for, see encoders; the response is 406 Not Acceptable when there is no such format
+func writeData(w http.ResponseWriter, r *http.Request, status int, body any) {
+	w.Header().Add("Vary", "Accept")
+	enc, mediaType, err := negotiate(r)
+	if err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := enc.encode(&buf, body); err != nil {
+		writeProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", mediaType)
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+// member is a struct that represents a member of an object of the generic representation of a body
+type member struct {
+	Name  string
+	Value any
+}
+
+// object is a type that represents an object of the generic representation of a body, with its members in order
+type object []member
+
+// MarshalJSON is a method that returns the JSON representation of the object, with its members in order
+func (o object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(m.Name)
+		value, err := json.Marshal(m.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// rows is a struct that represents the items of a list with the names of their columns,
+// so that the formats with a header, such as CSV, have one even when the list is empty;
+// the other formats write the items alone
+type rows struct {
+	columns []string
+	items   []any
+}
+
+// MarshalJSON is a method that returns the JSON representation of the items
+func (rs rows) MarshalJSON() ([]byte, error) {
+	return json.Marshal(rs.items)
+}
+
+// columnsOf is a function that returns the names of the members of the JSON representation of a value, in order
+func columnsOf(v any) (columns []string) {
+	g, _ := generic(v)
+	o, _ := g.(object)
+	for _, m := range o {
+		columns = append(columns, m.Name)
+	}
+	return
+}
+
+// generic is a function that returns the generic representation of a body, made of objects, []any, strings,
+// json.Number, bools and nils, from its JSON representation
+func generic(body any) (v any, err error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return decodeGeneric(dec)
+}
+
+// decodeGeneric is a function that decodes the next value of dec into its generic representation
+func decodeGeneric(dec *json.Decoder) (v any, err error) {
+	token, err := dec.Token()
+	if err != nil {
+		return
+	}
+
+	switch token {
+	case json.Delim('{'):
+		o := object{}
+		for dec.More() {
+			name, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeGeneric(dec)
+			if err != nil {
+				return nil, err
+			}
+			o = append(o, member{Name: name.(string), Value: value})
+		}
+		_, err = dec.Token()
+		return o, err
+	case json.Delim('['):
+		a := []any{}
+		for dec.More() {
+			value, err := decodeGeneric(dec)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, value)
+		}
+		_, err = dec.Token()
+		return a, err
+	}
+	return token, nil
+}
+
+// encodeJSON is a function that writes a body in JSON format, as response.JSON does
+func encodeJSON(w io.Writer, body any) (err error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return
+	}
+	_, err = w.Write(data)
+	return
+}
+
+// encodeXML is a function that writes a body in XML format, under a response element
+// the members of the objects are elements named after them and the items of the arrays are item elements
+func encodeXML(w io.Writer, body any) (err error) {
+	v, err := generic(body)
+	if err != nil {
+		return
+	}
+
+	if _, err = io.WriteString(w, xml.Header); err != nil {
+		return
+	}
+	enc := xml.NewEncoder(w)
+	if err = encodeXMLElement(enc, "response", v); err != nil {
+		return
+	}
+	return enc.Flush()
+}
+
+// encodeXMLElement is a function that writes a value of the generic representation of a body as an element
+func encodeXMLElement(enc *xml.Encoder, name string, v any) (err error) {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if err = enc.EncodeToken(start); err != nil {
+		return
+	}
+
+	switch v := v.(type) {
+	case object:
+		for _, m := range v {
+			if err = encodeXMLElement(enc, m.Name, m.Value); err != nil {
+				return
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if err = encodeXMLElement(enc, "item", item); err != nil {
+				return
+			}
+		}
+	case nil:
+	default:
+		if err = enc.EncodeToken(xml.CharData(text(v))); err != nil {
+			return
+		}
+	}
+
+	return enc.EncodeToken(start.End())
+}
+
+// encodeCSV is a function that writes the data of a body in CSV format, one row per item with a header of their names
+// the rest of the envelope has no place in CSV, the pages carry their total and links in the headers, see writePage;
+// data that is not an object or an array of objects is written in a single data column. The header of rows starts
+// with their columns, so it is written even when there is no row
+func encodeCSV(w io.Writer, body any) (err error) {
+	var header []string
+	if b, ok := body.(map[string]any); ok {
+		if rs, ok := b["data"].(rows); ok {
+			header = append(header, rs.columns...)
+		}
+	}
+
+	v, err := generic(body)
+	if err != nil {
+		return
+	}
+	if o, ok := v.(object); ok {
+		for _, m := range o {
+			if m.Name == "data" {
+				v = m.Value
+			}
+		}
+	}
+	items, ok := v.([]any)
+	if !ok {
+		items = []any{v}
+	}
+
+	// header, the names of the members in order of appearance
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[name] = i
+	}
+	for _, item := range items {
+		o, ok := item.(object)
+		if !ok {
+			o = object{{Name: "data", Value: item}}
+		}
+		for _, m := range o {
+			if _, exists := columns[m.Name]; !exists {
+				columns[m.Name] = len(header)
+				header = append(header, m.Name)
+			}
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	if err = cw.Write(header); err != nil {
+		return
+	}
+	for _, item := range items {
+		o, ok := item.(object)
+		if !ok {
+			o = object{{Name: "data", Value: item}}
+		}
+		record := make([]string, len(header))
+		for _, m := range o {
+			record[columns[m.Name]] = text(m.Value)
+		}
+		if err = cw.Write(record); err != nil {
+			return
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// text is a function that returns a value of the generic representation of a body as text
+// objects and arrays are written in JSON format
+func text(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"app/internal"
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name, accept, mediaType string
+	}{
+		{"no header", "", "application/json"},
+		{"anything", "*/*", "application/json"},
+		{"one media type", "application/xml", "application/xml"},
+		{"alias", "application/x-msgpack", "application/x-msgpack"},
+		{"highest q wins", "application/json;q=0.5, application/xml;q=0.9", "application/xml"},
+		{"first of the registry among equals", "text/*", "text/xml"},
+		{"json excluded", "application/json;q=0, */*", "application/xml"},
+		{"type excluded", "application/*;q=0, */*;q=0.1", "text/xml"},
+		{"most specific range wins", "*/*;q=0, text/csv", "text/csv"},
+		{"specific range over its type", "text/*;q=0, text/csv;q=0.2", "text/csv"},
+		{"malformed ranges skipped", "application/xml;q=2, ;;, text/csv", "text/csv"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/vehicles", nil)
+			req.Header.Set("Accept", tt.accept)
+
+			enc, mediaType, err := negotiate(req)
+
+			if err != nil || mediaType != tt.mediaType || enc.encode == nil {
+				t.Errorf("media type %q, %v; want %q", mediaType, err, tt.mediaType)
+			}
+		})
+	}
+
+	for _, accept := range []string{"image/png", "application/json;q=0", "*/*;q=0", "text/csv;q=0, text/xml;q=0, application/*;q=0"} {
+		t.Run("not acceptable "+accept, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/vehicles", nil)
+			req.Header.Set("Accept", accept)
+
+			_, _, err := negotiate(req)
+
+			if !errors.Is(err, errNotAcceptable) {
+				t.Errorf("error %v, want %v", err, errNotAcceptable)
+			}
+		})
+	}
+}
+
+func TestWriteData(t *testing.T) {
+	hd, _ := newTestHandler(1)
+
+	t.Run("negotiated", func(t *testing.T) {
+		res := serve(hd.GetById(), "/vehicles/{id}", http.MethodGet, "/vehicles/1?fields=id,brand", "", "Accept", "application/json;q=0, text/xml")
+
+		want := `<response><data><id>1</id><brand>Fiat</brand></data><message>success</message></response>`
+		if res.Code != http.StatusOK || res.Header().Get("Content-Type") != "text/xml" || !strings.Contains(res.Body.String(), want) {
+			t.Errorf("status %d %s body %s, want 200 and %s", res.Code, res.Header().Get("Content-Type"), res.Body, want)
+		}
+		if res.Header().Get("Vary") != "Accept" {
+			t.Errorf("vary %q, want Accept", res.Header().Get("Vary"))
+		}
+	})
+
+	t.Run("not acceptable", func(t *testing.T) {
+		res := serve(hd.GetById(), "/vehicles/{id}", http.MethodGet, "/vehicles/1", "", "Accept", "image/png, application/json;q=0")
+
+		// the problem is in JSON whatever the client asked for
+		var p Problem
+		decodeBody(t, res.Body, &p)
+		if res.Code != http.StatusNotAcceptable || p.Type != "/problems/not-acceptable" || !strings.Contains(p.Detail, "application/msgpack") {
+			t.Errorf("status %d problem %+v, want 406 listing the media types", res.Code, p)
+		}
+	})
+}
+
+func TestEncodeMsgPack(t *testing.T) {
+	vehicle := internal.Vehicle{Id: 1, VehicleAttributes: testAttributes(1)}
+	vehicle.MaxSpeed = 101.5
+
+	tests := []struct {
+		name string
+		body any
+		want []byte
+	}{
+		{"nil", nil, []byte{0xc0}},
+		{"booleans", []any{true, false}, []byte{0x92, 0xc3, 0xc2}},
+		{"positive fixint", 127, []byte{0x7f}},
+		{"negative fixint", -32, []byte{0xe0}},
+		{"int 8", -100, []byte{0xd0, 0x9c}},
+		{"uint 16", 300, []byte{0xcd, 0x01, 0x2c}},
+		{"int 32", -70000, []byte{0xd2, 0xff, 0xfe, 0xee, 0x90}},
+		{"uint 64", int64(1) << 40, []byte{0xcf, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00}},
+		{"float", 160.5, []byte{0xcb, 0x40, 0x64, 0x10, 0x00, 0x00, 0x00, 0x00, 0x00}},
+		{"integral float", 1000.0, []byte{0xcb, 0x40, 0x8f, 0x40, 0x00, 0x00, 0x00, 0x00, 0x00}},
+		{"struct", struct {
+			Id     int     `json:"id"`
+			Weight float64 `json:"weight"`
+			Color  string  `json:"color,omitempty"`
+			hidden bool
+		}{Id: 1, Weight: 2}, []byte{
+			0x82,
+			0xa2, 'i', 'd', 0x01,
+			0xa6, 'w', 'e', 'i', 'g', 'h', 't', 0xcb, 0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		}},
+		{"map with int keys", map[int]string{1: "a"}, []byte{0x81, 0x01, 0xa1, 'a'}},
+		{"fixstr", "Ford", []byte{0xa4, 'F', 'o', 'r', 'd'}},
+		{"str 8", strings.Repeat("a", 32), append([]byte{0xd9, 0x20}, bytes.Repeat([]byte("a"), 32)...)},
+		{"array 16", make([]any, 16), append([]byte{0xdc, 0x00, 0x10}, bytes.Repeat([]byte{0xc0}, 16)...)},
+		{"vehicle", map[string]any{
+			"message": "success",
+			"data":    project(vehicle, []string{"id", "brand", "max_speed"}),
+		}, []byte{
+			0x82,
+			0xa4, 'd', 'a', 't', 'a',
+			0x83,
+			0xa2, 'i', 'd', 0x01,
+			0xa5, 'b', 'r', 'a', 'n', 'd', 0xa4, 'F', 'i', 'a', 't',
+			0xa9, 'm', 'a', 'x', '_', 's', 'p', 'e', 'e', 'd', 0xcb, 0x40, 0x59, 0x60, 0x00, 0x00, 0x00, 0x00, 0x00,
+			0xa7, 'm', 'e', 's', 's', 'a', 'g', 'e', 0xa7, 's', 'u', 'c', 'c', 'e', 's', 's',
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			err := encodeMsgPack(&buf, tt.body)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf.Bytes(), tt.want) {
+				t.Errorf("bytes % x, want % x", buf.Bytes(), tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeMsgPack_FloatFields(t *testing.T) {
+	// the float fields are 64-bit floats in every record, integral or not
+	integral := internal.Vehicle{Id: 1, VehicleAttributes: testAttributes(1)}
+	fractional := internal.Vehicle{Id: 2, VehicleAttributes: testAttributes(2)}
+	fractional.Weight, fractional.Height, fractional.MaxSpeed = 1000.5, 150.25, 102.5
+
+	for _, fields := range [][]string{nil, {"id", "weight", "height", "max_speed"}} {
+		t.Run(fmt.Sprint(fields), func(t *testing.T) {
+			var buf bytes.Buffer
+
+			err := encodeMsgPack(&buf, map[string]any{
+				"data": []any{project(integral, fields), project(fractional, fields)},
+			})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range []string{"weight", "height", "max_speed"} {
+				key := append([]byte{0xa0 | byte(len(name))}, name...)
+				if n := bytes.Count(buf.Bytes(), key); n != 2 {
+					t.Fatalf("%s: %d occurrences, want 2", name, n)
+				}
+				rest := buf.Bytes()
+				for i := 0; i < 2; i++ {
+					rest = rest[bytes.Index(rest, key)+len(key):]
+					if rest[0] != 0xcb {
+						t.Errorf("%s of vehicle %d: type % x, want cb", name, i+1, rest[0])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeCSV_Header(t *testing.T) {
+	tests := []struct {
+		name, query string
+		n           int
+		want        string
+	}{
+		{"empty list", "", 0, "id,brand,model,registration,color,year,passengers,max_speed,fuel_type,transmission,weight,height,length,width\n"},
+		{"empty projection", "?fields=id,brand", 0, "id,brand\n"},
+		{"projection", "?fields=brand,id", 1, "brand,id\nFiat,1\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hd, _ := newTestHandler(tt.n)
+
+			res := serve(hd.GetAll(), "/vehicles", http.MethodGet, "/vehicles"+tt.query, "", "Accept", "text/csv")
+
+			if res.Code != http.StatusOK || res.Body.String() != tt.want {
+				t.Errorf("status %d body %q, want 200 and %q", res.Code, res.Body, tt.want)
+			}
+		})
+	}
+}
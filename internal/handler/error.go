@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"app/internal"
+	"errors"
+	"net/http"
+)
+
+var (
+	// errMalformedRequest is the kind of the errors returned when a request cannot be parsed
+	errMalformedRequest = errors.New("malformed request")
+	// errUnsupportedMediaType is the kind of the errors returned when the body of a request is in a format not supported
+	errUnsupportedMediaType = errors.New("unsupported media type")
+	// errNotAcceptable is the kind of the errors returned when a response cannot be written in any format the request accepts
+	errNotAcceptable = errors.New("not acceptable")
+	// errReloadFailed is the kind of the errors returned when the vehicles cannot be reloaded from their source
+	errReloadFailed = errors.New("reload failed")
+	// errTooLarge is the kind of the errors returned when the body of a request is larger than allowed
+	errTooLarge = errors.New("content too large")
+)
+
+// malformed is a function that returns an error of kind errMalformedRequest
+func malformed(code string, args ...any) error {
+	return &internal.Error{Kind: errMalformedRequest, Code: code, Args: args}
+}
+
+// statusOf is a function that returns the HTTP status code that corresponds to an error of the domain
+func statusOf(err error) int {
+	switch {
+	case errors.Is(err, internal.ErrVehicleNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, internal.ErrVehicleConflict):
+		return http.StatusConflict
+	case errors.Is(err, internal.ErrVehicleInvalid), errors.Is(err, internal.ErrInvalidRange), errors.Is(err, errMalformedRequest):
+		return http.StatusBadRequest
+	case errors.Is(err, internal.ErrPreconditionFailed):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, errUnsupportedMediaType):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, errNotAcceptable):
+		return http.StatusNotAcceptable
+	case errors.Is(err, errTooLarge):
+		return http.StatusRequestEntityTooLarge
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"app/internal"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestStatusOf(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"not found", internal.NotFound("vehicle_not_found"), http.StatusNotFound},
+		{"conflict", internal.Conflict("vehicle_id_conflict"), http.StatusConflict},
+		{"validation", &internal.ValidationError{}, http.StatusBadRequest},
+		{"invalid range", internal.InvalidRange("invalid_weight_range"), http.StatusBadRequest},
+		{"malformed", malformed("invalid_id"), http.StatusBadRequest},
+		{"precondition failed", internal.PreconditionFailed("version_mismatch", 2), http.StatusPreconditionFailed},
+		{"unsupported media type", &internal.Error{Kind: errUnsupportedMediaType}, http.StatusUnsupportedMediaType},
+		{"not acceptable", &internal.Error{Kind: errNotAcceptable}, http.StatusNotAcceptable},
+		{"too large", &internal.Error{Kind: errTooLarge}, http.StatusRequestEntityTooLarge},
+		{"wrapped", fmt.Errorf("find: %w", internal.NotFound("vehicle_not_found")), http.StatusNotFound},
+		{"of an operation", &internal.OperationError{Index: 2, Err: internal.NotFound("vehicle_not_found")}, http.StatusNotFound},
+		{"reload failed", &internal.Error{Kind: errReloadFailed}, http.StatusInternalServerError},
+		{"unexpected", errors.New("disk full"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := statusOf(tt.err); got != tt.status {
+				t.Errorf("got %d, want %d", got, tt.status)
+			}
+		})
+	}
+}
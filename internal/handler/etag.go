@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"app/internal"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// etag is a function that returns the entity tag of a vehicle, which changes with its version
+func etag(v internal.Vehicle) string {
+	return `"` + strconv.Itoa(v.Version) + `"`
+}
+
+// readTag is a function that returns the entity tag of the representation of a vehicle in the negotiated media type
+// only the whole vehicle in JSON, the representation of the writes, has a strong tag; the tag of a projection
+// or of another format is weak and also depends on the fields and the media type, e.g. W/"3-1c4e9a2f",
+// so that two representations of the same version never share a tag
+func readTag(v internal.Vehicle, fields []string, mediaType string) string {
+	if fields == nil && mediaType == encoders[0].mediaTypes[0] {
+		return etag(v)
+	}
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%s;%s", mediaType, strings.Join(fields, ","))
+	return fmt.Sprintf(`W/"%d-%08x"`, v.Version, h.Sum32())
+}
+
+// entityTags is a function that returns the entity tags of a list, e.g. "3", W/"4"
+func entityTags(header string) (tags []string) {
+	for _, tag := range strings.Split(header, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return
+}
+
+// matchVersions is a function that returns the versions of the vehicle the If-Match header of a write request lists
+// versions is nil when the request expects any version; the comparison is strong, so weak entity tags never match
+// and the list can be empty
+func matchVersions(r *http.Request) (versions []int, err error) {
+	tags := entityTags(r.Header.Get("If-Match"))
+	if len(tags) == 0 || slices.Contains(tags, "*") {
+		return nil, nil
+	}
+
+	versions = []int{}
+	for _, tag := range tags {
+		if strings.HasPrefix(tag, "W/") {
+			continue
+		}
+		version, err := strconv.Atoi(strings.Trim(tag, `"`))
+		if err != nil || version < 1 || !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) {
+			return nil, internal.PreconditionFailed("invalid_if_match")
+		}
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
+
+// ifMatch is a method that returns the version the vehicle of id must be at for a write request, from its If-Match header
+// it is 0 when the request expects any version; otherwise the current version of the vehicle is expected if it is
+// among the listed entity tags, so the write still fails if the vehicle changes in between
+// a missing vehicle fails the precondition of listed tags; with If-Match: * it is left to the write,
+// which answers 404 Not Found, more telling to the client than the 412 the header alone would deserve
+func (h *VehicleDefault) ifMatch(r *http.Request, id int) (version int, err error) {
+	versions, err := matchVersions(r)
+	if err != nil || versions == nil {
+		return 0, err
+	}
+
+	vh, err := h.sv.FindById(id)
+	if errors.Is(err, internal.ErrVehicleNotFound) {
+		return 0, internal.PreconditionFailed("if_match_not_found")
+	}
+	if err != nil {
+		return 0, err
+	}
+	if !slices.Contains(versions, vh.Version) {
+		return 0, internal.PreconditionFailed("version_mismatch", vh.Version)
+	}
+	return vh.Version, nil
+}
+
+// notModified is a function that returns true when the If-None-Match header of a read request
+// matches the entity tag of the representation it gets, see readTag; the comparison is weak,
+// so a weak tag matches its strong counterpart but never the tag of another representation
+func notModified(r *http.Request, current string) bool {
+	for _, tag := range entityTags(r.Header.Get("If-None-Match")) {
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(current, "W/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"app/internal"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReadTag(t *testing.T) {
+	vh := internal.Vehicle{Id: 1, Version: 3}
+	if got := readTag(vh, nil, "application/json"); got != `"3"` {
+		t.Errorf("whole vehicle in JSON: got %s, want \"3\"", got)
+	}
+
+	// every other representation has a weak tag of its own
+	tests := []struct {
+		name      string
+		fields    []string
+		mediaType string
+	}{
+		{"projection", []string{"id", "brand"}, "application/json"},
+		{"another projection", []string{"brand", "id"}, "application/json"},
+		{"another format", nil, "application/xml"},
+		{"projection in another format", []string{"id", "brand"}, "application/xml"},
+	}
+	seen := map[string]string{}
+	for _, tt := range tests {
+		got := readTag(vh, tt.fields, tt.mediaType)
+		if !strings.HasPrefix(got, `W/"3-`) {
+			t.Errorf("%s: got %s, want a weak tag of version 3", tt.name, got)
+		}
+		if other, ok := seen[got]; ok {
+			t.Errorf("%s: tag %s already of %s", tt.name, got, other)
+		}
+		seen[got] = tt.name
+	}
+}
+
+func TestMatchVersions_WeakReadTag(t *testing.T) {
+	// the weak tag of a projection never satisfies the strong comparison of a write
+	r := httptest.NewRequest(http.MethodPut, "/vehicles/1", nil)
+	r.Header.Set("If-Match", `W/"3"`)
+
+	versions, err := matchVersions(r)
+
+	if err != nil || versions == nil || len(versions) != 0 {
+		t.Errorf("versions %v, %v; want none to match", versions, err)
+	}
+}
+
+func TestVehicleDefault_IfMatch(t *testing.T) {
+	tests := []struct {
+		name, method, ifMatch string
+		status                int
+		detail                string
+	}{
+		{"replace any version", http.MethodPut, "", http.StatusOK, ""},
+		{"replace the current version", http.MethodPut, `"1"`, http.StatusOK, ""},
+		{"replace with a wildcard", http.MethodPut, "*", http.StatusOK, ""},
+		{"replace another version", http.MethodPut, `"2"`, http.StatusPreconditionFailed, localize(LanguagePortuguese, "version_mismatch", 1)},
+		{"replace one of many versions", http.MethodPut, `"2", "1"`, http.StatusOK, ""},
+		{"replace none of many versions", http.MethodPut, `"2", "3"`, http.StatusPreconditionFailed, localize(LanguagePortuguese, "version_mismatch", 1)},
+		{"replace with a weak tag", http.MethodPut, `W/"1"`, http.StatusPreconditionFailed, localize(LanguagePortuguese, "version_mismatch", 1)},
+		{"replace with an unquoted tag", http.MethodPut, `1`, http.StatusPreconditionFailed, localize(LanguagePortuguese, "invalid_if_match")},
+		{"delete the current version", http.MethodDelete, `"1"`, http.StatusNoContent, ""},
+		{"delete another version", http.MethodDelete, `"2"`, http.StatusPreconditionFailed, localize(LanguagePortuguese, "version_mismatch", 1)},
+		{"delete with a weak tag", http.MethodDelete, `W/"1"`, http.StatusPreconditionFailed, localize(LanguagePortuguese, "version_mismatch", 1)},
+		{"delete with an unquoted tag", http.MethodDelete, `1`, http.StatusPreconditionFailed, localize(LanguagePortuguese, "invalid_if_match")},
+		{"replace a missing vehicle", http.MethodPut, `"1"`, http.StatusPreconditionFailed, localize(LanguagePortuguese, "if_match_not_found")},
+		{"replace a missing vehicle of many versions", http.MethodPut, `"1", "2"`, http.StatusPreconditionFailed, localize(LanguagePortuguese, "if_match_not_found")},
+		{"replace a missing vehicle with a wildcard", http.MethodPut, "*", http.StatusNotFound, localize(LanguagePortuguese, "vehicle_not_found")},
+		{"delete a missing vehicle", http.MethodDelete, `"1"`, http.StatusPreconditionFailed, localize(LanguagePortuguese, "if_match_not_found")},
+		{"delete a missing vehicle with a wildcard", http.MethodDelete, "*", http.StatusNotFound, localize(LanguagePortuguese, "vehicle_not_found")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hd, rp := newTestHandler(1)
+			h, body := hd.Replace(), testVehicleJSON(2)
+			if tt.method == http.MethodDelete {
+				h, body = hd.DeleteById(), ""
+			}
+			target := "/vehicles/1"
+			if strings.Contains(tt.name, "missing") {
+				target = "/vehicles/9"
+			}
+
+			res := serve(h, "/vehicles/{id}", tt.method, target, body, "If-Match", tt.ifMatch)
+
+			if res.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", res.Code, tt.status, res.Body)
+			}
+			if tt.detail == "" {
+				return
+			}
+			var p Problem
+			decodeBody(t, res.Body, &p)
+			if p.Detail != tt.detail {
+				t.Errorf("detail %q, want %q", p.Detail, tt.detail)
+			}
+			// the vehicle is left as it was
+			if vh, err := rp.FindById(1); err != nil || vh.Version != 1 || vh.Model != "Model 1" {
+				t.Errorf("vehicle %+v, %v; want it unchanged", vh, err)
+			}
+		})
+	}
+}
+
+func TestVehicleDefault_IfNoneMatch(t *testing.T) {
+	projection := readTag(internal.Vehicle{Version: 1}, []string{"brand"}, "application/json")
+	tests := []struct {
+		name, target, accept, ifNoneMatch string
+		status                            int
+		tag                               string
+	}{
+		{"no header", "/vehicles/1", "", "", http.StatusOK, `"1"`},
+		{"current version", "/vehicles/1", "", `"1"`, http.StatusNotModified, `"1"`},
+		{"weak tag of the current version", "/vehicles/1", "", `W/"1"`, http.StatusNotModified, `"1"`},
+		{"one of many tags", "/vehicles/1", "", `"3", "1"`, http.StatusNotModified, `"1"`},
+		{"wildcard", "/vehicles/1", "", "*", http.StatusNotModified, `"1"`},
+		{"another version", "/vehicles/1", "", `"2"`, http.StatusOK, `"1"`},
+		{"projection of its tag", "/vehicles/1?fields=brand", "", projection, http.StatusNotModified, projection},
+		{"projection of the whole tag", "/vehicles/1?fields=brand", "", `"1"`, http.StatusOK, projection},
+		{"another projection", "/vehicles/1?fields=model", "", projection, http.StatusOK, readTag(internal.Vehicle{Version: 1}, []string{"model"}, "application/json")},
+		{"another format", "/vehicles/1?fields=brand", "application/xml", projection, http.StatusOK, readTag(internal.Vehicle{Version: 1}, []string{"brand"}, "application/xml")},
+		{"whole vehicle of a projection tag", "/vehicles/1", "", projection, http.StatusOK, `"1"`},
+		{"not acceptable", "/vehicles/1", "image/png", `"1"`, http.StatusNotAcceptable, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hd, _ := newTestHandler(1)
+
+			res := serve(hd.GetById(), "/vehicles/{id}", http.MethodGet, tt.target, "", "If-None-Match", tt.ifNoneMatch, "Accept", tt.accept)
+
+			if res.Code != tt.status || res.Header().Get("ETag") != tt.tag {
+				t.Errorf("status %d ETag %s, want %d and %s", res.Code, res.Header().Get("ETag"), tt.status, tt.tag)
+			}
+			if tt.status == http.StatusNotModified && res.Body.Len() != 0 {
+				t.Errorf("body %q, want none", res.Body)
+			}
+		})
+	}
+}
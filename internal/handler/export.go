@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"app/internal"
+	"app/internal/loader"
+	"log"
+	"net/http"
+)
+
+// exportFormats are the content types of the formats the vehicles can be exported in
+var exportFormats = map[string]string{
+	"json":   "application/json",
+	"ndjson": "application/x-ndjson",
+	"csv":    "text/csv",
+}
+
+// exportUnsupportedParams are the parameters of the list endpoints an export does not take, it writes every vehicle
+var exportUnsupportedParams = []string{"limit", "offset", "cursor", fieldsParam}
+
+// Export is a method that returns a handler for the route GET /vehicles/export
+// the vehicles are written as a file in ?format=json|csv|ndjson, json by default, with the columns the loaders read,
+// so the file can be imported back; they can be filtered as in GET /vehicles and sorted by ?sort, by id by default,
+// but neither paged nor projected
+func (h *VehicleDefault) Export() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		query := r.URL.Query()
+		format := query.Get("format")
+		if format == "" {
+			format = "json"
+		}
+		contentType, ok := exportFormats[format]
+		if !ok {
+			writeProblem(w, r, malformed("unsupported_export_format", format))
+			return
+		}
+		query.Del("format")
+		for _, param := range exportUnsupportedParams {
+			if query.Has(param) {
+				writeProblem(w, r, malformed("invalid_parameter", param))
+				return
+			}
+		}
+
+		var q internal.VehicleQuery
+		var err error
+		q.Sort, err = parseSort(query.Get("sort"))
+		if err != nil {
+			writeProblem(w, r, err)
+			return
+		}
+		query.Del("sort")
+		q.Filter, err = parseFilter(query)
+		if err != nil {
+			writeProblem(w, r, err)
+			return
+		}
+
+		// process and response
+		// - the vehicles are written one at a time as they are read, with the headers before the first one,
+		// so a failure past them can only cut the file short
+		enc, err := loader.NewVehicleEncoder(w, format)
+		if err != nil {
+			writeProblem(w, r, err)
+			return
+		}
+		started := false
+		start := func() {
+			if started {
+				return
+			}
+			started = true
+			w.Header().Set("Content-Type", contentType)
+			w.Header().Set("Content-Disposition", `attachment; filename="vehicles.`+format+`"`)
+			w.WriteHeader(http.StatusOK)
+		}
+		err = h.sv.Stream(q, func(v internal.Vehicle) (err error) {
+			start()
+			return enc.Encode(v)
+		})
+		if err != nil {
+			if !started {
+				writeProblem(w, r, err)
+				return
+			}
+			log.Println("export vehicles:", err)
+			return
+		}
+		start()
+		if err = enc.Close(); err != nil {
+			log.Println("export vehicles:", err)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// cutWriter is a response writer that fails once n bytes of body have been written
+type cutWriter struct {
+	*httptest.ResponseRecorder
+	n int
+}
+
+func (w *cutWriter) Write(p []byte) (n int, err error) {
+	if len(p) > w.n {
+		n, _ = w.ResponseRecorder.Write(p[:w.n])
+		w.n = 0
+		return n, errors.New("connection reset by peer")
+	}
+	w.n -= len(p)
+	return w.ResponseRecorder.Write(p)
+}
+
+func (w *cutWriter) WriteString(s string) (n int, err error) {
+	return w.Write([]byte(s))
+}
+
+func TestVehicleDefault_Export(t *testing.T) {
+	tests := []struct {
+		name, query string
+		code        int
+		body        string
+	}{
+		{"filtered and sorted", "?format=ndjson&brand=Ford&sort=-id", http.StatusOK, "6 3 "},
+		{"none", "?format=json&brand=Tesla", http.StatusOK, ""},
+		{"limit", "?limit=2", http.StatusBadRequest, localize(LanguagePortuguese, "invalid_parameter", "limit")},
+		{"offset", "?offset=2", http.StatusBadRequest, localize(LanguagePortuguese, "invalid_parameter", "offset")},
+		{"cursor", "?cursor=abc", http.StatusBadRequest, localize(LanguagePortuguese, "invalid_parameter", "cursor")},
+		{"fields", "?fields=id", http.StatusBadRequest, localize(LanguagePortuguese, "invalid_parameter", "fields")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hd, _ := newTestHandler(6)
+
+			res := serve(hd.Export(), "/vehicles/export", http.MethodGet, "/vehicles/export"+tt.query, "")
+
+			if res.Code != tt.code {
+				t.Fatalf("status %d, want %d: %s", res.Code, tt.code, res.Body.String())
+			}
+			if tt.code != http.StatusOK {
+				var p Problem
+				decodeBody(t, res.Body, &p)
+				if p.Detail != tt.body {
+					t.Errorf("detail %q, want %q", p.Detail, tt.body)
+				}
+				return
+			}
+			ids := ""
+			if strings.Contains(tt.query, "ndjson") {
+				dec := json.NewDecoder(res.Body)
+				for dec.More() {
+					var v struct {
+						Id int `json:"id"`
+					}
+					if err := dec.Decode(&v); err != nil {
+						t.Fatal(err)
+					}
+					ids += fmt.Sprint(v.Id) + " "
+				}
+			} else if res.Body.String() != "[]\n" {
+				ids = res.Body.String()
+			}
+			if ids != tt.body {
+				t.Errorf("vehicles %q, want %q", ids, tt.body)
+			}
+		})
+	}
+}
+
+func TestVehicleDefault_Export_CutOff(t *testing.T) {
+	hd, _ := newTestHandler(3)
+	full := serve(hd.Export(), "/vehicles/export", http.MethodGet, "/vehicles/export?format=json", "")
+	if full.Code != http.StatusOK || !strings.HasSuffix(full.Body.String(), "]\n") {
+		t.Fatalf("status %d body %q", full.Code, full.Body.String())
+	}
+
+	tests := []struct {
+		name string
+		n    int
+	}{
+		{"during a vehicle", 10},
+		{"at the closing bracket", full.Body.Len() - 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			defer log.SetOutput(log.Writer())
+			log.SetOutput(&logs)
+			w := &cutWriter{ResponseRecorder: httptest.NewRecorder(), n: tt.n}
+
+			hd.Export()(w, httptest.NewRequest(http.MethodGet, "/vehicles/export?format=json", nil))
+
+			if w.Body.Len() != tt.n || w.Body.String() != full.Body.String()[:tt.n] {
+				t.Errorf("body %q, want the first %d bytes of the export", w.Body.String(), tt.n)
+			}
+			if !strings.Contains(logs.String(), "export vehicles: connection reset by peer") {
+				t.Errorf("log %q", logs.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"app/internal"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// fieldsParam is the parameter of the query with the fields of the vehicles the response is projected to
+const fieldsParam = "fields"
+
+// fieldGroups are the names that stand for several fields of VehicleJSON
+var fieldGroups = map[string][]string{
+	"dimensions": {"height", "length", "width"},
+}
+
+// parseFields is a function that returns the fields a request asks for, e.g. fields=id,brand,dimensions
+// fields is nil when the request asks for every field; empty names, as in fields=id,,brand, are skipped
+func parseFields(r *http.Request) (fields []string, err error) {
+	for _, name := range strings.Split(r.URL.Query().Get(fieldsParam), ",") {
+		if name == "" {
+			continue
+		}
+		names := []string{name}
+		if group, ok := fieldGroups[name]; ok {
+			names = group
+		}
+
+		for _, name := range names {
+			if !slices.Contains(internal.VehicleFields, name) {
+				return nil, malformed("unknown_field", name)
+			}
+			if !slices.Contains(fields, name) {
+				fields = append(fields, name)
+			}
+		}
+	}
+	return fields, nil
+}
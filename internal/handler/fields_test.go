@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestParseFields(t *testing.T) {
+	tests := []struct {
+		name, query string
+		want        []string
+	}{
+		{"every field", "", nil},
+		{"some fields in order", "?fields=brand,id", []string{"brand", "id"}},
+		{"group", "?fields=id,dimensions", []string{"id", "height", "length", "width"}},
+		{"duplicates kept once", "?fields=width,dimensions,width", []string{"width", "height", "length"}},
+		{"empty names skipped", "?fields=id,,brand,", []string{"id", "brand"}},
+		{"only empty names", "?fields=,", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/vehicles"+tt.query, nil)
+
+			fields, err := parseFields(req)
+
+			if err != nil || !slices.Equal(fields, tt.want) || (fields == nil) != (tt.want == nil) {
+				t.Errorf("fields %v, %v; want %v", fields, err, tt.want)
+			}
+		})
+	}
+
+	t.Run("unknown field", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/vehicles?fields=id,owner", nil)
+
+		_, err := parseFields(req)
+
+		if !errors.Is(err, errMalformedRequest) || err.Error() != malformed("unknown_field", "owner").Error() {
+			t.Errorf("error %v, want unknown_field owner", err)
+		}
+	})
+}
+
+func TestVehicleDefault_Fields(t *testing.T) {
+	hd, _ := newTestHandler(2)
+
+	t.Run("vehicle", func(t *testing.T) {
+		res := serve(hd.GetById(), "/vehicles/{id}", http.MethodGet, "/vehicles/1?fields=id,brand,dimensions", "")
+
+		var body struct {
+			Data json.RawMessage `json:"data"`
+		}
+		decodeBody(t, res.Body, &body)
+		// the members keep the order of the fields
+		if want := `{"id":1,"brand":"Fiat","height":150,"length":400,"width":180}`; res.Code != http.StatusOK || string(body.Data) != want {
+			t.Errorf("status %d data %s, want 200 and %s", res.Code, body.Data, want)
+		}
+	})
+
+	t.Run("page", func(t *testing.T) {
+		res := serve(hd.GetAll(), "/vehicles", http.MethodGet, "/vehicles?fields=id,fuel_type", "")
+
+		var body struct {
+			Data []json.RawMessage `json:"data"`
+		}
+		decodeBody(t, res.Body, &body)
+		if res.Code != http.StatusOK || len(body.Data) != 2 {
+			t.Fatalf("status %d data %s, want 200 and 2 vehicles", res.Code, body.Data)
+		}
+		if string(body.Data[0]) != `{"id":1,"fuel_type":"diesel"}` || string(body.Data[1]) != `{"id":2,"fuel_type":"gasoline"}` {
+			t.Errorf("data %s", body.Data)
+		}
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		for _, res := range []*httptest.ResponseRecorder{
+			serve(hd.GetById(), "/vehicles/{id}", http.MethodGet, "/vehicles/1?fields=owner", "", "Accept-Language", "en"),
+			serve(hd.GetAll(), "/vehicles", http.MethodGet, "/vehicles?fields=owner", "", "Accept-Language", "en"),
+		} {
+			var p Problem
+			decodeBody(t, res.Body, &p)
+			if res.Code != http.StatusBadRequest || p.Detail != localize(LanguageEnglish, "unknown_field", "owner") {
+				t.Errorf("status %d detail %q, want 400 about owner", res.Code, p.Detail)
+			}
+		}
+	})
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"app/internal"
+	"errors"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// parseFilter is a function that returns the filter described by the query of a request
+// every parameter is a condition on a field, e.g. brand=Ford, year[gte]=1995 or fuel_type[in]=diesel,gas;
+// a parameter without an operator compares for equality and all conditions must hold;
+// the parameters of the page, see pageParams, and the fields parameter are skipped
+func parseFilter(query url.Values) (f internal.And, err error) {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		if slices.Contains(pageParams, key) || key == fieldsParam {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	f = internal.And{}
+	for _, key := range keys {
+		field, operator := key, internal.OperatorEq
+		if name, rest, ok := strings.Cut(key, "["); ok && strings.HasSuffix(rest, "]") {
+			field, operator = name, strings.TrimSuffix(rest, "]")
+		}
+
+		for _, value := range query[key] {
+			values := []string{value}
+			if operator == internal.OperatorIn {
+				values = strings.Split(value, ",")
+			}
+
+			c, err := internal.NewCondition(field, operator, values...)
+			switch {
+			case errors.Is(err, internal.ErrUnknownField):
+				return nil, malformed("unknown_filter_field", field)
+			case errors.Is(err, internal.ErrUnknownOperator):
+				return nil, malformed("unknown_filter_operator", operator)
+			case err != nil:
+				return nil, malformed("invalid_filter_value", key)
+			}
+			f = append(f, c)
+		}
+	}
+	return f, nil
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"app/internal"
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		name, query string
+		want        internal.And
+	}{
+		{"no conditions", "", internal.And{}},
+		{"equality", "brand=Ford", internal.And{
+			internal.Condition{Field: "brand", Operator: internal.OperatorEq, Values: []any{"Ford"}},
+		}},
+		{"operators", "year[gte]=1995&max_speed[lt]=150.5&color[ne]=Red", internal.And{
+			internal.Condition{Field: "color", Operator: internal.OperatorNe, Values: []any{"Red"}},
+			internal.Condition{Field: "max_speed", Operator: internal.OperatorLt, Values: []any{150.5}},
+			internal.Condition{Field: "year", Operator: internal.OperatorGte, Values: []any{1995.0}},
+		}},
+		{"in splits its values", "fuel_type[in]=diesel,gas", internal.And{
+			internal.Condition{Field: "fuel_type", Operator: internal.OperatorIn, Values: []any{"diesel", "gas"}},
+		}},
+		{"repeated parameter", "year[gte]=1990&year[gte]=2000", internal.And{
+			internal.Condition{Field: "year", Operator: internal.OperatorGte, Values: []any{1990.0}},
+			internal.Condition{Field: "year", Operator: internal.OperatorGte, Values: []any{2000.0}},
+		}},
+		{"page and fields skipped", "limit=10&offset=5&cursor=abc&sort=-year&fields=id,brand&brand=Fiat", internal.And{
+			internal.Condition{Field: "brand", Operator: internal.OperatorEq, Values: []any{"Fiat"}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, _ := url.ParseQuery(tt.query)
+
+			f, err := parseFilter(query)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(f, tt.want) {
+				t.Errorf("filter %v, want %v", f, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFilter_Errors(t *testing.T) {
+	tests := []struct {
+		name, query, code string
+		args              []any
+	}{
+		{"unknown field", "owner=Ana", "unknown_filter_field", []any{"owner"}},
+		{"unknown field with an operator", "owner[ne]=Ana", "unknown_filter_field", []any{"owner"}},
+		{"unknown operator", "year[like]=19", "unknown_filter_operator", []any{"like"}},
+		{"number of a text", "year[gte]=old", "invalid_filter_value", []any{"year[gte]"}},
+		{"number of a list", "weight[in]=900,heavy", "invalid_filter_value", []any{"weight[in]"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, _ := url.ParseQuery(tt.query)
+
+			f, err := parseFilter(query)
+
+			var de *internal.Error
+			if !errors.As(err, &de) || !errors.Is(err, errMalformedRequest) {
+				t.Fatalf("filter %v error %v, want a malformed request", f, err)
+			}
+			if de.Code != tt.code || !reflect.DeepEqual(de.Args, tt.args) {
+				t.Errorf("code %s %v, want %s %v", de.Code, de.Args, tt.code, tt.args)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// LanguagePortuguese is the language of the messages by default
+	LanguagePortuguese = "pt-BR"
+	// LanguageEnglish is the language of the messages for english speaking clients
+	LanguageEnglish = "en"
+)
+
+// catalog holds the messages shown to the clients, by language and code
+// messages missing in a language fall back to LanguagePortuguese
+var catalog = map[string]map[string]string{
+	LanguagePortuguese: {
+		// titles
+		"title.not_found":              "Veículo não encontrado",
+		"title.conflict":               "Conflito com um veículo existente",
+		"title.validation":             "Dados do veículo inválidos",
+		"title.invalid_range":          "Intervalo inválido",
+		"title.precondition_failed":    "Versão do veículo desatualizada",
+		"title.malformed_request":      "Requisição malformada",
+		"title.unsupported_media_type": "Formato não suportado",
+		"title.not_acceptable":         "Formato de resposta não suportado",
+		"title.reload_failed":          "Falha ao recarregar os veículos",
+		"title.too_large":              "Conteúdo grande demais",
+		"title.internal":               "Erro interno do servidor",
+		// not found
+		"vehicle_not_found":      "Veículo não encontrado.",
+		"vehicles_not_found":     "Nenhum veículo encontrado com esses critérios.",
+		"brand_not_found":        "Não foram encontrados veículos dessa marca.",
+		"fuel_type_not_found":    "Não foram encontrados veículos com esse tipo de combustível.",
+		"transmission_not_found": "Não foram encontrados veículos com esse tipo de transmissão.",
+		"dimensions_not_found":   "Não foram encontrados veículos com essas dimensões.",
+		"weight_not_found":       "Não foram encontrados veículos nessa faixa de peso.",
+		// conflict
+		"vehicle_id_conflict":  "Identificador do veículo já existente.",
+		"vehicles_id_conflict": "Algum veículo possui um identificador já existente.",
+		// invalid range
+		"invalid_year_range":       "O ano inicial deve ser menor ou igual ao ano final.",
+		"invalid_dimensions_range": "As dimensões mínimas devem ser menores ou iguais às máximas.",
+		"invalid_weight_range":     "O peso mínimo deve ser menor ou igual ao máximo.",
+		// precondition failed
+		"version_mismatch":   "O veículo foi alterado por outra requisição, a versão atual é %d.",
+		"invalid_if_match":   "O cabeçalho If-Match deve ter ETags fortes do veículo ou *.",
+		"if_match_not_found": "O veículo do cabeçalho If-Match não existe.",
+		// malformed request
+		"malformed_vehicle":       "Dados do veículo mal formatados ou incompletos.",
+		"malformed_vehicles":      "Dados de algum veículo malformados ou incompletos.",
+		"malformed_speed":         "Velocidade malformada ou fora de alcance.",
+		"malformed_fuel_type":     "Tipo de combustível malformado ou não suportado.",
+		"invalid_id":              "Identificador do veículo inválido.",
+		"invalid_parameter":       "Parâmetro %s inválido ou ausente.",
+		"invalid_range_parameter": "Parâmetro %s inválido ou ausente, use o formato min-max.",
+		"unknown_filter_field":    "Não é possível filtrar pelo campo %s, ele não existe.",
+		"unknown_filter_operator": "Operador de filtro %s desconhecido, use eq, ne, lt, lte, gt, gte ou in.",
+		"invalid_filter_value":    "Valor inválido para o filtro %s.",
+		"unknown_sort_field":      "Não é possível ordenar pelo campo %s, ele não existe.",
+		"unknown_field":           "O campo %s não existe.",
+		"immutable_field":         "O campo %s não pode ser alterado.",
+		"malformed_patch":         "Patch malformado.",
+		"invalid_patch_operation": "A operação %d do patch é inválida ou aponta para um caminho inexistente.",
+		"patch_test_failed":       "A operação test %d do patch falhou, o veículo foi alterado por outra requisição.",
+		"unsupported_patch_type":  "Formato de patch %s não suportado, use application/merge-patch+json ou application/json-patch+json.",
+		"invalid_limit":           "O parâmetro limit deve ser um número entre 1 e %d.",
+		"invalid_cursor":          "Cursor inválido, ele deve vir de um link da mesma listagem.",
+		// validation
+		"validation_failed":      "Um ou mais campos do veículo são inválidos.",
+		"batch_invalid":          "Nenhum veículo foi criado, há itens inválidos no lote.",
+		"batch_partial":          "Alguns veículos do lote não foram criados, veja os itens inválidos.",
+		"malformed_bulk":         "Operações em massa malformadas, envie {\"operations\": [...]} com ao menos uma operação.",
+		"unknown_bulk_operation": "Operação %s desconhecida, use create, patch ou delete.",
+		"bulk_target_required":   "A operação deve ter um id ou um filtro where, não ambos.",
+		"unknown_operation":      "Operação %s desconhecida.",
+		// imports
+		"import_not_found":          "Importação não encontrada.",
+		"import_finished":           "A importação já terminou com o status %s.",
+		"invalid_import_id":         "Identificador da importação inválido.",
+		"unsupported_import_format": "Formato de importação %q não suportado, use json, csv ou ndjson.",
+		"import_too_large":          "O arquivo da importação passa de %d MB.",
+		"unsupported_export_format": "Formato de exportação %q não suportado, use json, csv ou ndjson.",
+		"not_acceptable":            "Nenhum dos formatos do cabeçalho Accept é suportado, use %s.",
+		"validation.required":       "O campo %s é obrigatório.",
+		"validation.range":          "O campo %s deve estar entre %g e %g.",
+		"validation.one_of":         "O campo %s deve ser um destes valores: %s.",
+		"validation.not_zeros":      "O campo %s não pode conter apenas zeros.",
+		// admin
+		"reload_failed": "Falha ao recarregar os veículos, os atuais foram mantidos.",
+	},
+	LanguageEnglish: {
+		// titles
+		"title.not_found":              "Vehicle not found",
+		"title.conflict":               "Conflict with an existing vehicle",
+		"title.validation":             "Invalid vehicle data",
+		"title.invalid_range":          "Invalid range",
+		"title.precondition_failed":    "Outdated vehicle version",
+		"title.malformed_request":      "Malformed request",
+		"title.unsupported_media_type": "Unsupported media type",
+		"title.not_acceptable":         "Unsupported response format",
+		"title.reload_failed":          "Failed to reload the vehicles",
+		"title.too_large":              "Content too large",
+		"title.internal":               "Internal server error",
+		// not found
+		"vehicle_not_found":      "Vehicle not found.",
+		"vehicles_not_found":     "No vehicles found matching these criteria.",
+		"brand_not_found":        "No vehicles found for this brand.",
+		"fuel_type_not_found":    "No vehicles found with this fuel type.",
+		"transmission_not_found": "No vehicles found with this transmission type.",
+		"dimensions_not_found":   "No vehicles found with these dimensions.",
+		"weight_not_found":       "No vehicles found in this weight range.",
+		// conflict
+		"vehicle_id_conflict":  "The vehicle id already exists.",
+		"vehicles_id_conflict": "Some vehicle has an id that already exists.",
+		// invalid range
+		"invalid_year_range":       "The start year must be less than or equal to the end year.",
+		"invalid_dimensions_range": "The minimum dimensions must be less than or equal to the maximum ones.",
+		"invalid_weight_range":     "The minimum weight must be less than or equal to the maximum one.",
+		// precondition failed
+		"version_mismatch":   "The vehicle was changed by another request, its current version is %d.",
+		"invalid_if_match":   "The If-Match header must have strong ETags of the vehicle or *.",
+		"if_match_not_found": "The vehicle of the If-Match header does not exist.",
+		// malformed request
+		"malformed_vehicle":       "Malformed or incomplete vehicle data.",
+		"malformed_vehicles":      "Malformed or incomplete data in some vehicle.",
+		"malformed_speed":         "Malformed or out of range speed.",
+		"malformed_fuel_type":     "Malformed or unsupported fuel type.",
+		"invalid_id":              "Invalid vehicle id.",
+		"invalid_parameter":       "Invalid or missing parameter %s.",
+		"invalid_range_parameter": "Invalid or missing parameter %s, use the format min-max.",
+		"unknown_filter_field":    "Cannot filter by the field %s, it does not exist.",
+		"unknown_filter_operator": "Unknown filter operator %s, use eq, ne, lt, lte, gt, gte or in.",
+		"invalid_filter_value":    "Invalid value for the filter %s.",
+		"unknown_sort_field":      "Cannot sort by the field %s, it does not exist.",
+		"unknown_field":           "The field %s does not exist.",
+		"immutable_field":         "The field %s cannot be changed.",
+		"malformed_patch":         "Malformed patch.",
+		"invalid_patch_operation": "The operation %d of the patch is invalid or points to a path that does not exist.",
+		"patch_test_failed":       "The test operation %d of the patch failed, the vehicle was changed by another request.",
+		"unsupported_patch_type":  "Unsupported patch format %s, use application/merge-patch+json or application/json-patch+json.",
+		"invalid_limit":           "The parameter limit must be a number between 1 and %d.",
+		"invalid_cursor":          "Invalid cursor, it must come from a link of the same listing.",
+		// validation
+		"validation_failed":      "One or more fields of the vehicle are invalid.",
+		"batch_invalid":          "No vehicle was created, there are invalid items in the batch.",
+		"batch_partial":          "Some vehicles of the batch were not created, see the invalid items.",
+		"malformed_bulk":         "Malformed bulk operations, send {\"operations\": [...]} with at least one operation.",
+		"unknown_bulk_operation": "Unknown operation %s, use create, patch or delete.",
+		"bulk_target_required":   "The operation must have either an id or a where filter, not both.",
+		"unknown_operation":      "Unknown operation %s.",
+		// imports
+		"import_not_found":          "Import not found.",
+		"import_finished":           "The import already finished with the status %s.",
+		"invalid_import_id":         "Invalid import id.",
+		"unsupported_import_format": "Unsupported import format %q, use json, csv or ndjson.",
+		"import_too_large":          "The import file is larger than %d MB.",
+		"unsupported_export_format": "Unsupported export format %q, use json, csv or ndjson.",
+		"not_acceptable":            "None of the formats of the Accept header is supported, use %s.",
+		"validation.required":       "The field %s is required.",
+		"validation.range":          "The field %s must be between %g and %g.",
+		"validation.one_of":         "The field %s must be one of: %s.",
+		"validation.not_zeros":      "The field %s must not contain only zeros.",
+		// admin
+		"reload_failed": "Failed to reload the vehicles, the current ones were kept.",
+	},
+}
+
+// language is a function that returns the language of the catalog that best matches the Accept-Language header
+// of the request, LanguagePortuguese when none matches
+func language(r *http.Request) string {
+	type tag struct {
+		lang string
+		q    float64
+	}
+	var tags []tag
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		tags = append(tags, tag{lang: strings.ToLower(name), q: q})
+	}
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].q > tags[j].q
+	})
+
+	for _, t := range tags {
+		if t.q <= 0 {
+			continue
+		}
+		switch {
+		case t.lang == "pt" || strings.HasPrefix(t.lang, "pt-"):
+			return LanguagePortuguese
+		case t.lang == "en" || strings.HasPrefix(t.lang, "en-"):
+			return LanguageEnglish
+		}
+	}
+	return LanguagePortuguese
+}
+
+// localize is a function that returns the message of the catalog for code in lang, formatted with args
+// it falls back to LanguagePortuguese and then to the code itself
+func localize(lang, code string, args ...any) string {
+	message, ok := catalog[lang][code]
+	if !ok {
+		message, ok = catalog[LanguagePortuguese][code]
+	}
+	if !ok {
+		return code
+	}
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
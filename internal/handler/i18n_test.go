@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLanguage(t *testing.T) {
+	tests := []struct {
+		name, header, want string
+	}{
+		{"no header", "", LanguagePortuguese},
+		{"english", "en", LanguageEnglish},
+		{"region of english", "en-US", LanguageEnglish},
+		{"portuguese of portugal", "pt-PT", LanguagePortuguese},
+		{"case insensitive", "EN-gb", LanguageEnglish},
+		{"highest q wins", "en;q=0.5, pt-BR;q=0.9", LanguagePortuguese},
+		{"first of equal q wins", "en, pt-BR", LanguageEnglish},
+		{"unsupported ones skipped", "fr, de;q=0.8, en;q=0.1", LanguageEnglish},
+		{"excluded with q=0", "en;q=0", LanguagePortuguese},
+		{"malformed q is 1", "pt;q=x, en;q=0.9", LanguagePortuguese},
+		{"none supported", "fr, de", LanguagePortuguese},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/vehicles", nil)
+			if tt.header != "" {
+				req.Header.Set("Accept-Language", tt.header)
+			}
+
+			if got := language(req); got != tt.want {
+				t.Errorf("language %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	// every message has a portuguese version to fall back to
+	for code := range catalog[LanguageEnglish] {
+		if _, ok := catalog[LanguagePortuguese][code]; !ok {
+			t.Errorf("code %s has no portuguese message", code)
+		}
+	}
+
+	catalog[LanguagePortuguese]["test.only_portuguese"] = "Somente em português, %d."
+	defer delete(catalog[LanguagePortuguese], "test.only_portuguese")
+
+	tests := []struct {
+		name, lang, code string
+		args             []any
+		want             string
+	}{
+		{"english", LanguageEnglish, "vehicle_not_found", nil, "Vehicle not found."},
+		{"portuguese", LanguagePortuguese, "vehicle_not_found", nil, "Veículo não encontrado."},
+		{"formatted", LanguageEnglish, "invalid_parameter", []any{"year"}, "Invalid or missing parameter year."},
+		{"missing in english", LanguageEnglish, "test.only_portuguese", []any{7}, "Somente em português, 7."},
+		{"unknown language", "fr", "vehicle_not_found", nil, "Veículo não encontrado."},
+		{"unknown code", LanguageEnglish, "no_such_code", nil, "no_such_code"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := localize(tt.lang, tt.code, tt.args...); got != tt.want {
+				t.Errorf("message %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"app/internal"
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bootcamp-go/web/response"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxImportSize is the greatest size in bytes of an imported file
+const maxImportSize = 64 << 20
+
+// importFormats are the formats of the files that can be imported, by media type
+var importFormats = map[string]string{
+	"application/json":     "json",
+	"text/csv":             "csv",
+	"application/x-ndjson": "ndjson",
+	"application/ndjson":   "ndjson",
+}
+
+// ImportErrorJSON is a struct that represents a vehicle of an import that could not be created, in JSON format
+type ImportErrorJSON struct {
+	Row    int            `json:"row,omitempty"`
+	Line   int            `json:"line,omitempty"`
+	Detail string         `json:"detail"`
+	Errors []FieldProblem `json:"errors,omitempty"`
+}
+
+// ImportJobJSON is a struct that represents an import job in JSON format
+type ImportJobJSON struct {
+	Id         int               `json:"id"`
+	Format     string            `json:"format"`
+	Status     string            `json:"status"`
+	Processed  int               `json:"processed"`
+	Created    int               `json:"created"`
+	Failed     int               `json:"failed"`
+	Errors     []ImportErrorJSON `json:"errors"`
+	Error      string            `json:"error,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+	FinishedAt *time.Time        `json:"finished_at,omitempty"`
+}
+
+// serializeImportJob is a function that converts an import job into its JSON representation, in the language lang
+func serializeImportJob(ij internal.ImportJob, lang string) ImportJobJSON {
+	data := ImportJobJSON{
+		Id:        ij.Id,
+		Format:    ij.Format,
+		Status:    ij.Status,
+		Processed: ij.Processed,
+		Created:   ij.Created,
+		Failed:    ij.Failed,
+		Errors:    make([]ImportErrorJSON, 0, len(ij.Errors)),
+		CreatedAt: ij.CreatedAt,
+	}
+	if ij.Err != nil {
+		data.Error = ij.Err.Error()
+	}
+	if !ij.FinishedAt.IsZero() {
+		data.FinishedAt = &ij.FinishedAt
+	}
+
+	for _, e := range ij.Errors {
+		item := ImportErrorJSON{Row: e.Row, Line: e.Line, Detail: e.Err.Error()}
+		var ve *internal.ValidationError
+		if errors.As(e.Err, &ve) {
+			item.Detail = localize(lang, "validation_failed")
+			item.Errors = fieldProblems(ve.Issues, lang)
+		}
+		data.Errors = append(data.Errors, item)
+	}
+	return data
+}
+
+// NewImportDefault is a function that returns a new instance of ImportDefault
+func NewImportDefault(im internal.VehicleImporter) *ImportDefault {
+	return &ImportDefault{im: im}
+}
+
+// ImportDefault is a struct with methods that represent handlers for the import jobs
+type ImportDefault struct {
+	// im is the runner of the import jobs
+	im internal.VehicleImporter
+}
+
+// Create is a method that returns a handler for the route POST /imports
+// the body is the file, in the format of its Content-Type or of ?format=json|csv|ndjson;
+// the job runs in the background and the response is 202 Accepted with its location;
+// files larger than maxImportSize are rejected with 413 Content Too Large
+func (h *ImportDefault) Create() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			format = mediaType
+			if f, ok := importFormats[mediaType]; ok {
+				format = f
+			}
+		}
+		supported := false
+		for _, f := range importFormats {
+			supported = supported || f == format
+		}
+		if !supported {
+			writeProblem(w, r, &internal.Error{Kind: errUnsupportedMediaType, Code: "unsupported_import_format", Args: []any{format}})
+			return
+		}
+
+		// process
+		ij, err := h.im.Start(r.Body, format)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			err = &internal.Error{Kind: errTooLarge, Code: "import_too_large", Args: []any{tooLarge.Limit >> 20}}
+		}
+		if err != nil {
+			writeProblem(w, r, err)
+			return
+		}
+
+		// response
+		w.Header().Set("Location", "/imports/"+strconv.Itoa(ij.Id))
+		response.JSON(w, http.StatusAccepted, map[string]any{
+			"message": "success",
+			"data":    serializeImportJob(ij, language(r)),
+		})
+	}
+}
+
+// Get is a method that returns a handler for the route GET /imports/{id}
+func (h *ImportDefault) Get() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			writeProblem(w, r, malformed("invalid_import_id"))
+			return
+		}
+
+		// process
+		ij, err := h.im.FindById(id)
+		if err != nil {
+			writeProblem(w, r, err)
+			return
+		}
+
+		// response
+		writeData(w, r, http.StatusOK, map[string]any{
+			"message": "success",
+			"data":    serializeImportJob(ij, language(r)),
+		})
+	}
+}
+
+// Cancel is a method that returns a handler for the route DELETE /imports/{id}
+// the vehicles already created are kept
+func (h *ImportDefault) Cancel() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			writeProblem(w, r, malformed("invalid_import_id"))
+			return
+		}
+
+		// process
+		ij, err := h.im.Cancel(id)
+		if err != nil {
+			writeProblem(w, r, err)
+			return
+		}
+
+		// response
+		response.JSON(w, http.StatusOK, map[string]any{
+			"message": "success",
+			"data":    serializeImportJob(ij, language(r)),
+		})
+	}
+}
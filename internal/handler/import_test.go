@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"app/internal"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// importerStub is an importer that reads the whole file and starts a job with its format
+type importerStub struct {
+	format string
+	size   int64
+}
+
+func (s *importerStub) Start(rd io.Reader, format string) (ij internal.ImportJob, err error) {
+	s.format = format
+	s.size, err = io.Copy(io.Discard, rd)
+	if err != nil {
+		return
+	}
+	return internal.ImportJob{Id: 3, Format: format, Status: internal.ImportPending}, nil
+}
+
+func (s *importerStub) FindById(id int) (ij internal.ImportJob, err error) {
+	return internal.ImportJob{}, internal.NotFound("import_not_found")
+}
+
+func (s *importerStub) Cancel(id int) (ij internal.ImportJob, err error) {
+	return internal.ImportJob{}, internal.NotFound("import_not_found")
+}
+
+// zeros is a reader of endless zeros
+type zeros struct{}
+
+func (zeros) Read(p []byte) (n int, err error) {
+	clear(p)
+	return len(p), nil
+}
+
+func TestImportDefault_Create(t *testing.T) {
+	tests := []struct {
+		name, query, contentType string
+		status                   int
+		format                   string
+	}{
+		{"format of the content type", "", "text/csv; charset=utf-8", http.StatusAccepted, "csv"},
+		{"format of the query", "?format=ndjson", "application/octet-stream", http.StatusAccepted, "ndjson"},
+		{"ndjson media type", "", "application/x-ndjson", http.StatusAccepted, "ndjson"},
+		{"unsupported format", "?format=xml", "", http.StatusUnsupportedMediaType, ""},
+		{"unsupported content type", "", "text/plain", http.StatusUnsupportedMediaType, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			im := &importerStub{}
+			hd := NewImportDefault(im)
+
+			res := serve(hd.Create(), "/imports", http.MethodPost, "/imports"+tt.query, "id\n1\n", "Content-Type", tt.contentType)
+
+			if res.Code != tt.status || im.format != tt.format {
+				t.Errorf("status %d format %q, want %d and %q", res.Code, im.format, tt.status, tt.format)
+			}
+			if tt.status == http.StatusAccepted && res.Header().Get("Location") != "/imports/3" {
+				t.Errorf("location %q, want /imports/3", res.Header().Get("Location"))
+			}
+		})
+	}
+
+	t.Run("too large", func(t *testing.T) {
+		im := &importerStub{}
+		rt := chi.NewRouter()
+		rt.Post("/imports", NewImportDefault(im).Create())
+		req := httptest.NewRequest(http.MethodPost, "/imports?format=csv", io.LimitReader(zeros{}, maxImportSize+1))
+		req.Header.Set("Accept-Language", "en")
+		res := httptest.NewRecorder()
+
+		rt.ServeHTTP(res, req)
+
+		var p Problem
+		decodeBody(t, res.Body, &p)
+		if res.Code != http.StatusRequestEntityTooLarge || p.Type != "/problems/too-large" {
+			t.Errorf("status %d type %s, want 413 and /problems/too-large", res.Code, p.Type)
+		}
+		if want := localize(LanguageEnglish, "import_too_large", int64(maxImportSize>>20)); p.Detail != want {
+			t.Errorf("detail %q, want %q", p.Detail, want)
+		}
+		// the file is not read past the limit
+		if im.size != maxImportSize {
+			t.Errorf("%d bytes read, want %d", im.size, maxImportSize)
+		}
+	})
+
+	t.Run("at the limit", func(t *testing.T) {
+		im := &importerStub{}
+		rt := chi.NewRouter()
+		rt.Post("/imports", NewImportDefault(im).Create())
+		req := httptest.NewRequest(http.MethodPost, "/imports?format=csv", io.LimitReader(zeros{}, maxImportSize))
+		res := httptest.NewRecorder()
+
+		rt.ServeHTTP(res, req)
+
+		if res.Code != http.StatusAccepted || im.size != maxImportSize {
+			t.Errorf("status %d after %d bytes, want 202 after %d", res.Code, im.size, maxImportSize)
+		}
+	})
+}
+
+func TestImportDefault_Get(t *testing.T) {
+	hd := NewImportDefault(&importerStub{})
+
+	for _, tt := range []struct {
+		target string
+		status int
+	}{
+		{"/imports/7", http.StatusNotFound},
+		{"/imports/seven", http.StatusBadRequest},
+	} {
+		res := serve(hd.Get(), "/imports/{id}", http.MethodGet, tt.target, "")
+
+		if res.Code != tt.status || !strings.HasPrefix(res.Header().Get("Content-Type"), ProblemContentType) {
+			t.Errorf("%s: status %d, want a %d problem", tt.target, res.Code, tt.status)
+		}
+	}
+}
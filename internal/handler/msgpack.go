@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"io"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// encodeMsgPack is a function that writes a body in MessagePack format, with the names of its JSON representation
+// the fields of the structs follow their json tags and the maps their keys sorted; the numbers keep their Go type,
+// integers take the smallest encoding that holds them and float64 fields are always 64-bit floats
+func encodeMsgPack(w io.Writer, body any) (err error) {
+	enc := msgpack.NewEncoder(w)
+	enc.SetCustomStructTag("json")
+	enc.SetSortMapKeys(true)
+	enc.UseCompactInts(true)
+	return enc.Encode(body)
+}
+
+// EncodeMsgpack is a method that writes the items of the rows as a MessagePack array
+func (rs rows) EncodeMsgpack(enc *msgpack.Encoder) (err error) {
+	return enc.Encode(rs.items)
+}
+
+// EncodeMsgpack is a method that writes the object as a MessagePack map, with its members in order
+func (o object) EncodeMsgpack(enc *msgpack.Encoder) (err error) {
+	if err = enc.EncodeMapLen(len(o)); err != nil {
+		return
+	}
+	for _, m := range o {
+		if err = enc.EncodeString(m.Name); err != nil {
+			return
+		}
+		if err = enc.Encode(m.Value); err != nil {
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"app/internal"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	// defaultLimit is the number of vehicles of a page when the request does not ask for one
+	defaultLimit = 100
+	// maxLimit is the greatest number of vehicles of a page
+	maxLimit = 1000
+)
+
+// pageParams are the parameters of the query that shape the page instead of filtering the vehicles
+var pageParams = []string{"limit", "offset", "cursor", "sort"}
+
+// cursorJSON is a struct that represents a cursor in the opaque text given to the clients
+type cursorJSON struct {
+	// Sort is the sort parameter of the request the cursor was made for
+	Sort     string `json:"s,omitempty"`
+	Values   []any  `json:"v,omitempty"`
+	Id       int    `json:"id"`
+	Backward bool   `json:"b,omitempty"`
+}
+
+// encodeCursor is a function that returns the opaque text of a cursor
+func encodeCursor(c internal.Cursor, sort string) string {
+	data, _ := json.Marshal(cursorJSON{Sort: sort, Values: c.Values, Id: c.Id, Backward: c.Backward})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor is a function that returns the cursor of an opaque text
+// the cursor must have been made for the same sort
+func decodeCursor(text, sort string) (c internal.Cursor, err error) {
+	data, err := base64.RawURLEncoding.DecodeString(text)
+	if err != nil {
+		return internal.Cursor{}, malformed("invalid_cursor")
+	}
+
+	var cj cursorJSON
+	if err := json.Unmarshal(data, &cj); err != nil || cj.Sort != sort {
+		return internal.Cursor{}, malformed("invalid_cursor")
+	}
+
+	return internal.Cursor{Values: cj.Values, Id: cj.Id, Backward: cj.Backward}, nil
+}
+
+// parseSort is a function that returns the sort keys of a sort parameter, e.g. -max_speed,brand
+// a leading - sorts the field in descending order; empty names, as in sort=brand,, are skipped
+func parseSort(text string) (keys []internal.SortKey, err error) {
+	for _, name := range strings.Split(text, ",") {
+		if name == "" {
+			continue
+		}
+		key := internal.SortKey{Field: strings.TrimPrefix(name, "-"), Descending: strings.HasPrefix(name, "-")}
+		if !slices.Contains(internal.VehicleFields, key.Field) {
+			return nil, malformed("unknown_sort_field", key.Field)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// parsePage is a function that returns the query with the page a request asks for, without a filter
+// it also checks the fields the page is projected to, see parseFields
+func parsePage(r *http.Request) (q internal.VehicleQuery, err error) {
+	params := r.URL.Query()
+
+	if _, err = parseFields(r); err != nil {
+		return internal.VehicleQuery{}, err
+	}
+
+	q.Sort, err = parseSort(params.Get("sort"))
+	if err != nil {
+		return internal.VehicleQuery{}, err
+	}
+
+	q.Limit = defaultLimit
+	if params.Has("limit") {
+		q.Limit, err = strconv.Atoi(params.Get("limit"))
+		if err != nil || q.Limit < 1 || q.Limit > maxLimit {
+			return internal.VehicleQuery{}, malformed("invalid_limit", maxLimit)
+		}
+	}
+
+	if params.Has("offset") {
+		q.Offset, err = strconv.Atoi(params.Get("offset"))
+		if err != nil || q.Offset < 0 {
+			return internal.VehicleQuery{}, malformed("invalid_parameter", "offset")
+		}
+	}
+
+	if params.Has("cursor") {
+		c, err := decodeCursor(params.Get("cursor"), params.Get("sort"))
+		if err != nil {
+			return internal.VehicleQuery{}, err
+		}
+		q.Cursor = &c
+	}
+
+	return q, nil
+}
+
+// parseQuery is a function that returns the query a request asks for, with the page and the filter
+func parseQuery(r *http.Request) (q internal.VehicleQuery, err error) {
+	q, err = parsePage(r)
+	if err != nil {
+		return internal.VehicleQuery{}, err
+	}
+
+	q.Filter, err = parseFilter(r.URL.Query())
+	if err != nil {
+		return internal.VehicleQuery{}, err
+	}
+
+	return q, nil
+}
+
+// pageLinks is a struct that represents the links to a page and its neighbours
+type pageLinks struct {
+	Self string `json:"self"`
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+// link is a function that returns the link to the request with some parameters changed
+// the parameters with an empty value are removed
+func link(r *http.Request, params map[string]string) string {
+	query := r.URL.Query()
+	for key, value := range params {
+		if value == "" {
+			query.Del(key)
+			continue
+		}
+		query.Set(key, value)
+	}
+
+	u := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+	return u.String()
+}
+
+// writePage is a function that writes a page of vehicles with its total and the links to its neighbours
+// the links page by offset when the request did, by cursor otherwise; the total and the links are also
+// in the X-Total-Count and Link headers, and the body is in the format the request accepts, see writeData
+func writePage(w http.ResponseWriter, r *http.Request, q internal.VehicleQuery, p internal.VehiclePage) {
+	// the fields were checked by parsePage
+	fields, _ := parseFields(r)
+
+	data := rows{columns: fields, items: make([]any, 0, len(p.Vehicles))}
+	if fields == nil {
+		data.columns = columnsOf(VehicleJSON{})
+	}
+	for _, v := range p.Vehicles {
+		data.items = append(data.items, project(v, fields))
+	}
+
+	links := pageLinks{Self: r.URL.RequestURI()}
+	sort := r.URL.Query().Get("sort")
+	if r.URL.Query().Has("offset") {
+		if p.Offset+len(p.Vehicles) < p.Total {
+			links.Next = link(r, map[string]string{"offset": strconv.Itoa(p.Offset + len(p.Vehicles))})
+		}
+		if p.Offset > 0 {
+			links.Prev = link(r, map[string]string{"offset": strconv.Itoa(max(p.Offset-q.Limit, 0))})
+		}
+	} else {
+		if p.Next != nil {
+			links.Next = link(r, map[string]string{"cursor": encodeCursor(*p.Next, sort)})
+		}
+		if p.Prev != nil {
+			links.Prev = link(r, map[string]string{"cursor": encodeCursor(*p.Prev, sort)})
+		}
+	}
+
+	// - the headers, for the formats without an envelope
+	w.Header().Set("X-Total-Count", strconv.Itoa(p.Total))
+	var rels []string
+	if links.Next != "" {
+		rels = append(rels, `<`+links.Next+`>; rel="next"`)
+	}
+	if links.Prev != "" {
+		rels = append(rels, `<`+links.Prev+`>; rel="prev"`)
+	}
+	if len(rels) > 0 {
+		w.Header().Set("Link", strings.Join(rels, ", "))
+	}
+
+	writeData(w, r, http.StatusOK, map[string]any{
+		"message": "success",
+		"data":    data,
+		"total":   p.Total,
+		"limit":   q.Limit,
+		"offset":  p.Offset,
+		"links":   links,
+	})
+}
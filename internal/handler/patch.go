@@ -0,0 +1,273 @@
+package handler
+
+import (
+	"app/internal"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MergePatchContentType is the media type of the JSON Merge Patch documents of RFC 7396
+	MergePatchContentType = "application/merge-patch+json"
+	// JSONPatchContentType is the media type of the JSON Patch documents of RFC 6902
+	JSONPatchContentType = "application/json-patch+json"
+)
+
+// errPatchPath is the error returned when the path of a patch operation does not lead to a value
+var errPatchPath = errors.New("path not found")
+
+// patchOperation is a struct that represents an operation of a JSON Patch document
+type patchOperation struct {
+	Op   string `json:"op"`
+	Path string `json:"path"`
+	From string `json:"from"`
+	// Value is raw so that a missing value can be told apart from null
+	Value json.RawMessage `json:"value"`
+}
+
+// mergePatch is a function that applies a JSON Merge Patch to a document
+// the members of the patch set to null are removed from the document
+func mergePatch(doc, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	d, ok := doc.(map[string]any)
+	if !ok {
+		d = make(map[string]any)
+	}
+	for key, value := range p {
+		if value == nil {
+			delete(d, key)
+			continue
+		}
+		d[key] = mergePatch(d[key], value)
+	}
+	return d
+}
+
+// jsonPatch is a function that applies the operations of a JSON Patch document to a document, in order
+// a failed test stops the patch with an error of kind ErrVehicleConflict
+func jsonPatch(doc any, ops []patchOperation) (any, error) {
+	for i, op := range ops {
+		path, err := parsePointer(op.Path)
+		if err != nil {
+			return nil, malformed("invalid_patch_operation", i)
+		}
+
+		var value any
+		switch op.Op {
+		case "add", "replace", "test":
+			if len(op.Value) == 0 || json.Unmarshal(op.Value, &value) != nil {
+				return nil, malformed("invalid_patch_operation", i)
+			}
+		case "move", "copy":
+			from, err := parsePointer(op.From)
+			if err != nil {
+				return nil, malformed("invalid_patch_operation", i)
+			}
+			value, err = pointerGet(doc, from)
+			if err != nil {
+				return nil, malformed("invalid_patch_operation", i)
+			}
+			if op.Op == "move" {
+				doc, _ = pointerRemove(doc, from)
+			}
+		case "remove":
+		default:
+			return nil, malformed("invalid_patch_operation", i)
+		}
+
+		switch op.Op {
+		case "add", "move", "copy":
+			doc, err = pointerAdd(doc, path, value)
+		case "remove":
+			doc, err = pointerRemove(doc, path)
+		case "replace":
+			doc, err = pointerRemove(doc, path)
+			if err == nil {
+				doc, err = pointerAdd(doc, path, value)
+			}
+		case "test":
+			var current any
+			current, err = pointerGet(doc, path)
+			if err == nil && !reflect.DeepEqual(current, value) {
+				return nil, internal.Conflict("patch_test_failed", i)
+			}
+		}
+		if err != nil {
+			return nil, malformed("invalid_patch_operation", i)
+		}
+	}
+	return doc, nil
+}
+
+// parsePointer is a function that returns the reference tokens of a JSON Pointer, e.g. /max_speed
+func parsePointer(pointer string) (tokens []string, err error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, errPatchPath
+	}
+
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.ReplaceAll(token, "~1", "/")
+		token = strings.ReplaceAll(token, "~0", "~")
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+// arrayIndex is a function that returns the index of an array a token refers to
+// the end of the array, "-" or its length, is only valid when appending
+func arrayIndex(token string, length int, appending bool) (i int, err error) {
+	if appending && token == "-" {
+		return length, nil
+	}
+
+	i, err = strconv.Atoi(token)
+	if err != nil || i < 0 || i > length || (i == length && !appending) {
+		return 0, errPatchPath
+	}
+	return i, nil
+}
+
+// pointerGet is a function that returns the value of a document at the path of the tokens
+func pointerGet(doc any, tokens []string) (any, error) {
+	for _, token := range tokens {
+		switch c := doc.(type) {
+		case map[string]any:
+			value, ok := c[token]
+			if !ok {
+				return nil, errPatchPath
+			}
+			doc = value
+		case []any:
+			i, err := arrayIndex(token, len(c), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = c[i]
+		default:
+			return nil, errPatchPath
+		}
+	}
+	return doc, nil
+}
+
+// pointerAdd is a function that returns the document with a value added at the path of the tokens
+// members of objects are set and values of arrays are inserted
+func pointerAdd(doc any, tokens []string, value any) (any, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	last := len(tokens) == 1
+	switch c := doc.(type) {
+	case map[string]any:
+		if last {
+			c[tokens[0]] = value
+			return c, nil
+		}
+		child, ok := c[tokens[0]]
+		if !ok {
+			return nil, errPatchPath
+		}
+		child, err := pointerAdd(child, tokens[1:], value)
+		c[tokens[0]] = child
+		return c, err
+	case []any:
+		i, err := arrayIndex(tokens[0], len(c), last)
+		if err != nil {
+			return nil, err
+		}
+		if last {
+			return slices.Insert(c, i, value), nil
+		}
+		c[i], err = pointerAdd(c[i], tokens[1:], value)
+		return c, err
+	}
+	return nil, errPatchPath
+}
+
+// pointerRemove is a function that returns the document without the value at the path of the tokens
+func pointerRemove(doc any, tokens []string) (any, error) {
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	last := len(tokens) == 1
+	switch c := doc.(type) {
+	case map[string]any:
+		child, ok := c[tokens[0]]
+		if !ok {
+			return nil, errPatchPath
+		}
+		if last {
+			delete(c, tokens[0])
+			return c, nil
+		}
+		child, err := pointerRemove(child, tokens[1:])
+		c[tokens[0]] = child
+		return c, err
+	case []any:
+		i, err := arrayIndex(tokens[0], len(c), false)
+		if err != nil {
+			return nil, err
+		}
+		if last {
+			return slices.Delete(c, i, i+1), nil
+		}
+		c[i], err = pointerRemove(c[i], tokens[1:])
+		return c, err
+	}
+	return nil, errPatchPath
+}
+
+// patchVehicle is a function that returns a vehicle changed by a patch of its JSON representation
+// the patched representation must only have fields of VehicleJSON and keep the id
+func patchVehicle(v internal.Vehicle, patch func(doc any) (any, error)) (internal.Vehicle, error) {
+	data, err := json.Marshal(toVehicleJSON(v))
+	if err != nil {
+		return internal.Vehicle{}, err
+	}
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return internal.Vehicle{}, err
+	}
+
+	doc, err = patch(doc)
+	if err != nil {
+		return internal.Vehicle{}, err
+	}
+
+	fields, ok := doc.(map[string]any)
+	if !ok {
+		return internal.Vehicle{}, malformed("malformed_vehicle")
+	}
+	for name, value := range fields {
+		if !slices.Contains(internal.VehicleFields, name) {
+			return internal.Vehicle{}, malformed("unknown_field", name)
+		}
+		if name == "id" && value != float64(v.Id) {
+			return internal.Vehicle{}, malformed("immutable_field", name)
+		}
+	}
+
+	data, err = json.Marshal(fields)
+	if err != nil {
+		return internal.Vehicle{}, err
+	}
+	var input VehicleJSON
+	if err := json.Unmarshal(data, &input); err != nil {
+		return internal.Vehicle{}, malformed("malformed_vehicle")
+	}
+
+	return internal.Vehicle{Id: v.Id, VehicleAttributes: fromVehicleJSON(input)}, nil
+}
@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"app/internal"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// decode is a function that returns the value of a JSON document
+func decode(t *testing.T, doc string) (v any) {
+	t.Helper()
+	if err := json.Unmarshal([]byte(doc), &v); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name, doc, patch, want string
+	}{
+		{"sets a member", `{"a": 1, "b": 2}`, `{"a": 3}`, `{"a": 3, "b": 2}`},
+		{"adds a member", `{"a": 1}`, `{"b": 2}`, `{"a": 1, "b": 2}`},
+		{"removes a member set to null", `{"a": 1, "b": 2}`, `{"a": null}`, `{"b": 2}`},
+		{"merges nested objects", `{"a": {"b": 1, "c": 2}}`, `{"a": {"b": null, "d": 3}}`, `{"a": {"c": 2, "d": 3}}`},
+		{"replaces arrays", `{"a": [1, 2]}`, `{"a": [3]}`, `{"a": [3]}`},
+		{"replaces a value by an object", `{"a": 1}`, `{"a": {"b": null, "c": 1}}`, `{"a": {"c": 1}}`},
+		{"replaces the document by a value", `{"a": 1}`, `[1]`, `[1]`},
+		{"ignores an empty patch", `{"a": 1}`, `{}`, `{"a": 1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergePatch(decode(t, tt.doc), decode(t, tt.patch))
+
+			if want := decode(t, tt.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	tests := []struct {
+		name, doc, ops, want string
+	}{
+		{"add a member", `{"a": 1}`, `[{"op": "add", "path": "/b", "value": 2}]`, `{"a": 1, "b": 2}`},
+		{"add to an array", `{"a": [1, 3]}`, `[{"op": "add", "path": "/a/1", "value": 2}]`, `{"a": [1, 2, 3]}`},
+		{"append to an array", `{"a": [1]}`, `[{"op": "add", "path": "/a/-", "value": 2}]`, `{"a": [1, 2]}`},
+		{"add null", `{"a": 1}`, `[{"op": "add", "path": "/a", "value": null}]`, `{"a": null}`},
+		{"remove", `{"a": 1, "b": 2}`, `[{"op": "remove", "path": "/a"}]`, `{"b": 2}`},
+		{"remove from an array", `{"a": [1, 2, 3]}`, `[{"op": "remove", "path": "/a/0"}]`, `{"a": [2, 3]}`},
+		{"replace", `{"a": 1}`, `[{"op": "replace", "path": "/a", "value": "x"}]`, `{"a": "x"}`},
+		{"move", `{"a": 1}`, `[{"op": "move", "from": "/a", "path": "/b"}]`, `{"b": 1}`},
+		{"copy", `{"a": {"b": 1}}`, `[{"op": "copy", "from": "/a/b", "path": "/c"}]`, `{"a": {"b": 1}, "c": 1}`},
+		{"escaped tokens", `{"a/b": 1, "c~d": 2}`, `[{"op": "remove", "path": "/a~1b"}, {"op": "replace", "path": "/c~0d", "value": 3}]`, `{"c~d": 3}`},
+		{"passed test", `{"a": [1, {"b": 2}]}`, `[{"op": "test", "path": "/a", "value": [1, {"b": 2}]}, {"op": "add", "path": "/c", "value": 3}]`, `{"a": [1, {"b": 2}], "c": 3}`},
+		{"operations in order", `{"a": 1}`, `[{"op": "add", "path": "/b", "value": 2}, {"op": "move", "from": "/b", "path": "/c"}, {"op": "remove", "path": "/a"}]`, `{"c": 2}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ops []patchOperation
+			if err := json.Unmarshal([]byte(tt.ops), &ops); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := jsonPatch(decode(t, tt.doc), ops)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := decode(t, tt.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestJSONPatch_Errors(t *testing.T) {
+	tests := []struct {
+		name, ops string
+		kind      error
+		index     int
+	}{
+		{"failed test", `[{"op": "test", "path": "/a", "value": 2}]`, internal.ErrVehicleConflict, 0},
+		{"unknown operation", `[{"op": "upsert", "path": "/a", "value": 2}]`, errMalformedRequest, 0},
+		{"missing value", `[{"op": "add", "path": "/b"}]`, errMalformedRequest, 0},
+		{"pointer without slash", `[{"op": "remove", "path": "a"}]`, errMalformedRequest, 0},
+		{"missing member", `[{"op": "remove", "path": "/b"}]`, errMalformedRequest, 0},
+		{"replace of a missing member", `[{"op": "replace", "path": "/b", "value": 1}]`, errMalformedRequest, 0},
+		{"index out of range", `[{"op": "add", "path": "/c/5", "value": 1}]`, errMalformedRequest, 0},
+		{"move from a missing member", `[{"op": "move", "from": "/b", "path": "/c"}]`, errMalformedRequest, 0},
+		{"second operation", `[{"op": "add", "path": "/b", "value": 1}, {"op": "test", "path": "/b", "value": 2}]`, internal.ErrVehicleConflict, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ops []patchOperation
+			if err := json.Unmarshal([]byte(tt.ops), &ops); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := jsonPatch(decode(t, `{"a": 1, "c": [1]}`), ops)
+
+			var e *internal.Error
+			if !errors.Is(err, tt.kind) || !errors.As(err, &e) || len(e.Args) == 0 || e.Args[0] != tt.index {
+				t.Errorf("error %v, want one of kind %v for operation %d", err, tt.kind, tt.index)
+			}
+		})
+	}
+}
+
+func TestPatchVehicle(t *testing.T) {
+	vh := internal.Vehicle{Id: 1, VehicleAttributes: internal.VehicleAttributes{Brand: "Ford", MaxSpeed: 100}}
+
+	t.Run("changes the fields", func(t *testing.T) {
+		got, err := patchVehicle(vh, func(doc any) (any, error) {
+			return mergePatch(doc, decode(t, `{"max_speed": 150, "color": "Red"}`)), nil
+		})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.MaxSpeed != 150 || got.Color != "Red" || got.Brand != "Ford" || got.Id != 1 {
+			t.Errorf("got %+v", got)
+		}
+	})
+
+	t.Run("keeps the id", func(t *testing.T) {
+		_, err := patchVehicle(vh, func(doc any) (any, error) {
+			return mergePatch(doc, decode(t, `{"id": 2}`)), nil
+		})
+
+		if err == nil {
+			t.Errorf("id changed")
+		}
+	})
+}
+
+func TestVehicleDefault_Patch(t *testing.T) {
+	tests := []struct {
+		name, contentType, body string
+		status                  int
+		detail, color           string
+	}{
+		{"merge patch", MergePatchContentType, `{"color": "Red", "weight": null}`, http.StatusOK, "", "Red"},
+		{"json patch", JSONPatchContentType, `[{"op": "test", "path": "/color", "value": "Blue"}, {"op": "replace", "path": "/color", "value": "Red"}]`, http.StatusOK, "", "Red"},
+		{"json patch with parameters", JSONPatchContentType + "; charset=utf-8", `[{"op": "replace", "path": "/color", "value": "Red"}]`, http.StatusOK, "", "Red"},
+		{"unsupported patch type", "application/json", `{"color": "Red"}`, http.StatusUnsupportedMediaType,
+			localize(LanguagePortuguese, "unsupported_patch_type", "application/json"), "Blue"},
+		{"malformed merge patch", MergePatchContentType, `{"color": `, http.StatusBadRequest, localize(LanguagePortuguese, "malformed_patch"), "Blue"},
+		{"malformed json patch", JSONPatchContentType, `{"op": "replace"}`, http.StatusBadRequest, localize(LanguagePortuguese, "malformed_patch"), "Blue"},
+		{"immutable id of a merge patch", MergePatchContentType, `{"id": 7, "color": "Red"}`, http.StatusBadRequest,
+			localize(LanguagePortuguese, "immutable_field", "id"), "Blue"},
+		{"immutable id of a json patch", JSONPatchContentType, `[{"op": "replace", "path": "/color", "value": "Red"}, {"op": "replace", "path": "/id", "value": 7}]`, http.StatusBadRequest,
+			localize(LanguagePortuguese, "immutable_field", "id"), "Blue"},
+		{"unknown field", MergePatchContentType, `{"wheels": 4}`, http.StatusBadRequest, localize(LanguagePortuguese, "unknown_field", "wheels"), "Blue"},
+		{"failed test", JSONPatchContentType, `[{"op": "replace", "path": "/color", "value": "Red"}, {"op": "test", "path": "/brand", "value": "Kia"}]`, http.StatusConflict,
+			localize(LanguagePortuguese, "patch_test_failed", 1), "Blue"},
+		{"path out of range", JSONPatchContentType, `[{"op": "replace", "path": "/color/3", "value": "R"}]`, http.StatusBadRequest,
+			localize(LanguagePortuguese, "invalid_patch_operation", 0), "Blue"},
+		{"missing path", JSONPatchContentType, `[{"op": "remove", "path": "/wheels"}]`, http.StatusBadRequest,
+			localize(LanguagePortuguese, "invalid_patch_operation", 0), "Blue"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hd, rp := newTestHandler(2)
+
+			res := serve(hd.Patch(), "/vehicles/{id}", http.MethodPatch, "/vehicles/1", tt.body, "Content-Type", tt.contentType)
+
+			if res.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", res.Code, tt.status, res.Body.String())
+			}
+			if tt.detail != "" {
+				var p Problem
+				decodeBody(t, res.Body, &p)
+				if p.Detail != tt.detail {
+					t.Errorf("detail %q, want %q", p.Detail, tt.detail)
+				}
+			}
+			// a failed patch changes nothing
+			vh, err := rp.FindById(1)
+			if err != nil || vh.Color != tt.color || vh.Brand != testAttributes(1).Brand {
+				t.Errorf("vehicle %+v, %v; want color %s", vh, err, tt.color)
+			}
+		})
+	}
+
+	t.Run("missing vehicle", func(t *testing.T) {
+		hd, _ := newTestHandler(2)
+
+		res := serve(hd.Patch(), "/vehicles/{id}", http.MethodPatch, "/vehicles/9", `{"color": "Red"}`, "Content-Type", MergePatchContentType)
+
+		if res.Code != http.StatusNotFound {
+			t.Errorf("status %d, want 404", res.Code)
+		}
+	})
+}
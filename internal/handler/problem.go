@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"app/internal"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// ProblemContentType is the media type of the error responses
+const ProblemContentType = "application/problem+json"
+
+// Problem is a struct that represents an error response in the format of RFC 7807
+type Problem struct {
+	// Type identifies the kind of problem
+	Type string `json:"type"`
+	// Title is a short summary of the kind of problem
+	Title string `json:"title"`
+	// Status is the HTTP status code
+	Status int `json:"status"`
+	// Detail explains this occurrence of the problem
+	Detail string `json:"detail,omitempty"`
+	// Instance is the path of the request that caused the problem
+	Instance string `json:"instance,omitempty"`
+	// Errors are the problems of each field, for validation problems
+	Errors []FieldProblem `json:"errors,omitempty"`
+	// Operation is the position of the operation that caused the problem, for problems of bulks
+	Operation *int `json:"operation,omitempty"`
+	// Items are the outcomes of the items of a batch, for batches that created no vehicle
+	Items []BatchItemJSON `json:"items,omitempty"`
+}
+
+// FieldProblem is a struct that represents the problem of a field of the request
+type FieldProblem struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// problemTypes are the type and the code of the title of the problems, by error kind
+var problemTypes = []struct {
+	kind  error
+	typ   string
+	title string
+}{
+	{internal.ErrVehicleNotFound, "/problems/not-found", "title.not_found"},
+	{internal.ErrVehicleConflict, "/problems/conflict", "title.conflict"},
+	{internal.ErrVehicleInvalid, "/problems/validation", "title.validation"},
+	{internal.ErrInvalidRange, "/problems/invalid-range", "title.invalid_range"},
+	{internal.ErrPreconditionFailed, "/problems/precondition-failed", "title.precondition_failed"},
+	{errMalformedRequest, "/problems/malformed-request", "title.malformed_request"},
+	{errUnsupportedMediaType, "/problems/unsupported-media-type", "title.unsupported_media_type"},
+	{errNotAcceptable, "/problems/not-acceptable", "title.not_acceptable"},
+	{errReloadFailed, "/problems/reload-failed", "title.reload_failed"},
+	{errTooLarge, "/problems/too-large", "title.too_large"},
+}
+
+// newProblem is a function that returns the problem that describes an error, in the language lang
+// unexpected errors are not detailed to the client
+func newProblem(r *http.Request, err error, lang string) (p Problem) {
+	p = Problem{
+		Type:     "/problems/internal",
+		Title:    localize(lang, "title.internal"),
+		Status:   statusOf(err),
+		Instance: r.URL.RequestURI(),
+	}
+	for _, pt := range problemTypes {
+		if errors.Is(err, pt.kind) {
+			p.Type = pt.typ
+			p.Title = localize(lang, pt.title)
+			break
+		}
+	}
+
+	var de *internal.Error
+	if errors.As(err, &de) {
+		p.Detail = localize(lang, de.Code, de.Args...)
+	}
+
+	var ve *internal.ValidationError
+	if errors.As(err, &ve) {
+		p.Detail = localize(lang, "validation_failed")
+		p.Errors = fieldProblems(ve.Issues, lang)
+	}
+
+	var oe *internal.OperationError
+	if errors.As(err, &oe) {
+		p.Operation = &oe.Index
+	}
+
+	var be *batchError
+	if errors.As(err, &be) {
+		p.Items = serializeBatch(be.Results, lang)
+	}
+	return
+}
+
+// fieldProblems is a function that returns the problems of each field for the issues of a vehicle, in the language lang
+func fieldProblems(issues []internal.ValidationIssue, lang string) (fps []FieldProblem) {
+	for _, is := range issues {
+		// rules without a message in the catalog keep their own
+		message := is.Message
+		if _, ok := catalog[LanguagePortuguese]["validation."+is.Code]; ok {
+			message = localize(lang, "validation."+is.Code, is.Args...)
+		}
+		fps = append(fps, FieldProblem{
+			Field:   is.Field,
+			Code:    is.Code,
+			Message: message,
+		})
+	}
+	return
+}
+
+// writeProblem is a function that writes the error response for err as application/problem+json
+// it is the only way the vehicle handlers report errors
+// the texts are in the language asked for in the Accept-Language header
+func writeProblem(w http.ResponseWriter, r *http.Request, err error) {
+	lang := language(r)
+	p := newProblem(r, err, lang)
+
+	body, errJSON := json.Marshal(p)
+	if errJSON != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.Header().Set("Content-Language", lang)
+	w.WriteHeader(p.Status)
+	w.Write(body)
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"app/internal"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteProblem(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		typ    string
+		title  string
+		detail string
+	}{
+		{"not found", internal.NotFound("vehicle_not_found"), http.StatusNotFound, "/problems/not-found", "title.not_found", localize(LanguageEnglish, "vehicle_not_found")},
+		{"conflict", internal.Conflict("vehicle_id_conflict"), http.StatusConflict, "/problems/conflict", "title.conflict", localize(LanguageEnglish, "vehicle_id_conflict")},
+		{"malformed request", malformed("invalid_parameter", "year"), http.StatusBadRequest, "/problems/malformed-request", "title.malformed_request", localize(LanguageEnglish, "invalid_parameter", "year")},
+		{"validation", &internal.ValidationError{}, http.StatusBadRequest, "/problems/validation", "title.validation", localize(LanguageEnglish, "validation_failed")},
+		{"unexpected error", errors.New("connection refused by 10.0.0.1"), http.StatusInternalServerError, "/problems/internal", "title.internal", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/vehicles/7?year=x", nil)
+			req.Header.Set("Accept-Language", "en")
+			res := httptest.NewRecorder()
+
+			writeProblem(res, req, tt.err)
+
+			if ct := res.Header().Get("Content-Type"); ct != ProblemContentType {
+				t.Errorf("content type %s, want %s", ct, ProblemContentType)
+			}
+			if cl := res.Header().Get("Content-Language"); cl != LanguageEnglish {
+				t.Errorf("content language %s, want %s", cl, LanguageEnglish)
+			}
+			var p Problem
+			decodeBody(t, res.Body, &p)
+			if res.Code != tt.status || p.Status != tt.status {
+				t.Errorf("status %d and %d, want %d", res.Code, p.Status, tt.status)
+			}
+			if p.Type != tt.typ || p.Title != localize(LanguageEnglish, tt.title) || p.Instance != "/vehicles/7?year=x" {
+				t.Errorf("problem %+v", p)
+			}
+			// the detail of an unexpected error stays on the server
+			if p.Detail != tt.detail {
+				t.Errorf("detail %q, want %q", p.Detail, tt.detail)
+			}
+		})
+	}
+}
+
+func TestWriteProblem_Errors(t *testing.T) {
+	err := &internal.ValidationError{Issues: []internal.ValidationIssue{
+		{Field: "brand", Code: "required", Args: []any{"brand"}, Message: "brand is required"},
+		{Field: "color", Code: "custom_rule", Message: "color is not allowed"},
+	}}
+	req := httptest.NewRequest(http.MethodPost, "/vehicles", nil)
+	res := httptest.NewRecorder()
+
+	writeProblem(res, req, err)
+
+	var p Problem
+	decodeBody(t, res.Body, &p)
+	if len(p.Errors) != 2 {
+		t.Fatalf("errors %v, want 2", p.Errors)
+	}
+	if fp := p.Errors[0]; fp.Field != "brand" || fp.Code != "required" || fp.Message != localize(LanguagePortuguese, "validation.required", "brand") {
+		t.Errorf("first error %+v", fp)
+	}
+	// rules without a message in the catalog keep their own
+	if fp := p.Errors[1]; fp.Field != "color" || fp.Message != "color is not allowed" {
+		t.Errorf("second error %+v", fp)
+	}
+	if p.Operation != nil {
+		t.Errorf("operation %d, want none", *p.Operation)
+	}
+}
+
+func TestWriteProblem_Operation(t *testing.T) {
+	err := &internal.OperationError{Index: 2, Err: internal.NotFound("vehicle_not_found")}
+	req := httptest.NewRequest(http.MethodPost, "/vehicles/bulk", nil)
+	res := httptest.NewRecorder()
+
+	writeProblem(res, req, err)
+
+	var p Problem
+	decodeBody(t, res.Body, &p)
+	if res.Code != http.StatusNotFound || p.Type != "/problems/not-found" || p.Detail != localize(LanguagePortuguese, "vehicle_not_found") {
+		t.Errorf("status %d problem %+v, want the one of the operation", res.Code, p)
+	}
+	if p.Operation == nil || *p.Operation != 2 {
+		t.Errorf("operation %v, want 2", p.Operation)
+	}
+}
@@ -3,7 +3,7 @@ package handler
 import (
 	"app/internal"
 	"encoding/json"
-	"fmt"
+	"mime"
 	"net/http"
 	"strconv"
 	"strings"
@@ -12,24 +12,6 @@ import (
 	"github.com/go-chi/chi/v5"
 )
 
-// VehicleJSON is a struct that represents a vehicle in JSON format
-type VehicleJSON struct {
-	ID              int     `json:"id"`
-	Brand           string  `json:"brand"`
-	Model           string  `json:"model"`
-	Registration    string  `json:"registration"`
-	Color           string  `json:"color"`
-	FabricationYear int     `json:"year"`
-	Capacity        int     `json:"passengers"`
-	MaxSpeed        float64 `json:"max_speed"`
-	FuelType        string  `json:"fuel_type"`
-	Transmission    string  `json:"transmission"`
-	Weight          float64 `json:"weight"`
-	Height          float64 `json:"height"`
-	Length          float64 `json:"length"`
-	Width           float64 `json:"width"`
-}
-
 // NewVehicleDefault is a function that returns a new instance of VehicleDefault
 func NewVehicleDefault(sv internal.VehicleService) *VehicleDefault {
 	return &VehicleDefault{sv: sv}
@@ -42,81 +24,197 @@ type VehicleDefault struct {
 }
 
 // GetAll is a method that returns a handler for the route GET /vehicles
+// the vehicles can be filtered by any field with the query, see parseFilter, and are paged, see parsePage
 func (h *VehicleDefault) GetAll() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// request
-		// ...
+		q, err := parseQuery(r)
+		if err != nil {
+			writeProblem(w, r, err)
+			return
+		}
 
 		// process
-		// - get all vehicles
-		v, err := h.sv.FindAll()
+		// - get the page of the vehicles that match the filter
+		p, err := h.sv.Find(q)
 		if err != nil {
-			response.JSON(w, http.StatusInternalServerError, nil)
+			writeProblem(w, r, err)
 			return
 		}
 
 		// response
-		data := make(map[int]VehicleJSON)
-		for key, value := range v {
-			data[key] = VehicleJSON{
-				ID:              value.Id,
-				Brand:           value.Brand,
-				Model:           value.Model,
-				Registration:    value.Registration,
-				Color:           value.Color,
-				FabricationYear: value.FabricationYear,
-				Capacity:        value.Capacity,
-				MaxSpeed:        value.MaxSpeed,
-				FuelType:        value.FuelType,
-				Transmission:    value.Transmission,
-				Weight:          value.Weight,
-				Height:          value.Height,
-				Length:          value.Length,
-				Width:           value.Width,
+		writePage(w, r, q, p)
+	}
+}
+
+// GetById is a method that returns a handler for the route GET /vehicles/{id}
+// the vehicle can be projected to some fields, see parseFields; the response carries the ETag of the negotiated
+// representation, see readTag, and is 304 Not Modified when it matches the If-None-Match header
+func (h *VehicleDefault) GetById() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			writeProblem(w, r, malformed("invalid_id"))
+			return
+		}
+
+		fields, err := parseFields(r)
+		if err != nil {
+			writeProblem(w, r, err)
+			return
+		}
+
+		// process
+		vh, err := h.sv.FindById(id)
+		if err != nil {
+			writeProblem(w, r, err)
+			return
+		}
+
+		// response
+		_, mediaType, err := negotiate(r)
+		if err != nil {
+			w.Header().Add("Vary", "Accept")
+			writeProblem(w, r, err)
+			return
+		}
+		tag := readTag(vh, fields, mediaType)
+		w.Header().Set("ETag", tag)
+		if notModified(r, tag) {
+			w.Header().Add("Vary", "Accept")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		writeData(w, r, http.StatusOK, map[string]any{
+			"message": "success",
+			"data":    project(vh, fields),
+		})
+	}
+}
+
+// Replace is a method that returns a handler for the route PUT /vehicles/{id}
+// every attribute of the vehicle is replaced by the ones of the body, at the version of the If-Match header if any
+func (h *VehicleDefault) Replace() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			writeProblem(w, r, malformed("invalid_id"))
+			return
+		}
+
+		version, err := h.ifMatch(r, id)
+		if err != nil {
+			writeProblem(w, r, err)
+			return
+		}
+
+		var input VehicleJSON
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			writeProblem(w, r, malformed("malformed_vehicle"))
+			return
+		}
+
+		// process
+		vh, err := h.sv.Replace(internal.Vehicle{Id: id, Version: version, VehicleAttributes: fromVehicleJSON(input)})
+		if err != nil {
+			writeProblem(w, r, err)
+			return
+		}
+
+		// response
+		w.Header().Set("ETag", etag(vh))
+		response.JSON(w, http.StatusOK, map[string]any{
+			"message": "success",
+			"data":    toVehicleJSON(vh),
+		})
+	}
+}
+
+// Patch is a method that returns a handler for the route PATCH /vehicles/{id}
+// the body is a JSON Merge Patch or a JSON Patch of the JSON representation of the vehicle, as its Content-Type says;
+// it is applied atomically, at the version of the If-Match header if any, and the patched vehicle must be valid
+func (h *VehicleDefault) Patch() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			writeProblem(w, r, malformed("invalid_id"))
+			return
+		}
+
+		version, err := h.ifMatch(r, id)
+		if err != nil {
+			writeProblem(w, r, err)
+			return
+		}
+
+		var patch func(doc any) (any, error)
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		switch mediaType {
+		case MergePatchContentType:
+			var p map[string]any
+			if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+				writeProblem(w, r, malformed("malformed_patch"))
+				return
+			}
+			patch = func(doc any) (any, error) {
+				return mergePatch(doc, p), nil
 			}
+		case JSONPatchContentType:
+			var ops []patchOperation
+			if err := json.NewDecoder(r.Body).Decode(&ops); err != nil {
+				writeProblem(w, r, malformed("malformed_patch"))
+				return
+			}
+			patch = func(doc any) (any, error) {
+				return jsonPatch(doc, ops)
+			}
+		default:
+			writeProblem(w, r, &internal.Error{Kind: errUnsupportedMediaType, Code: "unsupported_patch_type", Args: []any{mediaType}})
+			return
 		}
+
+		// process
+		vh, err := h.sv.Update(id, version, func(v internal.Vehicle) (internal.Vehicle, error) {
+			return patchVehicle(v, patch)
+		})
+		if err != nil {
+			writeProblem(w, r, err)
+			return
+		}
+
+		// response
+		w.Header().Set("ETag", etag(vh))
 		response.JSON(w, http.StatusOK, map[string]any{
 			"message": "success",
-			"data":    data,
+			"data":    toVehicleJSON(vh),
 		})
 	}
 }
 
 func (h *VehicleDefault) Create() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var input internal.VehicleAttributes
+		var input VehicleJSON
 
 		err := json.NewDecoder(r.Body).Decode(&input)
 
 		if err != nil {
-			w.Write([]byte(`{message: 400 Bad Request: Dados do veículo mal formatados ou incompletos.}`))
-			response.JSON(w, http.StatusBadRequest, 400)
+			writeProblem(w, r, malformed("malformed_vehicle"))
 			return
 		}
 
-		newVehicleAttributes := internal.VehicleAttributes{
-			Brand:           input.Brand,
-			Model:           input.Model,
-			Registration:    input.Registration,
-			Color:           input.Color,
-			FabricationYear: input.FabricationYear,
-			Capacity:        input.Capacity,
-			MaxSpeed:        input.MaxSpeed,
-			FuelType:        input.FuelType,
-			Transmission:    input.Transmission,
-			Weight:          input.Weight,
-			Dimensions:      input.Dimensions,
-		}
-
-		err = h.sv.Create(newVehicleAttributes)
+		vh, err := h.sv.Create(fromVehicleJSON(input))
 		if err != nil {
-			w.Write([]byte(`{message: 409 Conflict: Identificador do veículo já existente.}`))
-			response.JSON(w, http.StatusBadRequest, 400)
+			writeProblem(w, r, err)
 			return
 		}
 
+		w.Header().Set("ETag", etag(vh))
 		response.JSON(w, http.StatusCreated, map[string]any{
 			"message": "success",
+			"data":    toVehicleJSON(vh),
 		})
 
 	}
@@ -124,13 +222,18 @@ func (h *VehicleDefault) Create() http.HandlerFunc {
 
 func (h *VehicleDefault) GetByColorAndYear() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		q, err := parsePage(r)
+		if err != nil {
+			writeProblem(w, r, err)
+			return
+		}
+
 		color := r.URL.Query().Get("color")
 		yearStr := r.URL.Query().Get("year")
 		year, err := strconv.Atoi(yearStr)
 
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte(`{"message":"Parâmetro year inválido ou ausente."}`))
+			writeProblem(w, r, malformed("invalid_parameter", "year"))
 			return
 		}
 
@@ -144,23 +247,24 @@ func (h *VehicleDefault) GetByColorAndYear() http.HandlerFunc {
 			FabricationYear: input.FabricationYear,
 		}
 
-		vehiclesList, err := h.sv.FindByColorAndYear(vehicle)
+		p, err := h.sv.FindByColorAndYear(vehicle, q)
 
 		if err != nil {
-			w.Write([]byte(`{"message": "404 Not Found: Nenhum veículo encontrado com esses critérios." }`))
-			response.JSON(w, http.StatusNotFound, 404)
+			writeProblem(w, r, err)
 			return
 		}
 
-		response.JSON(w, http.StatusOK, map[string]any{
-			"message": "success",
-			"data":    vehiclesList,
-		})
+		writePage(w, r, q, p)
 	}
 }
 
 func (h *VehicleDefault) GetByBrandAndYearInterval() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		q, err := parsePage(r)
+		if err != nil {
+			writeProblem(w, r, err)
+			return
+		}
 
 		brand := chi.URLParam(r, "brand")
 		startYearStr := chi.URLParam(r, "start_year")
@@ -168,15 +272,13 @@ func (h *VehicleDefault) GetByBrandAndYearInterval() http.HandlerFunc {
 
 		startYear, err := strconv.Atoi(startYearStr)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte(`{"message":"Parâmetro start_year inválido ou ausente."}`))
+			writeProblem(w, r, malformed("invalid_parameter", "start_year"))
 			return
 		}
 
 		endYear, err := strconv.Atoi(endYearStr)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte(`{"message":"Parâmetro end_year inválido ou ausente."}`))
+			writeProblem(w, r, malformed("invalid_parameter", "end_year"))
 			return
 		}
 
@@ -186,18 +288,14 @@ func (h *VehicleDefault) GetByBrandAndYearInterval() http.HandlerFunc {
 			EndYear:   endYear,
 		}
 
-		vehiclesList, err := h.sv.FindByBrandAndYearInterval(req)
+		p, err := h.sv.FindByBrandAndYearInterval(req, q)
 
 		if err != nil {
-			w.Write([]byte(`{"message": "404 Not Found: Nenhum veículo encontrado com esses critérios." }`))
-			response.JSON(w, http.StatusNotFound, 404)
+			writeProblem(w, r, err)
 			return
 		}
 
-		response.JSON(w, http.StatusOK, map[string]any{
-			"message": "success",
-			"data":    vehiclesList,
-		})
+		writePage(w, r, q, p)
 	}
 }
 
@@ -208,38 +306,110 @@ func (h *VehicleDefault) GetAverageSpeedByBrand() http.HandlerFunc {
 		averageSpeed, err := h.sv.GetAverageSpeedByBrand(brand)
 
 		if err != nil {
-			w.Write([]byte(`{message: 404 Not Found: Nenhum veículo encontrado dessa marca.}`))
-			response.JSON(w, http.StatusNotFound, 404)
+			writeProblem(w, r, err)
 			return
 		}
 
-		response.JSON(w, http.StatusOK, map[string]any{
+		writeData(w, r, http.StatusOK, map[string]any{
 			"message": "success",
 			"data":    averageSpeed})
 
 	}
 }
 
+// CreateSome is a method that returns a handler for the route POST /vehicles/batch
+// every vehicle is validated first and, unless ?mode=partial, either all of them are created or none is;
+// the response tells the outcome of every vehicle: 201 when all were created, 207 when only some were
+// and a 400 problem, with the outcomes in its items, when none was because some are invalid
 func (h *VehicleDefault) CreateSome() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var input []internal.VehicleAttributes
+		var partial bool
+		switch r.URL.Query().Get("mode") {
+		case "", "atomic":
+		case "partial":
+			partial = true
+		default:
+			writeProblem(w, r, malformed("invalid_parameter", "mode"))
+			return
+		}
+
+		var input []VehicleJSON
 		err := json.NewDecoder(r.Body).Decode(&input)
 
 		if err != nil {
-			w.Write([]byte(`"message": "400 Bad Request: Dados de algum veículo malformados ou incompletos."`))
-			response.JSON(w, http.StatusBadRequest, 400)
+			writeProblem(w, r, malformed("malformed_vehicles"))
+			return
+		}
+
+		vs := make([]internal.VehicleAttributes, 0, len(input))
+		for _, v := range input {
+			vs = append(vs, fromVehicleJSON(v))
+		}
+
+		results, err := h.sv.CreateSome(vs, partial)
+
+		if err != nil && results == nil {
+			writeProblem(w, r, err)
+			return
+		}
+		created := 0
+		for _, rs := range results {
+			if rs.Created {
+				created++
+			}
+		}
+		// an atomic batch with invalid items, or a partial one with no vehicle stored, failed as a whole
+		if err != nil || (created == 0 && len(results) > 0) {
+			writeProblem(w, r, invalidBatch(results))
+			return
+		}
+
+		lang := language(r)
+		status, message := http.StatusCreated, "success"
+		if created < len(results) {
+			status, message = http.StatusMultiStatus, localize(lang, "batch_partial")
+		}
+
+		w.Header().Set("Content-Language", lang)
+		response.JSON(w, status, map[string]any{
+			"message": message,
+			"data":    serializeBatch(results, lang),
+		})
+	}
+}
+
+// Bulk is a method that returns a handler for the route POST /vehicles/bulk
+// the body has the operations, see BulkOperationJSON, which are applied in order and atomically;
+// the response has the ids of the vehicles created, updated and deleted
+func (h *VehicleDefault) Bulk() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		var input struct {
+			Operations []BulkOperationJSON `json:"operations"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil || len(input.Operations) == 0 {
+			writeProblem(w, r, malformed("malformed_bulk"))
 			return
 		}
 
-		err = h.sv.CreateSome(input)
+		ops, err := deserializeBulk(input.Operations)
+		if err != nil {
+			writeProblem(w, r, err)
+			return
+		}
 
+		// process
+		rs, err := h.sv.Bulk(ops)
 		if err != nil {
-			w.Write([]byte(`"message": "409 Conflict: Algum veículo possui um identificador já existente."`))
-			response.JSON(w, http.StatusConflict, 409)
+			writeProblem(w, r, err)
 			return
 		}
 
-		response.JSON(w, http.StatusCreated, 201)
+		// response
+		response.JSON(w, http.StatusOK, map[string]any{
+			"message": "success",
+			"data":    serializeBulk(rs),
+		})
 	}
 }
 
@@ -252,7 +422,7 @@ func (h *VehicleDefault) UpdateSpeed() http.HandlerFunc {
 		err := json.NewDecoder(r.Body).Decode(&s)
 
 		if err != nil {
-			response.JSON(w, http.StatusBadRequest, `{"message": "400 Bad Request: Velocidade malformada ou fora de alcance."}`)
+			writeProblem(w, r, malformed("malformed_speed"))
 			return
 		}
 
@@ -260,55 +430,56 @@ func (h *VehicleDefault) UpdateSpeed() http.HandlerFunc {
 		id, err := strconv.Atoi(idStr)
 
 		if err != nil {
-			w.Write([]byte(`400 Bad Request: Velocidade malformada ou fora de alcance.`))
-			response.JSON(w, http.StatusBadRequest, 400)
+			writeProblem(w, r, malformed("invalid_id"))
+			return
+		}
+
+		version, err := h.ifMatch(r, id)
+		if err != nil {
+			writeProblem(w, r, err)
 			return
 		}
 
 		var u internal.UpdateSpeed
 		u = internal.UpdateSpeed{
-			Id:    int(id),
-			Speed: s.Speed,
+			Id:      int(id),
+			Speed:   s.Speed,
+			Version: version,
 		}
 
-		fmt.Println(u, "struct on handler")
-
-		err = h.sv.UpdateSpeed(u)
+		vh, err := h.sv.UpdateSpeed(u)
 
 		if err != nil {
-			w.Write([]byte(`404 Not Found: Veículo não encontrado.`))
-			response.JSON(w, http.StatusNotFound, 404)
+			writeProblem(w, r, err)
 			return
 		}
 
-		response.JSON(w, http.StatusOK, 200)
+		w.Header().Set("ETag", etag(vh))
+		response.JSON(w, http.StatusOK, map[string]any{
+			"message": "success",
+			"data":    toVehicleJSON(vh),
+		})
 	}
 }
 
 func (h *VehicleDefault) GetByFuelType() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		fuelType := chi.URLParam(r, "type")
-
-		data, err := h.sv.GetByFuelType(fuelType)
-
+		q, err := parsePage(r)
 		if err != nil {
-			w.Write([]byte(`404 Not Found: Não foram encontrados veículos com esse tipo de combustível.`))
-			response.JSON(w, http.StatusNotFound, 404)
+			writeProblem(w, r, err)
 			return
 		}
 
-		if len(data) == 0 {
-			w.WriteHeader(http.StatusNotFound)
-			response.JSON(w, http.StatusNotFound, map[string]string{
-				"message": "404 Not Found: Não foram encontrados veículos com esse tipo de combustível.",
-			})
+		fuelType := chi.URLParam(r, "type")
+
+		p, err := h.sv.GetByFuelType(fuelType, q)
+
+		if err != nil {
+			writeProblem(w, r, err)
 			return
 		}
 
-		response.JSON(w, http.StatusOK, map[string]any{
-			"message": "success",
-			"data":    data,
-		})
+		writePage(w, r, q, p)
 		return
 	}
 }
@@ -320,40 +491,45 @@ func (h *VehicleDefault) DeleteById() http.HandlerFunc {
 		id, err := strconv.Atoi(idStr)
 
 		if err != nil {
-			w.Write([]byte(`{message: 400 Bad Request: Dados do veículo mal formatados ou incompletos.}`))
-			response.JSON(w, http.StatusBadRequest, 400)
+			writeProblem(w, r, malformed("invalid_id"))
+			return
+		}
+
+		version, err := h.ifMatch(r, id)
+		if err != nil {
+			writeProblem(w, r, err)
 			return
 		}
 
-		err = h.sv.DeleteById(id)
+		err = h.sv.DeleteById(id, version)
 
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusNotFound)
+			writeProblem(w, r, err)
 			return
 		}
 
-		response.JSON(w, http.StatusNoContent, map[string]any{
-			"message": "204 No Content: Veículo removido com sucesso.",
-		})
+		w.WriteHeader(http.StatusNoContent)
 	}
 }
 
 func (h *VehicleDefault) GetByTransmissionType() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		q, err := parsePage(r)
+		if err != nil {
+			writeProblem(w, r, err)
+			return
+		}
+
 		t := chi.URLParam(r, "type")
 
-		data, err := h.sv.GetByTransmissionType(t)
+		p, err := h.sv.GetByTransmissionType(t, q)
 
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusNotFound)
-			w.Write([]byte(`{message: 404 Not Found: Não foram encontrados veículos com esse tipo de transmissão.}`))
+			writeProblem(w, r, err)
 			return
 		}
 
-		response.JSON(w, http.StatusOK, map[string]any{
-			"message": "success",
-			"data":    data,
-		})
+		writePage(w, r, q, p)
 	}
 }
 
@@ -362,8 +538,13 @@ func (h *VehicleDefault) UpdateFuel() http.HandlerFunc {
 		idStr := chi.URLParam(r, "id")
 		id, err := strconv.Atoi(idStr)
 		if err != nil {
-			http.Error(w, err.Error(), 400)
-			w.Write([]byte(`400 Bad Request: Tipo de combustível malformado ou não suportado.`))
+			writeProblem(w, r, malformed("invalid_id"))
+			return
+		}
+
+		version, err := h.ifMatch(r, id)
+		if err != nil {
+			writeProblem(w, r, err)
 			return
 		}
 
@@ -373,24 +554,28 @@ func (h *VehicleDefault) UpdateFuel() http.HandlerFunc {
 
 		err = json.NewDecoder(r.Body).Decode(&f)
 		if err != nil {
-			http.Error(w, err.Error(), 400)
-			w.Write([]byte(`400 Bad Request: Tipo de combustível malformado ou não suportado.`))
+			writeProblem(w, r, malformed("malformed_fuel_type"))
 			return
 		}
 
 		v := internal.UpdateFuel{
 			Id:       id,
 			FuelType: f.FuelType,
+			Version:  version,
 		}
 
-		err = h.sv.UpdateFuelType(v)
+		vh, err := h.sv.UpdateFuelType(v)
 
 		if err != nil {
-			http.Error(w, err.Error(), 404)
+			writeProblem(w, r, err)
 			return
 		}
 
-		response.JSON(w, http.StatusOK, 200)
+		w.Header().Set("ETag", etag(vh))
+		response.JSON(w, http.StatusOK, map[string]any{
+			"message": "success",
+			"data":    toVehicleJSON(vh),
+		})
 	}
 }
 
@@ -401,12 +586,11 @@ func (h *VehicleDefault) GetAverageCapacityByBrand() http.HandlerFunc {
 		data, err := h.sv.GetAverageCapacityByBrand(brand)
 
 		if err != nil {
-			http.Error(w, err.Error(), 404)
-			w.Write([]byte(`"message": "404 Not Found: Não foram encontrados veículos dessa marca."`))
+			writeProblem(w, r, err)
 			return
 		}
 
-		response.JSON(w, http.StatusOK, map[string]any{
+		writeData(w, r, http.StatusOK, map[string]any{
 			"message": "success",
 			"data":    data,
 		})
@@ -415,94 +599,100 @@ func (h *VehicleDefault) GetAverageCapacityByBrand() http.HandlerFunc {
 
 func (h *VehicleDefault) GetByDimensions() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		q, err := parsePage(r)
+		if err != nil {
+			writeProblem(w, r, err)
+			return
+		}
+
 		length := r.URL.Query().Get("length")
 
 		l := strings.Split(length, "-")
+		if len(l) != 2 {
+			writeProblem(w, r, malformed("invalid_range_parameter", "length"))
+			return
+		}
 
 		minLength, err := strconv.ParseFloat(l[0], 64)
 
 		if err != nil {
-			w.Write([]byte(`{message: 400 Bad Request: Dados do veículo mal formatados ou incompletos.}`))
-			response.JSON(w, http.StatusBadRequest, 400)
+			writeProblem(w, r, malformed("invalid_range_parameter", "length"))
 			return
 		}
 
 		maxLength, err := strconv.ParseFloat(l[1], 64)
 
 		if err != nil {
-			w.Write([]byte(`{message: 400 Bad Request: Dados do veículo mal formatados ou incompletos.}`))
-			response.JSON(w, http.StatusBadRequest, 400)
+			writeProblem(w, r, malformed("invalid_range_parameter", "length"))
 			return
 		}
 
 		width := r.URL.Query().Get("width")
 		wh := strings.Split(width, "-")
+		if len(wh) != 2 {
+			writeProblem(w, r, malformed("invalid_range_parameter", "width"))
+			return
+		}
 
 		minWidth, err := strconv.ParseFloat(wh[0], 64)
 
 		if err != nil {
-			w.Write([]byte(`{message: 400 Bad Request: Dados do veículo mal formatados ou incompletos.}`))
-			response.JSON(w, http.StatusBadRequest, 400)
+			writeProblem(w, r, malformed("invalid_range_parameter", "width"))
 			return
 		}
 
 		maxWidth, err := strconv.ParseFloat(wh[1], 64)
 
 		if err != nil {
-			w.Write([]byte(`{message: 400 Bad Request: Dados do veículo mal formatados ou incompletos.}`))
-			response.JSON(w, http.StatusBadRequest, 400)
+			writeProblem(w, r, malformed("invalid_range_parameter", "width"))
 			return
 		}
 
-		data, err := h.sv.GetByDimensions(minLength, maxLength, minWidth, maxWidth)
+		p, err := h.sv.GetByDimensions(minLength, maxLength, minWidth, maxWidth, q)
 
 		if err != nil {
-			w.Write([]byte(`{message: 404 Not Found: Não foram encontrados veículos com essas dimensões.}`))
-			response.JSON(w, http.StatusNotFound, 404)
+			writeProblem(w, r, err)
 			return
 		}
 
-		response.JSON(w, http.StatusOK, map[string]any{
-			"message": "success",
-			"data":    data,
-		})
+		writePage(w, r, q, p)
 
 	}
 }
 
 func (h *VehicleDefault) GetByWeight() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		q, err := parsePage(r)
+		if err != nil {
+			writeProblem(w, r, err)
+			return
+		}
+
 		min := r.URL.Query().Get("min")
 		max := r.URL.Query().Get("max")
 
 		wmin, err := strconv.ParseFloat(min, 64)
 
 		if err != nil {
-			w.Write([]byte(`{message: 400 Bad Request: Dados do veículo mal formatados ou incompletos.}`))
-			response.JSON(w, http.StatusBadRequest, 400)
+			writeProblem(w, r, malformed("invalid_parameter", "min"))
 			return
 		}
 
 		wmax, err := strconv.ParseFloat(max, 64)
 
 		if err != nil {
-			w.Write([]byte(`{message: 400 Bad Request: Dados do veículo mal formatados ou incompletos.}`))
-			response.JSON(w, http.StatusBadRequest, 400)
+			writeProblem(w, r, malformed("invalid_parameter", "max"))
 			return
 		}
 
-		data, err := h.sv.GetByWeight(wmin, wmax)
+		p, err := h.sv.GetByWeight(wmin, wmax, q)
 
 		if err != nil {
-			w.Write([]byte(`{message: 404 Not Found: Não foram encontrados veículos nessa faixa de peso.}`))
-			response.JSON(w, http.StatusNotFound, 404)
+			writeProblem(w, r, err)
 			return
 		}
 
-		response.JSON(w, http.StatusOK, map[string]any{
-			"message": "success",
-			"data":    data,
-		})
+		writePage(w, r, q, p)
 
 	}
 }
@@ -0,0 +1,81 @@
+package handler
+
+import "app/internal"
+
+// the functions of this file are the only mapping between the vehicles of the domain and their
+// representation in the requests and responses; every handler goes through them
+
+// VehicleJSON is a struct that represents a vehicle in JSON format
+type VehicleJSON struct {
+	ID              int     `json:"id"`
+	Brand           string  `json:"brand"`
+	Model           string  `json:"model"`
+	Registration    string  `json:"registration"`
+	Color           string  `json:"color"`
+	FabricationYear int     `json:"year"`
+	Capacity        int     `json:"passengers"`
+	MaxSpeed        float64 `json:"max_speed"`
+	FuelType        string  `json:"fuel_type"`
+	Transmission    string  `json:"transmission"`
+	Weight          float64 `json:"weight"`
+	Height          float64 `json:"height"`
+	Length          float64 `json:"length"`
+	Width           float64 `json:"width"`
+}
+
+// toVehicleJSON is a function that converts a vehicle into its JSON representation
+func toVehicleJSON(v internal.Vehicle) VehicleJSON {
+	return VehicleJSON{
+		ID:              v.Id,
+		Brand:           v.Brand,
+		Model:           v.Model,
+		Registration:    v.Registration,
+		Color:           v.Color,
+		FabricationYear: v.FabricationYear,
+		Capacity:        v.Capacity,
+		MaxSpeed:        v.MaxSpeed,
+		FuelType:        v.FuelType,
+		Transmission:    v.Transmission,
+		Weight:          v.Weight,
+		Height:          v.Height,
+		Length:          v.Length,
+		Width:           v.Width,
+	}
+}
+
+// fromVehicleJSON is a function that converts the JSON representation of a vehicle into its attributes
+// the id is assigned by the repository so it is not taken from the request
+func fromVehicleJSON(v VehicleJSON) internal.VehicleAttributes {
+	return internal.VehicleAttributes{
+		Brand:           v.Brand,
+		Model:           v.Model,
+		Registration:    v.Registration,
+		Color:           v.Color,
+		FabricationYear: v.FabricationYear,
+		Capacity:        v.Capacity,
+		MaxSpeed:        v.MaxSpeed,
+		FuelType:        v.FuelType,
+		Transmission:    v.Transmission,
+		Weight:          v.Weight,
+		Dimensions: internal.Dimensions{
+			Height: v.Height,
+			Length: v.Length,
+			Width:  v.Width,
+		},
+	}
+}
+
+// project is a function that returns the representation of a vehicle with only the given fields
+// all of them when fields is nil; the fields keep the order they were asked in
+func project(v internal.Vehicle, fields []string) any {
+	if fields == nil {
+		return toVehicleJSON(v)
+	}
+
+	data := make(object, 0, len(fields))
+	for _, name := range fields {
+		value, _ := v.Field(name)
+		data = append(data, member{Name: name, Value: value})
+	}
+	return data
+}
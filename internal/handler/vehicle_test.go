@@ -0,0 +1,360 @@
+package handler
+
+import (
+	"app/internal"
+	"app/internal/repository"
+	"app/internal/service"
+	"app/internal/validator"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// testAttributes is a function that returns valid attributes, told apart by i
+func testAttributes(i int) internal.VehicleAttributes {
+	return internal.VehicleAttributes{
+		Brand:           []string{"Ford", "Fiat", "Honda"}[i%3],
+		Model:           "Model " + fmt.Sprint(i),
+		Registration:    fmt.Sprintf("REG%06d", i),
+		Color:           "Blue",
+		FabricationYear: 2000 + i%20,
+		Capacity:        1 + i%5,
+		MaxSpeed:        float64(100 + i%100),
+		FuelType:        []string{"gasoline", "diesel"}[i%2],
+		Transmission:    "manual",
+		Weight:          1000,
+		Dimensions:      internal.Dimensions{Height: 150, Length: 400, Width: 180},
+	}
+}
+
+// testVehicleJSON is a function that returns the JSON representation of valid attributes, told apart by i
+func testVehicleJSON(i int) string {
+	a := testAttributes(i)
+	return fmt.Sprintf(`{"brand":%q,"model":%q,"registration":%q,"color":%q,"year":%d,"passengers":%d,"max_speed":%g,"fuel_type":%q,"transmission":%q,"weight":%g,"height":%g,"length":%g,"width":%g}`,
+		a.Brand, a.Model, a.Registration, a.Color, a.FabricationYear, a.Capacity, a.MaxSpeed, a.FuelType, a.Transmission, a.Weight, a.Height, a.Length, a.Width)
+}
+
+// newTestHandler is a function that returns a handler over a repository with n valid vehicles of ids 1 to n
+func newTestHandler(n int) (hd *VehicleDefault, rp *repository.VehicleMap) {
+	db := make(map[int]internal.Vehicle, n)
+	for id := 1; id <= n; id++ {
+		db[id] = internal.Vehicle{Id: id, VehicleAttributes: testAttributes(id)}
+	}
+	rp = repository.NewVehicleMap(db)
+	hd = NewVehicleDefault(service.NewVehicleDefault(rp, validator.NewVehicleRules(nil)))
+	return
+}
+
+// serve is a function that returns the response of the handler to a request, header holds pairs of names and values
+// the handler is mounted on pattern so it sees the parameters of the path
+func serve(h http.HandlerFunc, pattern, method, target, body string, header ...string) *httptest.ResponseRecorder {
+	rt := chi.NewRouter()
+	rt.Method(method, pattern, h)
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	res := httptest.NewRecorder()
+	rt.ServeHTTP(res, req)
+	return res
+}
+
+// decodeBody is a function that decodes the JSON body of a response into v
+func decodeBody(t *testing.T, body io.Reader, v any) {
+	t.Helper()
+	if err := json.NewDecoder(body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVehicleDefault_CreateSome(t *testing.T) {
+	invalid := `{"brand":"","max_speed":900}`
+	tests := []struct {
+		name, query, body string
+		status            int
+		message           string
+		statuses          []string
+		created           int
+	}{
+		{"atomic", "", "[" + testVehicleJSON(1) + "," + testVehicleJSON(2) + "]", http.StatusCreated, "success", []string{batchCreated, batchCreated}, 2},
+		{"atomic with an invalid item", "?mode=atomic", "[" + testVehicleJSON(1) + "," + invalid + "]", http.StatusBadRequest, localize(LanguagePortuguese, "batch_invalid"), []string{batchSkipped, batchInvalid}, 0},
+		{"partial", "?mode=partial", "[" + testVehicleJSON(1) + "," + testVehicleJSON(2) + "]", http.StatusCreated, "success", []string{batchCreated, batchCreated}, 2},
+		{"partial with an invalid item", "?mode=partial", "[" + invalid + "," + testVehicleJSON(1) + "]", http.StatusMultiStatus, localize(LanguagePortuguese, "batch_partial"), []string{batchInvalid, batchCreated}, 1},
+		{"partial with every item invalid", "?mode=partial", "[" + invalid + "," + invalid + "]", http.StatusBadRequest, localize(LanguagePortuguese, "batch_invalid"), []string{batchInvalid, batchInvalid}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hd, rp := newTestHandler(3)
+
+			res := serve(hd.CreateSome(), "/vehicles/batch", http.MethodPost, "/vehicles/batch"+tt.query, tt.body)
+
+			var body struct {
+				Message string          `json:"message"`
+				Data    []BatchItemJSON `json:"data"`
+				// a batch that created no vehicle is a problem
+				Detail string          `json:"detail"`
+				Items  []BatchItemJSON `json:"items"`
+			}
+			decodeBody(t, res.Body, &body)
+			if res.Code == http.StatusBadRequest {
+				if res.Header().Get("Content-Type") != ProblemContentType {
+					t.Errorf("content type %s, want a problem", res.Header().Get("Content-Type"))
+				}
+				body.Message, body.Data = body.Detail, body.Items
+			}
+			if res.Code != tt.status || body.Message != tt.message {
+				t.Errorf("status %d message %q, want %d and %q", res.Code, body.Message, tt.status, tt.message)
+			}
+			var statuses []string
+			for i, item := range body.Data {
+				if item.Index != i {
+					t.Errorf("item %d has index %d", i, item.Index)
+				}
+				if item.Status == batchInvalid && len(item.Errors) == 0 {
+					t.Errorf("item %d invalid without errors", i)
+				}
+				statuses = append(statuses, item.Status)
+			}
+			if !slices.Equal(statuses, tt.statuses) {
+				t.Errorf("statuses %v, want %v", statuses, tt.statuses)
+			}
+			if v, _ := rp.FindAll(); len(v) != 3+tt.created {
+				t.Errorf("%d vehicles, want %d", len(v), 3+tt.created)
+			}
+		})
+	}
+
+	t.Run("unknown mode", func(t *testing.T) {
+		hd, _ := newTestHandler(0)
+
+		res := serve(hd.CreateSome(), "/vehicles/batch", http.MethodPost, "/vehicles/batch?mode=all", "["+testVehicleJSON(1)+"]")
+
+		if res.Code != http.StatusBadRequest || res.Header().Get("Content-Type") != ProblemContentType {
+			t.Errorf("status %d %s, want a 400 problem", res.Code, res.Header().Get("Content-Type"))
+		}
+	})
+}
+
+func TestVehicleDefault_GetById(t *testing.T) {
+	tests := []struct {
+		name, target string
+		status       int
+		detail       string
+	}{
+		{"found", "/vehicles/2", http.StatusOK, ""},
+		{"not found", "/vehicles/9", http.StatusNotFound, localize(LanguagePortuguese, "vehicle_not_found")},
+		{"invalid id", "/vehicles/two", http.StatusBadRequest, localize(LanguagePortuguese, "invalid_id")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hd, _ := newTestHandler(2)
+
+			res := serve(hd.GetById(), "/vehicles/{id}", http.MethodGet, tt.target, "")
+
+			if res.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", res.Code, tt.status, res.Body)
+			}
+			if tt.detail != "" {
+				var p Problem
+				decodeBody(t, res.Body, &p)
+				if p.Detail != tt.detail {
+					t.Errorf("detail %q, want %q", p.Detail, tt.detail)
+				}
+				return
+			}
+			var body struct {
+				Data VehicleJSON `json:"data"`
+			}
+			decodeBody(t, res.Body, &body)
+			if want := toVehicleJSON(internal.Vehicle{Id: 2, VehicleAttributes: testAttributes(2)}); body.Data != want {
+				t.Errorf("data %+v, want %+v", body.Data, want)
+			}
+		})
+	}
+}
+
+func TestVehicleDefault_Replace(t *testing.T) {
+	tests := []struct {
+		name, target, body string
+		status             int
+		detail             string
+	}{
+		{"replaced", "/vehicles/1", testVehicleJSON(5), http.StatusOK, ""},
+		{"not found", "/vehicles/9", testVehicleJSON(5), http.StatusNotFound, localize(LanguagePortuguese, "vehicle_not_found")},
+		{"invalid id", "/vehicles/one", testVehicleJSON(5), http.StatusBadRequest, localize(LanguagePortuguese, "invalid_id")},
+		{"malformed body", "/vehicles/1", `{"brand":`, http.StatusBadRequest, localize(LanguagePortuguese, "malformed_vehicle")},
+		{"invalid vehicle", "/vehicles/1", `{"brand":"","max_speed":900}`, http.StatusBadRequest, localize(LanguagePortuguese, "validation_failed")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hd, rp := newTestHandler(2)
+
+			res := serve(hd.Replace(), "/vehicles/{id}", http.MethodPut, tt.target, tt.body)
+
+			if res.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", res.Code, tt.status, res.Body)
+			}
+			vh, _ := rp.FindById(1)
+			if tt.detail != "" {
+				var p Problem
+				decodeBody(t, res.Body, &p)
+				if p.Detail != tt.detail {
+					t.Errorf("detail %q, want %q", p.Detail, tt.detail)
+				}
+				// the vehicle is left as it was
+				if vh.VehicleAttributes != testAttributes(1) {
+					t.Errorf("vehicle %+v changed", vh)
+				}
+				return
+			}
+			if vh.VehicleAttributes != testAttributes(5) || vh.Version != 2 {
+				t.Errorf("vehicle %+v, want the attributes of the body at version 2", vh)
+			}
+		})
+	}
+}
+
+func TestVehicleDefault_GetAll_InvalidPage(t *testing.T) {
+	tampered := base64.RawURLEncoding.EncodeToString([]byte(`{"id":"two"}`))
+	tests := []struct {
+		name, target, detail string
+	}{
+		{"tampered cursor", "/vehicles?cursor=" + tampered, localize(LanguagePortuguese, "invalid_cursor")},
+		{"cursor not in base64", "/vehicles?cursor=not*base64", localize(LanguagePortuguese, "invalid_cursor")},
+		{"cursor of another sort", "/vehicles?sort=-id&cursor=" + encodeCursor(internal.Cursor{Id: 2}, "brand"), localize(LanguagePortuguese, "invalid_cursor")},
+		{"cursor without its sort", "/vehicles?cursor=" + encodeCursor(internal.Cursor{Id: 2}, "brand"), localize(LanguagePortuguese, "invalid_cursor")},
+		{"limit 0", "/vehicles?limit=0", localize(LanguagePortuguese, "invalid_limit", maxLimit)},
+		{"limit over the maximum", "/vehicles?limit=1001", localize(LanguagePortuguese, "invalid_limit", maxLimit)},
+		{"limit not a number", "/vehicles?limit=ten", localize(LanguagePortuguese, "invalid_limit", maxLimit)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hd, _ := newTestHandler(5)
+
+			res := serve(hd.GetAll(), "/vehicles", http.MethodGet, tt.target, "")
+
+			if res.Code != http.StatusBadRequest {
+				t.Fatalf("status %d, want %d: %s", res.Code, http.StatusBadRequest, res.Body)
+			}
+			var p Problem
+			decodeBody(t, res.Body, &p)
+			if p.Detail != tt.detail {
+				t.Errorf("detail %q, want %q", p.Detail, tt.detail)
+			}
+		})
+	}
+}
+
+// testPage is a struct that represents the body of a page of vehicles
+type testPage struct {
+	Data  []VehicleJSON `json:"data"`
+	Total int           `json:"total"`
+	Links pageLinks     `json:"links"`
+}
+
+// getPage is a function that returns the page of vehicles of a target, checking its status and its headers
+func getPage(t *testing.T, hd *VehicleDefault, target string) (p testPage, ids []int) {
+	t.Helper()
+	res := serve(hd.GetAll(), "/vehicles", http.MethodGet, target, "")
+	if res.Code != http.StatusOK {
+		t.Fatalf("%s: status %d, want %d: %s", target, res.Code, http.StatusOK, res.Body)
+	}
+	decodeBody(t, res.Body, &p)
+	for _, v := range p.Data {
+		ids = append(ids, v.ID)
+	}
+
+	// the headers repeat the total and the links of the body
+	if got := res.Header().Get("X-Total-Count"); got != fmt.Sprint(p.Total) {
+		t.Errorf("%s: X-Total-Count %q, want %d", target, got, p.Total)
+	}
+	var rels []string
+	if p.Links.Next != "" {
+		rels = append(rels, `<`+p.Links.Next+`>; rel="next"`)
+	}
+	if p.Links.Prev != "" {
+		rels = append(rels, `<`+p.Links.Prev+`>; rel="prev"`)
+	}
+	if got := res.Header().Get("Link"); got != strings.Join(rels, ", ") {
+		t.Errorf("%s: Link %q, want %q", target, got, strings.Join(rels, ", "))
+	}
+	return
+}
+
+func TestVehicleDefault_GetAll_OffsetLinks(t *testing.T) {
+	tests := []struct {
+		name, target, next, prev string
+		ids                      []int
+	}{
+		{"first page", "/vehicles?limit=2&offset=0", "/vehicles?limit=2&offset=2", "", []int{1, 2}},
+		{"middle page", "/vehicles?limit=2&offset=2", "/vehicles?limit=2&offset=4", "/vehicles?limit=2&offset=0", []int{3, 4}},
+		{"last page", "/vehicles?limit=2&offset=4", "", "/vehicles?limit=2&offset=2", []int{5}},
+		{"past the last page", "/vehicles?limit=2&offset=9", "", "/vehicles?limit=2&offset=3", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hd, _ := newTestHandler(5)
+
+			p, ids := getPage(t, hd, tt.target)
+
+			if !slices.Equal(ids, tt.ids) || p.Total != 5 {
+				t.Errorf("ids %v of %d, want %v of 5", ids, p.Total, tt.ids)
+			}
+			if p.Links.Next != tt.next || p.Links.Prev != tt.prev {
+				t.Errorf("next %q prev %q, want %q and %q", p.Links.Next, p.Links.Prev, tt.next, tt.prev)
+			}
+		})
+	}
+}
+
+func TestVehicleDefault_GetAll_CursorLinks(t *testing.T) {
+	tests := []struct {
+		name, target string
+		pages        [][]int
+	}{
+		{"by id", "/vehicles?limit=2", [][]int{{1, 2}, {3, 4}, {5}}},
+		{"by brand", "/vehicles?limit=2&sort=brand", [][]int{{1, 4}, {3, 2}, {5}}},
+		{"by brand with empty names", "/vehicles?limit=2&sort=,brand,", [][]int{{1, 4}, {3, 2}, {5}}},
+		{"by default with only empty names", "/vehicles?limit=2&sort=,", [][]int{{1, 2}, {3, 4}, {5}}},
+		{"by descending id", "/vehicles?limit=3&sort=-id", [][]int{{5, 4, 3}, {2, 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hd, _ := newTestHandler(5)
+
+			// the next links go through every page, then the prev links come back
+			var pages [][]int
+			var last testPage
+			for target := tt.target; target != ""; target = last.Links.Next {
+				p, ids := getPage(t, hd, target)
+				pages, last = append(pages, ids), p
+			}
+			if !slices.EqualFunc(pages, tt.pages, slices.Equal) {
+				t.Fatalf("pages %v, want %v", pages, tt.pages)
+			}
+
+			for i := len(tt.pages) - 2; i >= 0; i-- {
+				if last.Links.Prev == "" {
+					t.Fatalf("page %d has no prev link", i+1)
+				}
+				p, ids := getPage(t, hd, last.Links.Prev)
+				if !slices.Equal(ids, tt.pages[i]) {
+					t.Errorf("prev page %v, want %v", ids, tt.pages[i])
+				}
+				last = p
+			}
+			if last.Links.Prev != "" {
+				t.Errorf("first page has a prev link %q", last.Links.Prev)
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package importer
+
+import (
+	"app/internal"
+	"app/internal/loader"
+	"context"
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// chunkSize is the number of vehicles created at once
+	chunkSize = 500
+	// maxErrors is the number of errors kept for each job, the following ones are only counted
+	maxErrors = 1000
+	// jobTTL is how long a finished job is kept
+	jobTTL = time.Hour
+	// maxFinished is the number of finished jobs kept, the oldest ones are evicted first
+	maxFinished = 100
+)
+
+// NewImportDefault is a function that returns a new instance of ImportDefault
+// the jobs are canceled once ctx is done
+func NewImportDefault(ctx context.Context, sv internal.VehicleService) *ImportDefault {
+	return &ImportDefault{ctx: ctx, sv: sv, jobs: make(map[int]*job)}
+}
+
+// ImportDefault is a struct that implements the VehicleImporter interface
+// the files are parsed by the loaders of the loader package and their vehicles created through the service,
+// chunkSize of them at a time, skipping the invalid ones; finished jobs are kept for jobTTL, maxFinished of them at most
+type ImportDefault struct {
+	// ctx is the parent of the contexts of the jobs
+	ctx context.Context
+	// sv is the service the vehicles are created with
+	sv internal.VehicleService
+	// mu guards jobs, lastId and the state of every job
+	mu sync.Mutex
+	// jobs are the jobs by id, finished ones included
+	jobs map[int]*job
+	// lastId is the last id assigned to a job
+	lastId int
+}
+
+// job is a struct that represents a job and the means to cancel it
+type job struct {
+	internal.ImportJob
+	// cancel stops the job
+	cancel context.CancelFunc
+}
+
+// Start is a method that reads a file in the given format from rd and imports its vehicles in the background
+// the file is spooled to a temporary file with the format as extension, so any format with a loader is supported
+func (im *ImportDefault) Start(rd io.Reader, format string) (ij internal.ImportJob, err error) {
+	// spool file
+	file, err := os.CreateTemp("", "import-*."+format)
+	if err != nil {
+		return
+	}
+	path := file.Name()
+	_, err = io.Copy(file, rd)
+	if errClose := file.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		os.Remove(path)
+		return
+	}
+
+	ld, err := loader.New(path)
+	if err != nil {
+		os.Remove(path)
+		return
+	}
+
+	// register job
+	ctx, cancel := context.WithCancel(im.ctx)
+	im.mu.Lock()
+	im.evict(time.Now())
+	im.lastId++
+	j := &job{
+		ImportJob: internal.ImportJob{
+			Id:        im.lastId,
+			Format:    format,
+			Status:    internal.ImportPending,
+			CreatedAt: time.Now(),
+		},
+		cancel: cancel,
+	}
+	im.jobs[j.Id] = j
+	ij = j.snapshot()
+	im.mu.Unlock()
+
+	go func() {
+		defer os.Remove(path)
+		im.run(ctx, j, ld)
+	}()
+	return
+}
+
+// FindById is a method that returns the current state of a job
+func (im *ImportDefault) FindById(id int) (ij internal.ImportJob, err error) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	j, ok := im.jobs[id]
+	if !ok {
+		return internal.ImportJob{}, internal.NotFound("import_not_found")
+	}
+	return j.snapshot(), nil
+}
+
+// Cancel is a method that cancels a job not finished yet; the vehicles already created are kept
+func (im *ImportDefault) Cancel(id int) (ij internal.ImportJob, err error) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	j, ok := im.jobs[id]
+	if !ok {
+		return internal.ImportJob{}, internal.NotFound("import_not_found")
+	}
+	if !j.FinishedAt.IsZero() {
+		return internal.ImportJob{}, internal.Conflict("import_finished", j.Status)
+	}
+
+	j.cancel()
+	j.Status = internal.ImportCanceled
+	j.FinishedAt = time.Now()
+	return j.snapshot(), nil
+}
+
+// evict is a method that removes the finished jobs older than jobTTL and the oldest ones beyond maxFinished
+// it must be called with mu held
+func (im *ImportDefault) evict(now time.Time) {
+	var finished []*job
+	for id, j := range im.jobs {
+		switch {
+		case j.FinishedAt.IsZero():
+		case now.Sub(j.FinishedAt) > jobTTL:
+			delete(im.jobs, id)
+		default:
+			finished = append(finished, j)
+		}
+	}
+	if len(finished) <= maxFinished {
+		return
+	}
+
+	slices.SortFunc(finished, func(a, b *job) int {
+		return a.FinishedAt.Compare(b.FinishedAt)
+	})
+	for _, j := range finished[:len(finished)-maxFinished] {
+		delete(im.jobs, j.Id)
+	}
+}
+
+// run is a method that imports the vehicles of a job, chunkSize at a time
+func (im *ImportDefault) run(ctx context.Context, j *job, ld internal.VehicleLoader) {
+	defer j.cancel()
+
+	im.mu.Lock()
+	if j.Status == internal.ImportPending {
+		j.Status = internal.ImportRunning
+	}
+	im.mu.Unlock()
+
+	// create vehicles
+	var row int
+	chunk := make([]internal.VehicleAttributes, 0, chunkSize)
+	rows := make([]int, 0, chunkSize)
+	flush := func() (err error) {
+		if len(chunk) == 0 {
+			return nil
+		}
+		results, err := im.sv.CreateSome(chunk, true)
+		if err != nil {
+			return
+		}
+
+		im.mu.Lock()
+		for i, rs := range results {
+			j.Processed++
+			if rs.Created {
+				j.Created++
+				continue
+			}
+			j.fail(internal.ImportError{Row: rows[i], Err: rs.Err})
+		}
+		im.mu.Unlock()
+
+		chunk, rows = chunk[:0], rows[:0]
+		return nil
+	}
+	err := loader.Stream(ld, func(v internal.Vehicle) (err error) {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		row++
+		chunk = append(chunk, v.VehicleAttributes)
+		rows = append(rows, row)
+		if len(chunk) < chunkSize {
+			return nil
+		}
+		return flush()
+	})
+	if err == nil && ctx.Err() == nil {
+		err = flush()
+	}
+
+	// rows that could not be decoded
+	im.mu.Lock()
+	defer im.mu.Unlock()
+	if rp, ok := ld.(loader.RowReporter); ok {
+		for _, e := range rp.Report() {
+			j.Processed++
+			j.fail(internal.ImportError{Line: e.Line, Err: e})
+		}
+	}
+
+	// status
+	switch {
+	case j.Status == internal.ImportCanceled:
+	case err != nil:
+		j.Status = internal.ImportFailed
+		j.Err = err
+		j.FinishedAt = time.Now()
+	default:
+		j.Status = internal.ImportCompleted
+		j.FinishedAt = time.Now()
+	}
+}
+
+// fail is a method that counts a vehicle that could not be created, keeping its error if there is room
+func (j *job) fail(e internal.ImportError) {
+	j.Failed++
+	if len(j.Errors) < maxErrors {
+		j.Errors = append(j.Errors, e)
+	}
+}
+
+// snapshot is a method that returns a copy of the state of the job
+func (j *job) snapshot() internal.ImportJob {
+	ij := j.ImportJob
+	ij.Errors = slices.Clone(j.Errors)
+	return ij
+}
@@ -0,0 +1,223 @@
+package importer
+
+import (
+	"app/internal"
+	"app/internal/repository"
+	"app/internal/service"
+	"app/internal/validator"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// csvHeader is the header of the csv files imported by the tests
+const csvHeader = "brand,model,registration,color,year,passengers,max_speed,fuel_type,transmission,weight,height,length,width\n"
+
+// csvRow is a function that returns the row of a valid vehicle of a csv file, told apart by i
+func csvRow(i int) string {
+	return fmt.Sprintf("Ford,Ka,REG%06d,Red,2010,5,160,gasoline,manual,900,150,380,170\n", i)
+}
+
+// newTestService is a function that returns a service over an empty repository
+func newTestService() (sv internal.VehicleService, rp *repository.VehicleMap) {
+	rp = repository.NewVehicleMap(map[int]internal.Vehicle{})
+	sv = service.NewVehicleDefault(rp, validator.NewVehicleRules(nil))
+	return
+}
+
+// serviceStub is a service whose CreateSome tells when it is entered and waits for release before it returns
+type serviceStub struct {
+	internal.VehicleService
+	entered chan struct{}
+	release chan struct{}
+	err     error
+}
+
+func (s *serviceStub) CreateSome(vs []internal.VehicleAttributes, partial bool) (results []internal.BatchResult, err error) {
+	s.entered <- struct{}{}
+	<-s.release
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.VehicleService.CreateSome(vs, partial)
+}
+
+// wait is a function that returns the job once done holds for it, failing the test after a second
+func wait(t *testing.T, im *ImportDefault, id int, done func(ij internal.ImportJob) bool) internal.ImportJob {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		ij, err := im.FindById(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if done(ij) {
+			return ij
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %+v never done", ij)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// finished is a function that returns true when the job completed or failed
+func finished(ij internal.ImportJob) bool {
+	return ij.Status == internal.ImportCompleted || ij.Status == internal.ImportFailed
+}
+
+func TestImportDefault_Start(t *testing.T) {
+	t.Run("completed", func(t *testing.T) {
+		sv, rp := newTestService()
+		im := NewImportDefault(context.Background(), sv)
+		var sb strings.Builder
+		sb.WriteString(csvHeader)
+		for i := 1; i <= chunkSize+1; i++ {
+			sb.WriteString(csvRow(i))
+		}
+		sb.WriteString(",Uno,NOBRAND,Blue,1995,4,140,diesel,manual,800,140,360,160\n")
+		sb.WriteString("Fiat,Uno,BADYEAR,Blue,old,4,140,diesel,manual,800,140,360,160\n")
+
+		ij, err := im.Start(strings.NewReader(sb.String()), "csv")
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ij.Id != 1 || ij.Format != "csv" || ij.Status != internal.ImportPending || ij.CreatedAt.IsZero() {
+			t.Errorf("job %+v, want a pending one", ij)
+		}
+		ij = wait(t, im, ij.Id, finished)
+		if ij.Status != internal.ImportCompleted || ij.FinishedAt.IsZero() || ij.Err != nil {
+			t.Fatalf("job %+v, want a completed one", ij)
+		}
+		// the vehicles are created in chunks and the invalid ones skipped
+		if ij.Processed != chunkSize+3 || ij.Created != chunkSize+1 || ij.Failed != 2 || len(ij.Errors) != 2 {
+			t.Errorf("processed %d created %d failed %d, want %d %d 2", ij.Processed, ij.Created, ij.Failed, chunkSize+3, chunkSize+1)
+		}
+		var ve *internal.ValidationError
+		if e := ij.Errors[0]; e.Row != chunkSize+2 || !errors.As(e.Err, &ve) {
+			t.Errorf("first error %+v, want an invalid vehicle at row %d", e, chunkSize+2)
+		}
+		if e := ij.Errors[1]; e.Row != 0 || e.Line != chunkSize+4 {
+			t.Errorf("second error %+v, want a row not decoded at line %d", e, chunkSize+4)
+		}
+		if v, _ := rp.FindAll(); len(v) != chunkSize+1 {
+			t.Errorf("%d vehicles, want %d", len(v), chunkSize+1)
+		}
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		sv, _ := newTestService()
+		im := NewImportDefault(context.Background(), sv)
+
+		_, err := im.Start(strings.NewReader("vehicles"), "txt")
+
+		if err == nil {
+			t.Errorf("file of an unknown format started")
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		sv, _ := newTestService()
+		errStorage := errors.New("storage down")
+		stub := &serviceStub{VehicleService: sv, entered: make(chan struct{}, 1), release: make(chan struct{}), err: errStorage}
+		close(stub.release)
+		im := NewImportDefault(context.Background(), stub)
+
+		ij, err := im.Start(strings.NewReader(csvHeader+csvRow(1)), "csv")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ij = wait(t, im, ij.Id, finished)
+		if ij.Status != internal.ImportFailed || !errors.Is(ij.Err, errStorage) || ij.FinishedAt.IsZero() {
+			t.Errorf("job %+v, want one failed by %v", ij, errStorage)
+		}
+	})
+}
+
+func TestImportDefault_Cancel(t *testing.T) {
+	sv, rp := newTestService()
+	stub := &serviceStub{VehicleService: sv, entered: make(chan struct{}), release: make(chan struct{})}
+	im := NewImportDefault(context.Background(), stub)
+	var sb strings.Builder
+	sb.WriteString(csvHeader)
+	for i := 1; i <= chunkSize*2; i++ {
+		sb.WriteString(csvRow(i))
+	}
+
+	ij, err := im.Start(strings.NewReader(sb.String()), "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-stub.entered
+
+	// canceled while its first chunk is being created
+	ij, err = im.Cancel(ij.Id)
+	if err != nil || ij.Status != internal.ImportCanceled || ij.FinishedAt.IsZero() {
+		t.Fatalf("job %+v, %v; want a canceled one", ij, err)
+	}
+	close(stub.release)
+
+	// the chunk already sent is kept and no other one is
+	ij = wait(t, im, ij.Id, func(ij internal.ImportJob) bool {
+		return ij.Processed == chunkSize
+	})
+	select {
+	case <-stub.entered:
+		t.Errorf("second chunk created after the cancel")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if ij.Status != internal.ImportCanceled || ij.Created != chunkSize {
+		t.Errorf("job %+v, want a canceled one with %d vehicles", ij, chunkSize)
+	}
+	if v, _ := rp.FindAll(); len(v) != chunkSize {
+		t.Errorf("%d vehicles, want %d", len(v), chunkSize)
+	}
+
+	// a finished job cannot be canceled
+	if _, err = im.Cancel(ij.Id); !errors.Is(err, internal.ErrVehicleConflict) {
+		t.Errorf("error %v, want %v", err, internal.ErrVehicleConflict)
+	}
+}
+
+func TestImportDefault_NotFound(t *testing.T) {
+	sv, _ := newTestService()
+	im := NewImportDefault(context.Background(), sv)
+
+	if _, err := im.FindById(1); !errors.Is(err, internal.ErrVehicleNotFound) {
+		t.Errorf("find: error %v, want %v", err, internal.ErrVehicleNotFound)
+	}
+	if _, err := im.Cancel(1); !errors.Is(err, internal.ErrVehicleNotFound) {
+		t.Errorf("cancel: error %v, want %v", err, internal.ErrVehicleNotFound)
+	}
+}
+
+func TestImportDefault_Evict(t *testing.T) {
+	sv, _ := newTestService()
+	im := NewImportDefault(context.Background(), sv)
+	now := time.Now()
+	for id := 1; id <= maxFinished+2; id++ {
+		im.jobs[id] = &job{ImportJob: internal.ImportJob{Id: id, FinishedAt: now.Add(time.Duration(id) * time.Second)}}
+	}
+	im.jobs[maxFinished+3] = &job{ImportJob: internal.ImportJob{Id: maxFinished + 3, FinishedAt: now.Add(-2 * jobTTL)}}
+	im.jobs[maxFinished+4] = &job{ImportJob: internal.ImportJob{Id: maxFinished + 4, Status: internal.ImportRunning}}
+
+	im.evict(now.Add(time.Minute))
+
+	// the expired job and the oldest finished ones beyond maxFinished are evicted, running ones are kept
+	if len(im.jobs) != maxFinished+1 {
+		t.Errorf("%d jobs, want %d", len(im.jobs), maxFinished+1)
+	}
+	for _, id := range []int{1, 2, maxFinished + 3} {
+		if _, ok := im.jobs[id]; ok {
+			t.Errorf("job %d kept", id)
+		}
+	}
+	if _, ok := im.jobs[maxFinished+4]; !ok {
+		t.Errorf("running job evicted")
+	}
+}
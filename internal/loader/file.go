@@ -0,0 +1,59 @@
+package loader
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"strings"
+)
+
+// gzipExtension is the extension of the files compressed with gzip
+const gzipExtension = ".gz"
+
+// open is a function that opens the file at path for reading,
+// transparently decompressing it when its name ends with .gz
+func open(path string) (rc io.ReadCloser, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+
+	rc, err = decompress(path, file)
+	if err != nil {
+		file.Close()
+		return
+	}
+	return
+}
+
+// decompress is a function that wraps r in a gzip reader when the name of the file at path ends with .gz, in any case,
+// as the registry matches the extensions; closing the returned reader closes r as well
+func decompress(path string, r io.ReadCloser) (rc io.ReadCloser, err error) {
+	if !strings.HasSuffix(strings.ToLower(path), gzipExtension) {
+		return r, nil
+	}
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return
+	}
+	rc = &gzipReadCloser{Reader: gz, file: r}
+	return
+}
+
+// gzipReadCloser is a struct that closes both the gzip reader and the underlying file
+type gzipReadCloser struct {
+	// Reader is the gzip reader
+	*gzip.Reader
+	// file is the underlying file
+	file io.Closer
+}
+
+// Close is a method that closes the gzip reader and the underlying file
+func (g *gzipReadCloser) Close() (err error) {
+	err = g.Reader.Close()
+	if errFile := g.file.Close(); err == nil {
+		err = errFile
+	}
+	return
+}
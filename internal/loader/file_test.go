@@ -0,0 +1,53 @@
+package loader
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeGzip is a function that writes data compressed with gzip to the file at path
+func writeGzip(t *testing.T, path string, data []byte) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	if _, err = gz.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err = gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	data := []byte(`[{"id": 1}]`)
+	dir := t.TempDir()
+	for _, name := range []string{"vehicles.json.gz", "vehicles.json.GZ", "VEHICLES.JSON.Gz"} {
+		writeGzip(t, filepath.Join(dir, name), data)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "vehicles.json"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"vehicles.json", "vehicles.json.gz", "vehicles.json.GZ", "VEHICLES.JSON.Gz"} {
+		t.Run(name, func(t *testing.T) {
+			rc, err := open(filepath.Join(dir, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rc.Close()
+
+			got, err := io.ReadAll(rc)
+
+			if err != nil || string(got) != string(data) {
+				t.Errorf("read %q, %v; want %q", got, err, data)
+			}
+		})
+	}
+}
@@ -0,0 +1,272 @@
+package loader
+
+import (
+	"app/internal"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrUnknownFormat is returned when no loader is registered for the extension of a file
+	ErrUnknownFormat = errors.New("loader: unknown file format")
+	// ErrUnknownScheme is returned when no loader is registered for the scheme of an uri
+	ErrUnknownScheme = errors.New("loader: unknown uri scheme")
+)
+
+// Factory is a function that returns a loader for the file or uri at path
+type Factory func(path string) (ld internal.VehicleLoader, err error)
+
+// csvFormat holds the format of the CSV files loaded by the default .csv factory, see SetCSVFormat
+var csvFormat struct {
+	mu     sync.RWMutex
+	format CSVFormat
+}
+
+// registry holds the loader factories by file extension and by uri scheme
+var registry = struct {
+	mu         sync.RWMutex
+	extensions map[string]Factory
+	schemes    map[string]Factory
+}{
+	extensions: map[string]Factory{
+		".json": func(path string) (internal.VehicleLoader, error) {
+			return NewVehicleJSONFile(path), nil
+		},
+		".csv": func(path string) (internal.VehicleLoader, error) {
+			csvFormat.mu.RLock()
+			defer csvFormat.mu.RUnlock()
+			return NewVehicleCSVFile(path, csvFormat.format), nil
+		},
+		".ndjson": func(path string) (internal.VehicleLoader, error) {
+			return NewVehicleStreamFile(path, nil), nil
+		},
+		".jsonl": func(path string) (internal.VehicleLoader, error) {
+			return NewVehicleStreamFile(path, nil), nil
+		},
+	},
+	schemes: map[string]Factory{},
+}
+
+// Register is a function that registers the factory of the loader for the files with extension ext, e.g. ".xml"
+// it replaces any factory previously registered for ext
+// files whose name ends with ext followed by .gz are decompressed by the default loaders
+func Register(ext string, f Factory) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	registry.extensions[strings.ToLower(ext)] = f
+}
+
+// RegisterScheme is a function that registers the factory of the loader for the uris with scheme, e.g. "s3"
+// the factory receives the whole uri
+func RegisterScheme(scheme string, f Factory) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	registry.schemes[strings.ToLower(scheme)] = f
+}
+
+// SetCSVFormat is a function that sets the format of the CSV files loaded by the default .csv factory
+// from then on, see CSVFormat; it fails with ErrInvalidCSVFormat, keeping the previous format, when format is not valid
+func SetCSVFormat(format CSVFormat) (err error) {
+	if err = format.Validate(); err != nil {
+		return
+	}
+
+	csvFormat.mu.Lock()
+	defer csvFormat.mu.Unlock()
+
+	csvFormat.format = format
+	return nil
+}
+
+// New is a function that returns the loader registered for path
+// - uris with a registered scheme are handed to the factory of the scheme; file:// uris are treated as paths
+// - directories are loaded file by file, see VehicleDir
+// - files are loaded by the factory registered for their extension, ignoring a trailing .gz
+func New(path string) (ld internal.VehicleLoader, err error) {
+	// scheme
+	local, ok := LocalPath(path)
+	if !ok {
+		scheme, _, _ := strings.Cut(path, "://")
+		scheme = strings.ToLower(scheme)
+		registry.mu.RLock()
+		f, ok := registry.schemes[scheme]
+		registry.mu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownScheme, scheme)
+		}
+		return f(path)
+	}
+	path = local
+
+	// directory
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	if info.IsDir() {
+		return NewVehicleDir(path), nil
+	}
+
+	// file
+	f, ok := factory(path)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, filepath.Base(path))
+	}
+	return f(path)
+}
+
+// LocalPath is a function that returns the path on disk of path, the path itself or the one of a file:// uri
+// ok is false for the uris of any other scheme
+func LocalPath(path string) (local string, ok bool) {
+	scheme, rest, found := strings.Cut(path, "://")
+	if !found {
+		return path, true
+	}
+	if strings.ToLower(scheme) != "file" {
+		return "", false
+	}
+	return rest, true
+}
+
+// factory is a function that returns the factory registered for the longest extension of the file at path
+func factory(path string) (f Factory, ok bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	name := strings.ToLower(filepath.Base(path))
+	for _, n := range []string{name, strings.TrimSuffix(name, gzipExtension)} {
+		// longest extension first, e.g. ".tar.xml" before ".xml"
+		for i := 0; i < len(n); i++ {
+			if n[i] != '.' {
+				continue
+			}
+			if f, ok = registry.extensions[n[i:]]; ok {
+				return
+			}
+		}
+	}
+	return
+}
+
+// NewVehicleDir is a function that returns a new instance of VehicleDir
+func NewVehicleDir(path string) *VehicleDir {
+	return &VehicleDir{
+		path: path,
+	}
+}
+
+// VehicleDir is a struct that implements the LoaderVehicle, VehicleStreamer, ProgressReporter and RowReporter interfaces
+// it loads every file of the directory with a registered extension, in name order;
+// a vehicle in a later file replaces a vehicle with the same id in an earlier one
+type VehicleDir struct {
+	// path is the path to the directory that contains the files
+	path string
+	// progress is the function that receives the progress of the files whose loaders report it
+	progress func(p Progress)
+	// report is the list of rows the files could not load in the last call to Load or Stream
+	report []RowError
+}
+
+// OnProgress is a method that sets the function that receives the progress of the following loads,
+// reported file by file
+func (l *VehicleDir) OnProgress(progress func(p Progress)) {
+	l.progress = progress
+}
+
+// Load is a method that loads the vehicles
+func (l *VehicleDir) Load() (v map[int]internal.Vehicle, err error) {
+	v = make(map[int]internal.Vehicle)
+	err = l.Stream(func(vh internal.Vehicle) error {
+		v[vh.Id] = vh
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+// Stream is a method that calls fn for each vehicle of each file, in file order
+// files whose loader cannot stream are loaded whole and their vehicles passed in id order;
+// the rows skipped by the files whose loaders report them are reported by Report
+func (l *VehicleDir) Stream(fn func(v internal.Vehicle) (err error)) (err error) {
+	l.report = nil
+	entries, err := os.ReadDir(l.path)
+	if err != nil {
+		return
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(l.path, entry.Name())
+		f, ok := factory(path)
+		if !ok {
+			continue
+		}
+
+		var ld internal.VehicleLoader
+		ld, err = f(path)
+		if err != nil {
+			return
+		}
+		if pr, ok := ld.(ProgressReporter); ok && l.progress != nil {
+			pr.OnProgress(l.progress)
+		}
+		err = Stream(ld, fn)
+		if rr, ok := ld.(RowReporter); ok {
+			for _, e := range rr.Report() {
+				e.File = entry.Name()
+				l.report = append(l.report, e)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+	}
+
+	return
+}
+
+// Report is a method that returns the rows the files could not load in the last call to Load or Stream,
+// each with the name of its file
+func (l *VehicleDir) Report() []RowError {
+	return l.report
+}
+
+// Stream is a function that calls fn for each vehicle of ld, streaming them when ld supports it
+// and otherwise passing the loaded vehicles in id order
+func Stream(ld internal.VehicleLoader, fn func(v internal.Vehicle) (err error)) (err error) {
+	if s, ok := ld.(internal.VehicleStreamer); ok {
+		return s.Stream(fn)
+	}
+
+	v, err := ld.Load()
+	if err != nil {
+		return
+	}
+	ids := make([]int, 0, len(v))
+	for id := range v {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		err = fn(v[id])
+		if err != nil {
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,274 @@
+package loader
+
+import (
+	"app/internal"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// loadStub is a loader that cannot stream, it loads its vehicles at once
+type loadStub map[int]internal.Vehicle
+
+func (l loadStub) Load() (v map[int]internal.Vehicle, err error) {
+	return l, nil
+}
+
+func TestNew(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"vehicles.json", "vehicles.CSV", "vehicles.ndjson", "vehicles.jsonl", "vehicles.json.gz", "vehicles.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name, path string
+		loader     string
+		err        error
+	}{
+		{"json", filepath.Join(dir, "vehicles.json"), "*loader.VehicleJSONFile", nil},
+		{"csv in upper case", filepath.Join(dir, "vehicles.CSV"), "*loader.VehicleCSVFile", nil},
+		{"ndjson", filepath.Join(dir, "vehicles.ndjson"), "*loader.VehicleStreamFile", nil},
+		{"jsonl", filepath.Join(dir, "vehicles.jsonl"), "*loader.VehicleStreamFile", nil},
+		{"gzip", filepath.Join(dir, "vehicles.json.gz"), "*loader.VehicleJSONFile", nil},
+		{"file uri", "file://" + filepath.Join(dir, "vehicles.json"), "*loader.VehicleJSONFile", nil},
+		{"directory", dir, "*loader.VehicleDir", nil},
+		{"unknown extension", filepath.Join(dir, "vehicles.txt"), "", ErrUnknownFormat},
+		{"unknown scheme", "ftp://host/vehicles.json", "", ErrUnknownScheme},
+		{"missing file", filepath.Join(dir, "missing.json"), "", os.ErrNotExist},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ld, err := New(tt.path)
+
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error %v, want %v", err, tt.err)
+			}
+			if got := fmt.Sprintf("%T", ld); tt.err == nil && got != tt.loader {
+				t.Errorf("loader %s, want %s", got, tt.loader)
+			}
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	stub := loadStub{1: {Id: 1}}
+	var got string
+	Register(".Registry-Test", func(path string) (internal.VehicleLoader, error) {
+		got = path
+		return stub, nil
+	})
+	RegisterScheme("registry-test", func(uri string) (internal.VehicleLoader, error) {
+		got = uri
+		return stub, nil
+	})
+
+	t.Run("extension", func(t *testing.T) {
+		// the longest extension wins and a trailing .gz is ignored
+		path := writeFile(t, "vehicles.json.registry-test.gz", "")
+
+		ld, err := New(path)
+
+		if err != nil || got != path {
+			t.Errorf("factory called with %s, %v; want %s", got, err, path)
+		}
+		if _, ok := ld.(loadStub); !ok {
+			t.Errorf("loader %T, want the registered one", ld)
+		}
+	})
+
+	t.Run("scheme", func(t *testing.T) {
+		ld, err := New("Registry-Test://bucket/vehicles")
+
+		if err != nil || got != "Registry-Test://bucket/vehicles" {
+			t.Errorf("factory called with %s, %v; want the whole uri", got, err)
+		}
+		if _, ok := ld.(loadStub); !ok {
+			t.Errorf("loader %T, want the registered one", ld)
+		}
+	})
+}
+
+func TestSetCSVFormat(t *testing.T) {
+	t.Cleanup(func() { SetCSVFormat(CSVFormat{}) })
+	path := writeFile(t, "vehicles.csv", "codigo;marca\n1;Ford\n")
+
+	t.Run("format of the csv files", func(t *testing.T) {
+		if err := SetCSVFormat(CSVFormat{Delimiter: ';', Header: map[string]string{"codigo": "id", "marca": "brand"}}); err != nil {
+			t.Fatal(err)
+		}
+
+		ld, err := New(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := ld.Load()
+
+		if err != nil || v[1].Brand != "Ford" {
+			t.Errorf("got %v, %v; want vehicle 1 of brand Ford", v, err)
+		}
+	})
+
+	t.Run("invalid format kept out", func(t *testing.T) {
+		err := SetCSVFormat(CSVFormat{Delimiter: '\n'})
+
+		if !errors.Is(err, ErrInvalidCSVFormat) {
+			t.Fatalf("error %v, want %v", err, ErrInvalidCSVFormat)
+		}
+		ld, _ := New(path)
+		if v, err := ld.Load(); err != nil || v[1].Brand != "Ford" {
+			t.Errorf("got %v, %v; want the previous format", v, err)
+		}
+	})
+}
+
+func TestLocalPath(t *testing.T) {
+	tests := []struct {
+		path, local string
+		ok          bool
+	}{
+		{"docs/db/vehicles.json", "docs/db/vehicles.json", true},
+		{"file:///srv/vehicles.json", "/srv/vehicles.json", true},
+		{"FILE://vehicles.json", "vehicles.json", true},
+		{"s3://bucket/vehicles.json", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			local, ok := LocalPath(tt.path)
+
+			if local != tt.local || ok != tt.ok {
+				t.Errorf("got %q %v, want %q %v", local, ok, tt.local, tt.ok)
+			}
+		})
+	}
+}
+
+func TestVehicleDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.json":   `[{"id":1,"brand":"Ford"},{"id":2,"brand":"Fiat"}]`,
+		"c.ndjson": `{"id":2,"brand":"Honda"}` + "\n" + `{"id":3,"brand":"Kia"}` + "\n",
+		"d.txt":    "not a fleet",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeGzip(t, filepath.Join(dir, "b.csv.gz"), []byte("id,brand\n4,Seat\n"))
+	if err := os.Mkdir(filepath.Join(dir, "e.json"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("files in name order", func(t *testing.T) {
+		var order []int
+		err := NewVehicleDir(dir).Stream(func(vh internal.Vehicle) error {
+			order = append(order, vh.Id)
+			return nil
+		})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the directories and the files of unknown formats are skipped
+		if want := []int{1, 2, 4, 2, 3}; !slices.Equal(order, want) {
+			t.Errorf("ids %v, want %v", order, want)
+		}
+	})
+
+	t.Run("later files replace earlier vehicles", func(t *testing.T) {
+		v, err := NewVehicleDir(dir).Load()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(v) != 4 || v[2].Brand != "Honda" || v[4].Brand != "Seat" {
+			t.Errorf("got %v", v)
+		}
+	})
+
+	t.Run("progress of the files that report it", func(t *testing.T) {
+		var done int
+		ld, err := New(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ld.(ProgressReporter).OnProgress(func(p Progress) {
+			if p.Done {
+				done++
+			}
+		})
+
+		if _, err = ld.Load(); err != nil {
+			t.Fatal(err)
+		}
+		// a.json and c.ndjson report their progress, b.csv.gz does not
+		if done != 2 {
+			t.Errorf("%d files done, want 2", done)
+		}
+	})
+
+	t.Run("rows of the files", func(t *testing.T) {
+		rows := t.TempDir()
+		files := map[string]string{
+			"a.csv": "id,brand,year\n1,Ford,2001\n2,Fiat,two\n",
+			"b.csv": "id,brand,year\nthree,Kia,2003\n4,Seat,2004\n",
+		}
+		for name, data := range files {
+			if err := os.WriteFile(filepath.Join(rows, name), []byte(data), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		ld := NewVehicleDir(rows)
+
+		v, err := ld.Load()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(v) != 2 || v[1].Brand != "Ford" || v[4].Brand != "Seat" {
+			t.Errorf("got %v", v)
+		}
+		var report []string
+		for _, e := range ld.Report() {
+			report = append(report, fmt.Sprintf("%s:%d:%s", e.File, e.Line, e.Column))
+		}
+		if want := []string{"a.csv:3:year", "b.csv:2:id"}; !slices.Equal(report, want) {
+			t.Errorf("report %v, want %v", report, want)
+		}
+		if msg := ld.Report()[0].Error(); !strings.HasPrefix(msg, "a.csv: line 3: column year") {
+			t.Errorf("error %q", msg)
+		}
+	})
+
+	t.Run("error of a file", func(t *testing.T) {
+		bad := t.TempDir()
+		if err := os.WriteFile(filepath.Join(bad, "z.json"), []byte(`[{"id":"one"}]`), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := NewVehicleDir(bad).Load()
+
+		if err == nil {
+			t.Errorf("malformed file loaded")
+		}
+	})
+}
+
+func TestStream(t *testing.T) {
+	var order []int
+	err := Stream(loadStub{3: {Id: 3}, 1: {Id: 1}, 2: {Id: 2}}, func(vh internal.Vehicle) error {
+		order = append(order, vh.Id)
+		return nil
+	})
+
+	// a loader that cannot stream passes its vehicles in id order
+	if err != nil || !slices.Equal(order, []int{1, 2, 3}) {
+		t.Errorf("ids %v, %v; want [1 2 3]", order, err)
+	}
+}
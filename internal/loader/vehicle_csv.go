@@ -0,0 +1,263 @@
+package loader
+
+import (
+	"app/internal"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidCSVFormat is returned when a CSV format has an unusable delimiter or maps a column to an unknown one
+var ErrInvalidCSVFormat = errors.New("loader: invalid csv format")
+
+// CSVFormat is a struct that represents the format of the CSV files of vehicles
+type CSVFormat struct {
+	// Delimiter is the field delimiter, a comma when it is zero
+	Delimiter rune
+	// Header maps the names of the columns of the files to the ones of CSVColumns, e.g. "placa": "registration";
+	// the names are compared in any case and the columns it does not map keep their own name
+	Header map[string]string
+}
+
+// Validate is a method that returns ErrInvalidCSVFormat when the delimiter cannot separate fields
+// or the header maps a column to one that is not in CSVColumns
+func (f CSVFormat) Validate() (err error) {
+	switch {
+	case f.Delimiter == 0:
+	case f.Delimiter == '"' || f.Delimiter == '\r' || f.Delimiter == '\n' || f.Delimiter == utf8.RuneError || !utf8.ValidRune(f.Delimiter):
+		return fmt.Errorf("%w: delimiter %q", ErrInvalidCSVFormat, f.Delimiter)
+	}
+	for name, column := range f.Header {
+		if !slices.Contains(CSVColumns, column) {
+			return fmt.Errorf("%w: column %q mapped to unknown column %q", ErrInvalidCSVFormat, name, column)
+		}
+	}
+	return nil
+}
+
+// NewVehicleCSVFile is a function that returns a new instance of VehicleCSVFile
+// the delimiter of format defaults to a comma when it is zero
+func NewVehicleCSVFile(path string, format CSVFormat) *VehicleCSVFile {
+	if format.Delimiter == 0 {
+		format.Delimiter = ','
+	}
+	return &VehicleCSVFile{
+		path:   path,
+		format: format,
+	}
+}
+
+// VehicleCSVFile is a struct that implements the LoaderVehicle and VehicleStreamer interfaces
+// the first row is a header naming the columns, see CSVColumns and CSVFormat; unknown columns are ignored
+type VehicleCSVFile struct {
+	// path is the path to the file that contains the vehicles in CSV format
+	path string
+	// format is the delimiter and the header mapping of the file
+	format CSVFormat
+	// report is the list of rows that could not be loaded by the last call to Load
+	report []RowError
+}
+
+// CSVColumns are the header names of the columns of a vehicle in CSV format, in export order
+var CSVColumns = []string{
+	"id", "brand", "model", "registration", "color", "year", "passengers",
+	"max_speed", "fuel_type", "transmission", "weight", "height", "length", "width",
+}
+
+// RowReporter is an interface that represents a loader that skips the rows it cannot load and reports them
+type RowReporter interface {
+	// Report is a method that returns the rows that could not be loaded by the last load
+	Report() []RowError
+}
+
+// RowError is a struct that represents a row of a file that could not be loaded
+type RowError struct {
+	// File is the name of the file of the row when it was loaded from a directory, empty otherwise
+	File string
+	// Line is the line of the row in the file
+	Line int
+	// Column is the column that could not be parsed, empty when the whole row is invalid
+	Column string
+	// Value is the value that could not be parsed
+	Value string
+	// Err is the cause
+	Err error
+}
+
+// Error is a method that returns the error message
+func (e RowError) Error() string {
+	msg := fmt.Sprintf("line %d: column %s: invalid value %q: %v", e.Line, e.Column, e.Value, e.Err)
+	if e.Column == "" {
+		msg = fmt.Sprintf("line %d: %v", e.Line, e.Err)
+	}
+	if e.File != "" {
+		msg = e.File + ": " + msg
+	}
+	return msg
+}
+
+// Unwrap is a method that returns the cause
+func (e RowError) Unwrap() error {
+	return e.Err
+}
+
+// Load is a method that loads the vehicles
+// rows with unparseable values are skipped and reported by Report instead of aborting the load
+func (l *VehicleCSVFile) Load() (v map[int]internal.Vehicle, err error) {
+	v = make(map[int]internal.Vehicle)
+	err = l.Stream(func(vh internal.Vehicle) error {
+		v[vh.Id] = vh
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+// Stream is a method that calls fn for each vehicle of the file, in file order
+// rows with unparseable values are skipped and reported by Report
+func (l *VehicleCSVFile) Stream(fn func(v internal.Vehicle) (err error)) (err error) {
+	// open file
+	file, err := open(l.path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	// decode file
+	l.report = nil
+	err = decodeCSV(file, l.format, func(vh VehicleJSON) error {
+		return fn(fromVehicleJSON(vh))
+	}, func(e RowError) {
+		l.report = append(l.report, e)
+	})
+	return
+}
+
+// Report is a method that returns the rows that could not be loaded by the last call to Load or Stream
+func (l *VehicleCSVFile) Report() []RowError {
+	return l.report
+}
+
+// decodeCSV is a function that decodes the vehicles in CSV format from rd, calling fn for each of them
+// and fail for each row that could not be decoded
+// vehicles without an id column are numbered after their position in the file
+func decodeCSV(rd io.Reader, format CSVFormat, fn func(vh VehicleJSON) error, fail func(e RowError)) (err error) {
+	r := csv.NewReader(rd)
+	r.Comma = format.Delimiter
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	// header
+	header, err := r.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = nil
+		}
+		return
+	}
+	mapping := make(map[string]string, len(format.Header))
+	for name, column := range format.Header {
+		mapping[strings.ToLower(strings.TrimSpace(name))] = column
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if column, ok := mapping[name]; ok {
+			name = column
+		}
+		columns[name] = i
+	}
+
+	// rows
+	for n := 1; ; n++ {
+		var record []string
+		record, err = r.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			var pe *csv.ParseError
+			if !errors.As(err, &pe) {
+				return
+			}
+			fail(RowError{Line: pe.Line, Err: pe.Err})
+			continue
+		}
+		line, _ := r.FieldPos(0)
+
+		vh, ok := parseCSVRecord(record, columns, line, fail)
+		if !ok {
+			continue
+		}
+		if _, ok := columns["id"]; !ok {
+			vh.Id = n
+		}
+		err = fn(vh)
+		if err != nil {
+			return
+		}
+	}
+}
+
+// parseCSVRecord is a function that parses a record using the column positions of the header
+// every invalid value of the record is reported to fail
+func parseCSVRecord(record []string, columns map[string]int, line int, fail func(e RowError)) (vh VehicleJSON, ok bool) {
+	ok = true
+	value := func(name string) string {
+		i, exists := columns[name]
+		if !exists || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+	parseInt := func(name string) int {
+		s := value(name)
+		if s == "" {
+			return 0
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			fail(RowError{Line: line, Column: name, Value: s, Err: errors.Unwrap(err)})
+			ok = false
+		}
+		return n
+	}
+	parseFloat := func(name string) float64 {
+		s := value(name)
+		if s == "" {
+			return 0
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			fail(RowError{Line: line, Column: name, Value: s, Err: errors.Unwrap(err)})
+			ok = false
+		}
+		return f
+	}
+
+	vh = VehicleJSON{
+		Id:              parseInt("id"),
+		Brand:           value("brand"),
+		Model:           value("model"),
+		Registration:    value("registration"),
+		Color:           value("color"),
+		FabricationYear: parseInt("year"),
+		Capacity:        parseInt("passengers"),
+		MaxSpeed:        parseFloat("max_speed"),
+		FuelType:        value("fuel_type"),
+		Transmission:    value("transmission"),
+		Weight:          parseFloat("weight"),
+		Height:          parseFloat("height"),
+		Length:          parseFloat("length"),
+		Width:           parseFloat("width"),
+	}
+	return
+}
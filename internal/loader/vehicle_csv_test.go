@@ -0,0 +1,187 @@
+package loader
+
+import (
+	"app/internal"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// writeFile is a function that writes data to the file name of a temporary directory and returns its path
+func writeFile(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestVehicleCSVFile_Load(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		delimiter rune
+		header    map[string]string
+		want      map[int]internal.Vehicle
+	}{
+		{
+			name: "every column",
+			data: "id,brand,model,registration,color,year,passengers,max_speed,fuel_type,transmission,weight,height,length,width\n" +
+				"1,Ford,Ka,ABC123,Red,2010,5,160.5,gasoline,manual,900,150,380,170\n" +
+				"2,Fiat,Uno,DEF456,Blue,1995,4,140,diesel,automatic,800,140,360,160\n",
+			want: map[int]internal.Vehicle{
+				1: {Id: 1, VehicleAttributes: internal.VehicleAttributes{Brand: "Ford", Model: "Ka", Registration: "ABC123", Color: "Red",
+					FabricationYear: 2010, Capacity: 5, MaxSpeed: 160.5, FuelType: "gasoline", Transmission: "manual", Weight: 900,
+					Dimensions: internal.Dimensions{Height: 150, Length: 380, Width: 170}}},
+				2: {Id: 2, VehicleAttributes: internal.VehicleAttributes{Brand: "Fiat", Model: "Uno", Registration: "DEF456", Color: "Blue",
+					FabricationYear: 1995, Capacity: 4, MaxSpeed: 140, FuelType: "diesel", Transmission: "automatic", Weight: 800,
+					Dimensions: internal.Dimensions{Height: 140, Length: 360, Width: 160}}},
+			},
+		},
+		{
+			name: "columns in any order and case, unknown ones ignored",
+			data: "Brand, ID ,owner,Max_Speed\nFord,7,Ana,120\n",
+			want: map[int]internal.Vehicle{
+				7: {Id: 7, VehicleAttributes: internal.VehicleAttributes{Brand: "Ford", MaxSpeed: 120}},
+			},
+		},
+		{
+			name: "vehicles without ids numbered by position",
+			data: "brand\nFord\nFiat\n",
+			want: map[int]internal.Vehicle{
+				1: {Id: 1, VehicleAttributes: internal.VehicleAttributes{Brand: "Ford"}},
+				2: {Id: 2, VehicleAttributes: internal.VehicleAttributes{Brand: "Fiat"}},
+			},
+		},
+		{
+			name:      "another delimiter",
+			data:      "id;brand;max_speed\n1;Ford;99.5\n",
+			delimiter: ';',
+			want: map[int]internal.Vehicle{
+				1: {Id: 1, VehicleAttributes: internal.VehicleAttributes{Brand: "Ford", MaxSpeed: 99.5}},
+			},
+		},
+		{
+			name:      "columns renamed by the header",
+			data:      "Código;Marca;Placa;color\n1;Ford;ABC123;Red\n",
+			delimiter: ';',
+			header:    map[string]string{"código": "id", "MARCA": "brand", "placa": "registration"},
+			want: map[int]internal.Vehicle{
+				1: {Id: 1, VehicleAttributes: internal.VehicleAttributes{Brand: "Ford", Registration: "ABC123", Color: "Red"}},
+			},
+		},
+		{
+			name: "empty file",
+			data: "",
+			want: map[int]internal.Vehicle{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ld := NewVehicleCSVFile(writeFile(t, "vehicles.csv", tt.data), CSVFormat{Delimiter: tt.delimiter, Header: tt.header})
+
+			v, err := ld.Load()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(v) != len(tt.want) {
+				t.Errorf("got %v, want %v", v, tt.want)
+			}
+			for id, vh := range tt.want {
+				if v[id] != vh {
+					t.Errorf("vehicle %d: %+v, want %+v", id, v[id], vh)
+				}
+			}
+			if len(ld.Report()) != 0 {
+				t.Errorf("report %v, want none", ld.Report())
+			}
+		})
+	}
+}
+
+func TestCSVFormat_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		format CSVFormat
+		err    error
+	}{
+		{"default", CSVFormat{}, nil},
+		{"semicolon and header", CSVFormat{Delimiter: ';', Header: map[string]string{"placa": "registration"}}, nil},
+		{"quote delimiter", CSVFormat{Delimiter: '"'}, ErrInvalidCSVFormat},
+		{"line break delimiter", CSVFormat{Delimiter: '\n'}, ErrInvalidCSVFormat},
+		{"invalid rune delimiter", CSVFormat{Delimiter: -1}, ErrInvalidCSVFormat},
+		{"header to an unknown column", CSVFormat{Header: map[string]string{"dono": "owner"}}, ErrInvalidCSVFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.format.Validate(); !errors.Is(err, tt.err) {
+				t.Errorf("error %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestVehicleCSVFile_Report(t *testing.T) {
+	data := "id,brand,year,max_speed\n" +
+		"1,Ford,2010,120\n" +
+		"2,Fiat,old,fast\n" +
+		"3,\"Honda,150\n"
+	ld := NewVehicleCSVFile(writeFile(t, "vehicles.csv", data), CSVFormat{})
+
+	v, err := ld.Load()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := v[1]; len(v) != 1 || !ok {
+		t.Errorf("got %v, want only vehicle 1", v)
+	}
+	// every invalid value of a row is reported, and so is a row the reader cannot split
+	report := ld.Report()
+	if len(report) != 3 {
+		t.Fatalf("report %v, want 3 rows", report)
+	}
+	if report[0].Line != 3 || report[0].Column != "year" || report[0].Value != "old" || !errors.Is(report[0], strconv.ErrSyntax) {
+		t.Errorf("first row %+v", report[0])
+	}
+	if report[1].Line != 3 || report[1].Column != "max_speed" || report[1].Value != "fast" {
+		t.Errorf("second row %+v", report[1])
+	}
+	if report[2].Line != 4 || report[2].Column != "" {
+		t.Errorf("third row %+v", report[2])
+	}
+
+	// the report is the one of the last load
+	if err = os.WriteFile(ld.path, []byte("id\n1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ld.Load(); err != nil || len(ld.Report()) != 0 {
+		t.Errorf("report %v, %v; want none", ld.Report(), err)
+	}
+}
+
+func TestVehicleCSVFile_Gzip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vehicles.csv.gz")
+	writeGzip(t, path, []byte("id,brand\n1,Ford\n2,Fiat\n"))
+
+	v, err := NewVehicleCSVFile(path, CSVFormat{}).Load()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(v) != 2 || v[1].Brand != "Ford" || v[2].Brand != "Fiat" {
+		t.Errorf("got %v", v)
+	}
+}
+
+func TestVehicleCSVFile_Missing(t *testing.T) {
+	_, err := NewVehicleCSVFile(filepath.Join(t.TempDir(), "missing.csv"), CSVFormat{}).Load()
+
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("error %v, want %v", err, os.ErrNotExist)
+	}
+}
@@ -0,0 +1,263 @@
+package loader
+
+import (
+	"app/internal"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+const (
+	// DuplicateFail fails the load on the first duplicate
+	DuplicateFail = "fail"
+	// DuplicateKeepFirst keeps the first vehicle and discards the later ones
+	DuplicateKeepFirst = "keep_first"
+	// DuplicateKeepLast keeps the last vehicle and discards the earlier ones
+	DuplicateKeepLast = "keep_last"
+	// DuplicateRenumber keeps every vehicle, giving the later ones a new id; only applies to ids
+	DuplicateRenumber = "renumber"
+	// DuplicateKeepAll keeps every vehicle and only reports the duplicates; only applies to registrations
+	DuplicateKeepAll = "keep_all"
+)
+
+const (
+	// ActionDiscarded means the later vehicle was discarded
+	ActionDiscarded = "discarded"
+	// ActionReplaced means the earlier vehicle was discarded
+	ActionReplaced = "replaced"
+	// ActionRenumbered means the later vehicle was given a new id
+	ActionRenumbered = "renumbered"
+	// ActionKept means both vehicles were kept
+	ActionKept = "kept"
+)
+
+var (
+	// ErrDuplicate is returned by VehicleDeduplicated when a duplicate is found with the DuplicateFail policy
+	ErrDuplicate = errors.New("loader: duplicate vehicle")
+	// ErrUnknownPolicy is returned when a duplicate policy does not exist or does not apply to its field
+	ErrUnknownPolicy = errors.New("loader: unknown duplicate policy")
+)
+
+// DuplicatePolicy is a struct that represents what to do with duplicate vehicles
+type DuplicatePolicy struct {
+	// Id is the policy for vehicles with the same id, DuplicateKeepLast by default
+	Id string
+	// Registration is the policy for vehicles with the same registration, DuplicateKeepAll by default
+	Registration string
+}
+
+// Validate is a method that returns ErrUnknownPolicy when a policy does not exist or does not apply to its field
+// empty policies are valid, they stand for the defaults
+func (p DuplicatePolicy) Validate() (err error) {
+	switch p.Id {
+	case "", DuplicateFail, DuplicateKeepFirst, DuplicateKeepLast, DuplicateRenumber:
+	default:
+		return fmt.Errorf("%w: %q for ids", ErrUnknownPolicy, p.Id)
+	}
+	switch p.Registration {
+	case "", DuplicateFail, DuplicateKeepFirst, DuplicateKeepLast, DuplicateKeepAll:
+	default:
+		return fmt.Errorf("%w: %q for registrations", ErrUnknownPolicy, p.Registration)
+	}
+	return nil
+}
+
+// Duplicate is a struct that represents a duplicate found while loading the vehicles
+type Duplicate struct {
+	// Field is the duplicated field, id or registration
+	Field string `json:"field"`
+	// Value is the duplicated value
+	Value string `json:"value"`
+	// Position is the position of the later vehicle in the source, starting at 1
+	Position int `json:"position"`
+	// Id is the id of the later vehicle, after renumbering
+	Id int `json:"id"`
+	// ConflictsWith is the id of the earlier vehicle
+	ConflictsWith int `json:"conflicts_with"`
+	// Action is what was done about the duplicate
+	Action string `json:"action"`
+}
+
+// String is a method that describes the duplicate
+func (d Duplicate) String() string {
+	s := fmt.Sprintf("%s %q at position %d (id %d) conflicts with id %d", d.Field, d.Value, d.Position, d.Id, d.ConflictsWith)
+	if d.Action != "" {
+		s += ": " + d.Action
+	}
+	return s
+}
+
+// NewVehicleDeduplicated is a function that returns a new instance of VehicleDeduplicated
+func NewVehicleDeduplicated(ld internal.VehicleLoader, policy DuplicatePolicy) *VehicleDeduplicated {
+	if policy.Id == "" {
+		policy.Id = DuplicateKeepLast
+	}
+	if policy.Registration == "" {
+		policy.Registration = DuplicateKeepAll
+	}
+	return &VehicleDeduplicated{ld: ld, policy: policy}
+}
+
+// VehicleDeduplicated is a struct that implements the LoaderVehicle interface
+// it detects the vehicles of the underlying loader with duplicate ids or registrations and applies the policy to them;
+// duplicate ids can only be seen when the underlying loader implements VehicleStreamer
+type VehicleDeduplicated struct {
+	// ld is the underlying loader
+	ld internal.VehicleLoader
+	// policy is what to do with duplicate vehicles
+	policy DuplicatePolicy
+	// report is the list of duplicates found by the last call to Load
+	report []Duplicate
+}
+
+// Load is a method that loads the vehicles
+// it fails with ErrUnknownPolicy before loading anything when the policy is not valid
+func (l *VehicleDeduplicated) Load() (v map[int]internal.Vehicle, err error) {
+	l.report = nil
+	if err = l.policy.Validate(); err != nil {
+		return nil, err
+	}
+	d := &deduplicator{
+		policy:        l.policy,
+		v:             make(map[int]internal.Vehicle),
+		registrations: make(map[string][]int),
+	}
+
+	// collect
+	err = Stream(l.ld, d.add)
+	if err != nil {
+		l.report = d.report
+		return nil, err
+	}
+
+	// renumber
+	lastId := 0
+	for id := range d.v {
+		if id > lastId {
+			lastId = id
+		}
+	}
+	for _, r := range d.renumbered {
+		lastId++
+		r.vh.Id = lastId
+		d.report[r.report].Id = lastId
+		err = d.insert(r.vh, r.position)
+		if err != nil {
+			l.report = d.report
+			return nil, err
+		}
+	}
+
+	l.report = d.report
+	v = d.v
+	return
+}
+
+// Report is a method that returns the duplicates found by the last call to Load
+func (l *VehicleDeduplicated) Report() []Duplicate {
+	return l.report
+}
+
+// Unwrap is a method that returns the underlying loader
+func (l *VehicleDeduplicated) Unwrap() internal.VehicleLoader {
+	return l.ld
+}
+
+// renumbered is a struct that represents a vehicle waiting for a new id
+type renumbered struct {
+	// vh is the vehicle
+	vh internal.Vehicle
+	// position is the position of the vehicle in the source
+	position int
+	// report is the index of the duplicate in the report
+	report int
+}
+
+// deduplicator is a struct that collects the vehicles of a load applying a duplicate policy
+type deduplicator struct {
+	// policy is what to do with duplicate vehicles
+	policy DuplicatePolicy
+	// v is the vehicles kept, by id
+	v map[int]internal.Vehicle
+	// registrations are the ids of the vehicles kept for each registration, in order;
+	// there are several only with the DuplicateKeepAll policy
+	registrations map[string][]int
+	// renumbered are the vehicles waiting for a new id, assigned once every id is known
+	renumbered []renumbered
+	// position is the position of the last vehicle in the source
+	position int
+	// report is the list of duplicates found
+	report []Duplicate
+}
+
+// add is a method that collects the next vehicle of the source
+func (d *deduplicator) add(vh internal.Vehicle) (err error) {
+	d.position++
+
+	old, exists := d.v[vh.Id]
+	if !exists {
+		return d.insert(vh, d.position)
+	}
+
+	dp := Duplicate{Field: "id", Value: strconv.Itoa(vh.Id), Position: d.position, Id: vh.Id, ConflictsWith: old.Id}
+	switch d.policy.Id {
+	case DuplicateFail:
+		return fmt.Errorf("%w: %s", ErrDuplicate, dp)
+	case DuplicateKeepFirst:
+		dp.Action = ActionDiscarded
+		d.report = append(d.report, dp)
+		return nil
+	case DuplicateRenumber:
+		dp.Action = ActionRenumbered
+		d.report = append(d.report, dp)
+		d.renumbered = append(d.renumbered, renumbered{vh: vh, position: d.position, report: len(d.report) - 1})
+		return nil
+	default:
+		dp.Action = ActionReplaced
+		d.report = append(d.report, dp)
+		d.remove(old)
+		return d.insert(vh, d.position)
+	}
+}
+
+// insert is a method that stores a vehicle whose id is free, applying the policy to its registration
+func (d *deduplicator) insert(vh internal.Vehicle, position int) (err error) {
+	if vh.Registration != "" {
+		if ids := d.registrations[vh.Registration]; len(ids) > 0 {
+			id := ids[len(ids)-1]
+			dp := Duplicate{Field: "registration", Value: vh.Registration, Position: position, Id: vh.Id, ConflictsWith: id}
+			switch d.policy.Registration {
+			case DuplicateFail:
+				return fmt.Errorf("%w: %s", ErrDuplicate, dp)
+			case DuplicateKeepFirst:
+				dp.Action = ActionDiscarded
+				d.report = append(d.report, dp)
+				return nil
+			case DuplicateKeepLast:
+				dp.Action = ActionReplaced
+				d.report = append(d.report, dp)
+				d.remove(d.v[id])
+			default:
+				dp.Action = ActionKept
+				d.report = append(d.report, dp)
+			}
+		}
+		d.registrations[vh.Registration] = append(d.registrations[vh.Registration], vh.Id)
+	}
+
+	d.v[vh.Id] = vh
+	return nil
+}
+
+// remove is a method that discards a stored vehicle
+// its registration is freed only once no other kept vehicle holds it
+func (d *deduplicator) remove(vh internal.Vehicle) {
+	delete(d.v, vh.Id)
+	ids := slices.DeleteFunc(d.registrations[vh.Registration], func(id int) bool { return id == vh.Id })
+	if len(ids) == 0 {
+		delete(d.registrations, vh.Registration)
+		return
+	}
+	d.registrations[vh.Registration] = ids
+}
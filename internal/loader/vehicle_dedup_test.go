@@ -0,0 +1,214 @@
+package loader
+
+import (
+	"app/internal"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// streamStub is a loader that streams its vehicles in order, duplicates included
+type streamStub []internal.Vehicle
+
+func (s streamStub) Load() (v map[int]internal.Vehicle, err error) {
+	v = make(map[int]internal.Vehicle)
+	err = s.Stream(func(vh internal.Vehicle) error {
+		v[vh.Id] = vh
+		return nil
+	})
+	return
+}
+
+func (s streamStub) Stream(fn func(v internal.Vehicle) (err error)) (err error) {
+	for _, vh := range s {
+		if err = fn(vh); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// vehicle is a function that returns a vehicle with an id, a registration and a model
+func vehicle(id int, registration, model string) internal.Vehicle {
+	return internal.Vehicle{Id: id, VehicleAttributes: internal.VehicleAttributes{Registration: registration, Model: model}}
+}
+
+func TestVehicleDeduplicated(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  streamStub
+		policy  DuplicatePolicy
+		want    map[int]string
+		actions []string
+	}{
+		{
+			name:    "ids kept last by default",
+			source:  streamStub{vehicle(1, "A", "first"), vehicle(2, "B", "b"), vehicle(1, "C", "last")},
+			want:    map[int]string{1: "last", 2: "b"},
+			actions: []string{ActionReplaced},
+		},
+		{
+			name:    "ids kept first",
+			source:  streamStub{vehicle(1, "A", "first"), vehicle(1, "C", "last")},
+			policy:  DuplicatePolicy{Id: DuplicateKeepFirst},
+			want:    map[int]string{1: "first"},
+			actions: []string{ActionDiscarded},
+		},
+		{
+			name:    "ids renumbered after the greatest one",
+			source:  streamStub{vehicle(1, "A", "first"), vehicle(1, "B", "second"), vehicle(5, "C", "c"), vehicle(1, "D", "third")},
+			policy:  DuplicatePolicy{Id: DuplicateRenumber},
+			want:    map[int]string{1: "first", 5: "c", 6: "second", 7: "third"},
+			actions: []string{ActionRenumbered, ActionRenumbered},
+		},
+		{
+			name:    "registrations kept all by default",
+			source:  streamStub{vehicle(1, "A", "a"), vehicle(2, "A", "b")},
+			want:    map[int]string{1: "a", 2: "b"},
+			actions: []string{ActionKept},
+		},
+		{
+			name:    "registrations kept all still held after a holder is replaced",
+			source:  streamStub{vehicle(1, "A", "a"), vehicle(2, "A", "b"), vehicle(2, "C", "c"), vehicle(3, "A", "d")},
+			want:    map[int]string{1: "a", 2: "c", 3: "d"},
+			actions: []string{ActionKept, ActionReplaced, ActionKept},
+		},
+		{
+			name:    "registrations kept first",
+			source:  streamStub{vehicle(1, "A", "a"), vehicle(2, "A", "b")},
+			policy:  DuplicatePolicy{Registration: DuplicateKeepFirst},
+			want:    map[int]string{1: "a"},
+			actions: []string{ActionDiscarded},
+		},
+		{
+			name:    "registrations kept last",
+			source:  streamStub{vehicle(1, "A", "a"), vehicle(2, "A", "b")},
+			policy:  DuplicatePolicy{Registration: DuplicateKeepLast},
+			want:    map[int]string{2: "b"},
+			actions: []string{ActionReplaced},
+		},
+		{
+			name:    "empty registrations are not duplicates",
+			source:  streamStub{vehicle(1, "", "a"), vehicle(2, "", "b")},
+			policy:  DuplicatePolicy{Registration: DuplicateFail},
+			want:    map[int]string{1: "a", 2: "b"},
+			actions: nil,
+		},
+		{
+			name:    "renumbered vehicles checked for duplicate registrations",
+			source:  streamStub{vehicle(1, "A", "a"), vehicle(1, "A", "b")},
+			policy:  DuplicatePolicy{Id: DuplicateRenumber, Registration: DuplicateKeepFirst},
+			want:    map[int]string{1: "a"},
+			actions: []string{ActionRenumbered, ActionDiscarded},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ld := NewVehicleDeduplicated(tt.source, tt.policy)
+
+			v, err := ld.Load()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[int]string)
+			for id, vh := range v {
+				if vh.Id != id {
+					t.Errorf("vehicle %d stored under %d", vh.Id, id)
+				}
+				got[id] = vh.Model
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			for id, model := range tt.want {
+				if got[id] != model {
+					t.Errorf("got %v, want %v", got, tt.want)
+					break
+				}
+			}
+			var actions []string
+			for _, d := range ld.Report() {
+				actions = append(actions, d.Action)
+			}
+			if !slices.Equal(actions, tt.actions) {
+				t.Errorf("actions %v, want %v", actions, tt.actions)
+			}
+		})
+	}
+}
+
+func TestVehicleDeduplicated_Fail(t *testing.T) {
+	tests := []struct {
+		name   string
+		source streamStub
+		policy DuplicatePolicy
+	}{
+		{"ids", streamStub{vehicle(1, "A", "a"), vehicle(1, "B", "b")}, DuplicatePolicy{Id: DuplicateFail}},
+		{"registrations", streamStub{vehicle(1, "A", "a"), vehicle(2, "A", "b")}, DuplicatePolicy{Registration: DuplicateFail}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewVehicleDeduplicated(tt.source, tt.policy).Load()
+
+			if !errors.Is(err, ErrDuplicate) {
+				t.Errorf("error %v, want %v", err, ErrDuplicate)
+			}
+		})
+	}
+}
+
+func TestDuplicatePolicy_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy DuplicatePolicy
+		valid  bool
+	}{
+		{"defaults", DuplicatePolicy{}, true},
+		{"every policy of ids", DuplicatePolicy{Id: DuplicateRenumber, Registration: DuplicateFail}, true},
+		{"every policy of registrations", DuplicatePolicy{Id: DuplicateFail, Registration: DuplicateKeepAll}, true},
+		{"unknown policy", DuplicatePolicy{Id: "skip"}, false},
+		{"keep all ids", DuplicatePolicy{Id: DuplicateKeepAll}, false},
+		{"renumber registrations", DuplicatePolicy{Registration: DuplicateRenumber}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate()
+
+			if tt.valid && err != nil {
+				t.Errorf("error %v, want none", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrUnknownPolicy) {
+				t.Errorf("error %v, want %v", err, ErrUnknownPolicy)
+			}
+		})
+	}
+}
+
+// streamFunc is a loader that streams the vehicles of a function
+type streamFunc func(fn func(v internal.Vehicle) (err error)) (err error)
+
+func (s streamFunc) Load() (v map[int]internal.Vehicle, err error) {
+	return nil, s(func(internal.Vehicle) error { return nil })
+}
+
+func (s streamFunc) Stream(fn func(v internal.Vehicle) (err error)) (err error) {
+	return s(fn)
+}
+
+func TestVehicleDeduplicated_InvalidPolicy(t *testing.T) {
+	loaded := false
+	ld := NewVehicleDeduplicated(streamFunc(func(fn func(v internal.Vehicle) (err error)) (err error) {
+		loaded = true
+		return nil
+	}), DuplicatePolicy{Id: "skip"})
+
+	_, err := ld.Load()
+
+	if !errors.Is(err, ErrUnknownPolicy) {
+		t.Errorf("error %v, want %v", err, ErrUnknownPolicy)
+	}
+	if loaded {
+		t.Errorf("vehicles loaded with an invalid policy")
+	}
+}
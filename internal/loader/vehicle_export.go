@@ -0,0 +1,125 @@
+package loader
+
+import (
+	"app/internal"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+)
+
+// VehicleEncoder is an interface that writes vehicles one at a time in a format the loaders read back
+type VehicleEncoder interface {
+	// Encode is a method that writes a vehicle
+	Encode(v internal.Vehicle) (err error)
+	// Close is a method that writes whatever the format needs after the last vehicle and flushes the output
+	// it does not close the underlying writer
+	Close() (err error)
+}
+
+// NewVehicleEncoder is a function that returns the encoder of the vehicles in format, the extension of its loader
+// without the dot: json, ndjson or csv
+func NewVehicleEncoder(w io.Writer, format string) (enc VehicleEncoder, err error) {
+	switch format {
+	case "json":
+		return &vehicleJSONEncoder{w: w, enc: json.NewEncoder(w)}, nil
+	case "ndjson", "jsonl":
+		return &vehicleNDJSONEncoder{enc: json.NewEncoder(w)}, nil
+	case "csv":
+		return &vehicleCSVEncoder{w: csv.NewWriter(w)}, nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+// vehicleJSONEncoder is a struct that writes the vehicles as a JSON array, one vehicle per line
+type vehicleJSONEncoder struct {
+	// w is the output
+	w io.Writer
+	// enc encodes each vehicle
+	enc *json.Encoder
+	// n is the number of vehicles written so far
+	n int
+}
+
+// Encode is a method that writes a vehicle
+func (e *vehicleJSONEncoder) Encode(v internal.Vehicle) (err error) {
+	sep := ","
+	if e.n == 0 {
+		sep = "["
+	}
+	if _, err = io.WriteString(e.w, sep); err != nil {
+		return
+	}
+	e.n++
+	return e.enc.Encode(toVehicleJSON(v))
+}
+
+// Close is a method that closes the array
+func (e *vehicleJSONEncoder) Close() (err error) {
+	if e.n == 0 {
+		_, err = io.WriteString(e.w, "[]\n")
+		return
+	}
+	_, err = io.WriteString(e.w, "]\n")
+	return
+}
+
+// vehicleNDJSONEncoder is a struct that writes the vehicles as newline-delimited JSON
+type vehicleNDJSONEncoder struct {
+	// enc encodes each vehicle
+	enc *json.Encoder
+}
+
+// Encode is a method that writes a vehicle
+func (e *vehicleNDJSONEncoder) Encode(v internal.Vehicle) (err error) {
+	return e.enc.Encode(toVehicleJSON(v))
+}
+
+// Close is a method that does nothing, newline-delimited JSON has no trailer
+func (e *vehicleNDJSONEncoder) Close() (err error) {
+	return
+}
+
+// vehicleCSVEncoder is a struct that writes the vehicles in CSV format, with a header of CSVColumns
+type vehicleCSVEncoder struct {
+	// w is the CSV output
+	w *csv.Writer
+	// header is true once the header has been written
+	header bool
+}
+
+// Encode is a method that writes a vehicle, preceded by the header on the first call
+func (e *vehicleCSVEncoder) Encode(v internal.Vehicle) (err error) {
+	if err = e.writeHeader(); err != nil {
+		return
+	}
+
+	vh := toVehicleJSON(v)
+	// floats are written with the fewest digits that parse back to the same value
+	float := func(f float64) string {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return e.w.Write([]string{
+		strconv.Itoa(vh.Id), vh.Brand, vh.Model, vh.Registration, vh.Color,
+		strconv.Itoa(vh.FabricationYear), strconv.Itoa(vh.Capacity), float(vh.MaxSpeed),
+		vh.FuelType, vh.Transmission, float(vh.Weight), float(vh.Height), float(vh.Length), float(vh.Width),
+	})
+}
+
+// Close is a method that writes the header when there were no vehicles and flushes the output
+func (e *vehicleCSVEncoder) Close() (err error) {
+	if err = e.writeHeader(); err != nil {
+		return
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// writeHeader is a method that writes the header unless it has already been written
+func (e *vehicleCSVEncoder) writeHeader() (err error) {
+	if e.header {
+		return
+	}
+	e.header = true
+	return e.w.Write(CSVColumns)
+}
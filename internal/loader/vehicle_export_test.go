@@ -0,0 +1,85 @@
+package loader
+
+import (
+	"app/internal"
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestVehicleEncoder_RoundTrip(t *testing.T) {
+	fleet := []internal.Vehicle{
+		{Id: 1, VehicleAttributes: internal.VehicleAttributes{Brand: "Ford", Model: "Ka", Registration: "ABC123", Color: "Red",
+			FabricationYear: 2010, Capacity: 5, MaxSpeed: 160.5, FuelType: "gasoline", Transmission: "manual", Weight: 900.25,
+			Dimensions: internal.Dimensions{Height: 150, Length: 380.5, Width: 170}}},
+		{Id: 7, VehicleAttributes: internal.VehicleAttributes{Brand: "Citroën", Model: `C4, "Picasso"`, Registration: "DEF456", Color: "Azul\nclaro",
+			FabricationYear: 1995, Capacity: 7, MaxSpeed: 140, FuelType: "diesel", Transmission: "automatic", Weight: 1400,
+			Dimensions: internal.Dimensions{Height: 160, Length: 450, Width: 180}}},
+	}
+
+	for _, format := range []string{"json", "ndjson", "jsonl", "csv"} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			enc, err := NewVehicleEncoder(&buf, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, vh := range fleet {
+				if err = enc.Encode(vh); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err = enc.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			// the export is loaded back by the loader of its extension
+			ld, err := New(writeFile(t, "vehicles."+format, buf.String()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			v, err := ld.Load()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(v) != len(fleet) {
+				t.Fatalf("%d vehicles, want %d", len(v), len(fleet))
+			}
+			for _, vh := range fleet {
+				if v[vh.Id] != vh {
+					t.Errorf("vehicle %d: %+v, want %+v", vh.Id, v[vh.Id], vh)
+				}
+			}
+		})
+
+		t.Run(format+" without vehicles", func(t *testing.T) {
+			var buf bytes.Buffer
+			enc, err := NewVehicleEncoder(&buf, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = enc.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			ld, err := New(writeFile(t, "vehicles."+format, buf.String()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			v, err := ld.Load()
+
+			if err != nil || len(v) != 0 {
+				t.Errorf("got %v, %v; want no vehicles", v, err)
+			}
+		})
+	}
+}
+
+func TestNewVehicleEncoder_UnknownFormat(t *testing.T) {
+	_, err := NewVehicleEncoder(&bytes.Buffer{}, "xml")
+
+	if !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("error %v, want %v", err, ErrUnknownFormat)
+	}
+}
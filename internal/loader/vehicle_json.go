@@ -2,8 +2,13 @@ package loader
 
 import (
 	"app/internal"
+	"compress/gzip"
 	"encoding/json"
+	"io"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 )
 
 // NewVehicleJSONFile is a function that returns a new instance of VehicleJSONFile
@@ -13,15 +18,18 @@ func NewVehicleJSONFile(path string) *VehicleJSONFile {
 	}
 }
 
-// VehicleJSONFile is a struct that implements the LoaderVehicle interface
+// VehicleJSONFile is a struct that implements the LoaderVehicle, VehicleStreamer, VehicleStorer and ProgressReporter interfaces
 type VehicleJSONFile struct {
 	// path is the path to the file that contains the vehicles in JSON format
 	path string
+	// progress is the function that receives the progress of the loads, nil when nobody listens
+	progress func(p Progress)
 }
 
 // VehicleJSON is a struct that represents a vehicle in JSON format
 type VehicleJSON struct {
 	Id              int     `json:"id"`
+	Version         int     `json:"version,omitempty"`
 	Brand           string  `json:"brand"`
 	Model           string  `json:"model"`
 	Registration    string  `json:"registration"`
@@ -37,46 +45,139 @@ type VehicleJSON struct {
 	Width           float64 `json:"width"`
 }
 
+// fromVehicleJSON is a function that converts a vehicle in JSON format into a vehicle
+func fromVehicleJSON(vh VehicleJSON) internal.Vehicle {
+	return internal.Vehicle{
+		Id:      vh.Id,
+		Version: vh.Version,
+		VehicleAttributes: internal.VehicleAttributes{
+			Brand:           vh.Brand,
+			Model:           vh.Model,
+			Registration:    vh.Registration,
+			Color:           vh.Color,
+			FabricationYear: vh.FabricationYear,
+			Capacity:        vh.Capacity,
+			MaxSpeed:        vh.MaxSpeed,
+			FuelType:        vh.FuelType,
+			Transmission:    vh.Transmission,
+			Weight:          vh.Weight,
+			Dimensions: internal.Dimensions{
+				Height: vh.Height,
+				Length: vh.Length,
+				Width:  vh.Width,
+			},
+		},
+	}
+}
+
+// toVehicleJSON is a function that converts a vehicle into a vehicle in JSON format
+func toVehicleJSON(vh internal.Vehicle) VehicleJSON {
+	return VehicleJSON{
+		Id:              vh.Id,
+		Version:         vh.Version,
+		Brand:           vh.Brand,
+		Model:           vh.Model,
+		Registration:    vh.Registration,
+		Color:           vh.Color,
+		FabricationYear: vh.FabricationYear,
+		Capacity:        vh.Capacity,
+		MaxSpeed:        vh.MaxSpeed,
+		FuelType:        vh.FuelType,
+		Transmission:    vh.Transmission,
+		Weight:          vh.Weight,
+		Height:          vh.Height,
+		Length:          vh.Length,
+		Width:           vh.Width,
+	}
+}
+
 // Load is a method that loads the vehicles
 func (l *VehicleJSONFile) Load() (v map[int]internal.Vehicle, err error) {
-	// open file
-	file, err := os.Open(l.path)
+	v = make(map[int]internal.Vehicle)
+	err = l.Stream(func(vh internal.Vehicle) error {
+		v[vh.Id] = vh
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+// OnProgress is a method that sets the function that receives the progress of the following loads
+func (l *VehicleJSONFile) OnProgress(progress func(p Progress)) {
+	l.progress = progress
+}
+
+// Stream is a method that calls fn for each vehicle of the file, in file order
+// the file is decoded one vehicle at a time, as VehicleStreamFile does
+func (l *VehicleJSONFile) Stream(fn func(v internal.Vehicle) (err error)) (err error) {
+	return NewVehicleStreamFile(l.path, l.progress).Stream(fn)
+}
+
+// Store is a method that writes the vehicles back to the file, compressed when its name ends with .gz, in any case
+// the vehicles are written to a temporary file in the same directory which then replaces the original one,
+// so a crash never leaves a partially written file behind; the file keeps its mode, 0644 when it is new
+func (l *VehicleJSONFile) Store(v map[int]internal.Vehicle) (err error) {
+	// deserialize vehicles, ordered by id
+	vehiclesJSON := make([]VehicleJSON, 0, len(v))
+	for _, vh := range v {
+		vehiclesJSON = append(vehiclesJSON, toVehicleJSON(vh))
+	}
+	sort.Slice(vehiclesJSON, func(i, j int) bool {
+		return vehiclesJSON[i].Id < vehiclesJSON[j].Id
+	})
+
+	// create temporary file
+	file, err := os.CreateTemp(filepath.Dir(l.path), filepath.Base(l.path)+".*.tmp")
 	if err != nil {
 		return
 	}
-	defer file.Close()
+	defer func() {
+		if err != nil {
+			file.Close()
+			os.Remove(file.Name())
+		}
+	}()
 
-	// decode file
-	var vehiclesJSON []VehicleJSON
-	err = json.NewDecoder(file).Decode(&vehiclesJSON)
+	// keep the mode of the original file, temporary files are only readable by their owner
+	mode := os.FileMode(0644)
+	if info, errStat := os.Stat(l.path); errStat == nil {
+		mode = info.Mode().Perm()
+	}
+	err = file.Chmod(mode)
 	if err != nil {
 		return
 	}
 
-	// serialize vehicles
-	v = make(map[int]internal.Vehicle)
-	for _, vh := range vehiclesJSON {
-		v[vh.Id] = internal.Vehicle{
-			Id: vh.Id,
-			VehicleAttributes: internal.VehicleAttributes{
-				Brand:           vh.Brand,
-				Model:           vh.Model,
-				Registration:    vh.Registration,
-				Color:           vh.Color,
-				FabricationYear: vh.FabricationYear,
-				Capacity:        vh.Capacity,
-				MaxSpeed:        vh.MaxSpeed,
-				FuelType:        vh.FuelType,
-				Transmission:    vh.Transmission,
-				Weight:          vh.Weight,
-				Dimensions: internal.Dimensions{
-					Height: vh.Height,
-					Length: vh.Length,
-					Width:  vh.Width,
-				},
-			},
+	// encode file
+	var w io.Writer = file
+	var gz *gzip.Writer
+	if strings.HasSuffix(strings.ToLower(l.path), gzipExtension) {
+		gz = gzip.NewWriter(file)
+		w = gz
+	}
+	err = json.NewEncoder(w).Encode(vehiclesJSON)
+	if err != nil {
+		return
+	}
+	if gz != nil {
+		err = gz.Close()
+		if err != nil {
+			return
 		}
 	}
+	err = file.Sync()
+	if err != nil {
+		return
+	}
+	err = file.Close()
+	if err != nil {
+		return
+	}
 
+	// replace file
+	err = os.Rename(file.Name(), l.path)
 	return
 }
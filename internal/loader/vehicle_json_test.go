@@ -0,0 +1,102 @@
+package loader
+
+import (
+	"app/internal"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVehicleJSONFile_Store(t *testing.T) {
+	v := map[int]internal.Vehicle{
+		1: {Id: 1, VehicleAttributes: internal.VehicleAttributes{Brand: "Ford", Weight: 1500}},
+		2: {Id: 2, VehicleAttributes: internal.VehicleAttributes{Brand: "Fiat", MaxSpeed: 180.5}},
+	}
+	tests := []struct {
+		name string
+		mode os.FileMode
+	}{
+		{"new file", 0},
+		{"private file", 0600},
+		{"shared file", 0664},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "vehicles.json")
+			if tt.mode != 0 {
+				if err := os.WriteFile(path, []byte(`[]`), tt.mode); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chmod(path, tt.mode); err != nil {
+					t.Fatal(err)
+				}
+			}
+			l := NewVehicleJSONFile(path)
+
+			if err := l.Store(v); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := l.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(v) || got[1] != v[1] || got[2] != v[2] {
+				t.Errorf("loaded %+v, want %+v", got, v)
+			}
+			want := tt.mode
+			if want == 0 {
+				want = 0644
+			}
+			if info, err := os.Stat(path); err != nil || info.Mode().Perm() != want {
+				t.Errorf("mode %v, %v; want %v", info.Mode().Perm(), err, want)
+			}
+			// no temporary file is left behind
+			if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+				t.Errorf("%d files in the directory, want 1", len(entries))
+			}
+		})
+	}
+}
+
+func TestVehicleJSONFile_StoreLoad(t *testing.T) {
+	v := map[int]internal.Vehicle{
+		1: {Id: 1, VehicleAttributes: internal.VehicleAttributes{Brand: "Ford", Weight: 1500}},
+		2: {Id: 2, VehicleAttributes: internal.VehicleAttributes{Brand: "Fiat", MaxSpeed: 180.5}},
+	}
+	for _, tt := range []struct {
+		name string
+		gzip bool
+	}{
+		{"vehicles.json", false},
+		{"vehicles.json.gz", true},
+		{"vehicles.json.GZ", true},
+		{"VEHICLES.JSON.Gz", true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.name)
+			l := NewVehicleJSONFile(path)
+
+			if err := l.Store(v); err != nil {
+				t.Fatal(err)
+			}
+			got, err := l.Load()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(v) || got[1] != v[1] || got[2] != v[2] {
+				t.Errorf("loaded %+v, want %+v", got, v)
+			}
+			f, err := os.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			if _, err = gzip.NewReader(f); (err == nil) != tt.gzip {
+				t.Errorf("gzip reader error %v, want compressed %v", err, tt.gzip)
+			}
+		})
+	}
+}
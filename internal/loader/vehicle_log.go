@@ -0,0 +1,185 @@
+package loader
+
+import (
+	"app/internal"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+// NewVehicleLogFile is a function that returns a new instance of VehicleLogFile
+func NewVehicleLogFile(path string) *VehicleLogFile {
+	return &VehicleLogFile{
+		path: path,
+	}
+}
+
+// VehicleLogFile is a struct that implements the VehicleLogger interface
+// operations are appended to the file as one JSON object per line
+type VehicleLogFile struct {
+	// mu guards file
+	mu sync.Mutex
+	// path is the path to the file that contains the operations
+	path string
+	// file is the file opened for appending, lazily opened by Append
+	file logFile
+}
+
+// logFile is an interface that represents the file the operations are appended to
+type logFile interface {
+	io.WriteSeeker
+	io.Closer
+	Sync() (err error)
+	Truncate(size int64) (err error)
+}
+
+// VehicleOperationJSON is a struct that represents a vehicle operation in JSON format
+type VehicleOperationJSON struct {
+	Op       string        `json:"op"`
+	Vehicles []VehicleJSON `json:"vehicles,omitempty"`
+	Id       int           `json:"id,omitempty"`
+	Ids      []int         `json:"ids,omitempty"`
+}
+
+// Append is a method that appends an operation to the log and syncs it to disk
+func (l *VehicleLogFile) Append(op internal.VehicleOperation) (err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// deserialize operation
+	opJSON := VehicleOperationJSON{
+		Op:  op.Type,
+		Id:  op.Id,
+		Ids: op.Ids,
+	}
+	for _, vh := range op.Vehicles {
+		opJSON.Vehicles = append(opJSON.Vehicles, toVehicleJSON(vh))
+	}
+	line, err := json.Marshal(opJSON)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+
+	// open file
+	if l.file == nil {
+		var file *os.File
+		file, err = os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return
+		}
+		l.file = file
+	}
+
+	// write file
+	// - a line that cannot be written and synced whole is cut, so the next one does not start on its fragment
+	offset, err := l.file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return
+	}
+	_, err = l.file.Write(line)
+	if err == nil {
+		err = l.file.Sync()
+	}
+	if err != nil {
+		err = errors.Join(err, l.file.Truncate(offset))
+	}
+	return
+}
+
+// Replay is a method that applies the logged operations on top of the vehicles
+// a trailing line left incomplete by a crash is discarded and cut from the file
+func (l *VehicleLogFile) Replay(v map[int]internal.Vehicle) (err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// open file
+	file, err := os.OpenFile(l.path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	// decode file
+	var offset int64
+	rd := bufio.NewReader(file)
+	for {
+		var line []byte
+		line, err = rd.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// incomplete line
+			err = nil
+			break
+		}
+		if err != nil {
+			return
+		}
+
+		var opJSON VehicleOperationJSON
+		err = json.Unmarshal(bytes.TrimSpace(line), &opJSON)
+		if err != nil {
+			return
+		}
+
+		// apply operation
+		switch opJSON.Op {
+		case internal.OperationDelete:
+			delete(v, opJSON.Id)
+		default:
+			for _, vh := range opJSON.Vehicles {
+				v[vh.Id] = fromVehicleJSON(vh)
+			}
+			// a bulk operation also removes vehicles, none of which is among the ones stored
+			for _, id := range opJSON.Ids {
+				delete(v, id)
+			}
+		}
+		offset += int64(len(line))
+	}
+
+	// cut incomplete line
+	err = file.Truncate(offset)
+	return
+}
+
+// Size is a method that returns the size of the log in bytes
+func (l *VehicleLogFile) Size() (n int64, err error) {
+	info, err := os.Stat(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return
+	}
+	n = info.Size()
+	return
+}
+
+// Truncate is a method that discards every logged operation
+func (l *VehicleLogFile) Truncate() (err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	err = os.Truncate(l.path, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		err = nil
+	}
+	return
+}
+
+// Close is a method that closes the log
+func (l *VehicleLogFile) Close() (err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return
+	}
+	err = l.file.Close()
+	l.file = nil
+	return
+}
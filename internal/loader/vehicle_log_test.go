@@ -0,0 +1,146 @@
+package loader
+
+import (
+	"app/internal"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVehicleLogFile_Replay(t *testing.T) {
+	lines := []string{
+		`{"op":"create","vehicles":[{"id":3,"brand":"Honda","max_speed":120}]}`,
+		`{"op":"update","vehicles":[{"id":1,"brand":"Ford","max_speed":150}]}`,
+		`{"op":"delete","id":2}`,
+		`{"op":"bulk","vehicles":[{"id":4,"brand":"Fiat"}],"ids":[3]}`,
+	}
+	complete := strings.Join(lines, "\n") + "\n"
+
+	tests := []struct {
+		name string
+		// log is the content of the log file
+		log string
+		// want are the brands of the vehicles after the replay, by id
+		want map[int]string
+		// size is the size of the log after the replay
+		size int
+	}{
+		{"empty log", "", map[int]string{1: "Ford", 2: "Fiat"}, 0},
+		{"every operation", complete, map[int]string{1: "Ford", 4: "Fiat"}, len(complete)},
+		{"torn line", complete + `{"op":"delete","i`, map[int]string{1: "Ford", 4: "Fiat"}, len(complete)},
+		{"torn line without its new line", complete + `{"op":"delete","id":1}`, map[int]string{1: "Ford", 4: "Fiat"}, len(complete)},
+		{"torn first line", `{"op":"create","vehic`, map[int]string{1: "Ford", 2: "Fiat"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "vehicles.log")
+			if err := os.WriteFile(path, []byte(tt.log), 0644); err != nil {
+				t.Fatal(err)
+			}
+			v := map[int]internal.Vehicle{
+				1: {Id: 1, VehicleAttributes: internal.VehicleAttributes{Brand: "Ford", MaxSpeed: 100}},
+				2: {Id: 2, VehicleAttributes: internal.VehicleAttributes{Brand: "Fiat"}},
+			}
+			lg := NewVehicleLogFile(path)
+
+			err := lg.Replay(v)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(v) != len(tt.want) {
+				t.Errorf("got %v, want %v", v, tt.want)
+			}
+			for id, brand := range tt.want {
+				if v[id].Id != id || v[id].Brand != brand {
+					t.Errorf("vehicle %d: %+v, want brand %s", id, v[id], brand)
+				}
+			}
+			// the torn line is cut so that the next appends start on a line of their own
+			if n, _ := lg.Size(); n != int64(tt.size) {
+				t.Errorf("size %d, want %d", n, tt.size)
+			}
+		})
+	}
+}
+
+func TestVehicleLogFile_ReplayCorrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vehicles.log")
+	log := `{"op":"delete","id":1}` + "\n" + `not json` + "\n" + `{"op":"delete","id":2}` + "\n"
+	if err := os.WriteFile(path, []byte(log), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := NewVehicleLogFile(path).Replay(map[int]internal.Vehicle{})
+
+	// a complete line that cannot be decoded is not a crash, it must not be skipped
+	if err == nil {
+		t.Errorf("corrupted line replayed")
+	}
+}
+
+func TestVehicleLogFile_AppendAfterReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vehicles.log")
+	if err := os.WriteFile(path, []byte(`{"op":"delete","id":1}`+"\n"+`{"op":"del`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	lg := NewVehicleLogFile(path)
+	if err := lg.Replay(map[int]internal.Vehicle{}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := lg.Append(internal.VehicleOperation{Type: internal.OperationDelete, Id: 2})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := map[int]internal.Vehicle{1: {Id: 1}, 2: {Id: 2}, 3: {Id: 3}}
+	if err = NewVehicleLogFile(path).Replay(v); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := v[3]; len(v) != 1 || !ok {
+		t.Errorf("got %v, want only vehicle 3", v)
+	}
+}
+
+// shortFile is a log file whose writes stop halfway through with err
+type shortFile struct {
+	*os.File
+	err error
+}
+
+func (f shortFile) Write(b []byte) (n int, err error) {
+	n, _ = f.File.Write(b[:len(b)/2])
+	return n, f.err
+}
+
+func TestVehicleLogFile_AppendFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vehicles.log")
+	lg := NewVehicleLogFile(path)
+	if err := lg.Append(internal.VehicleOperation{Type: internal.OperationDelete, Id: 1}); err != nil {
+		t.Fatal(err)
+	}
+	errShort := errors.New("disk full")
+	lg.file = shortFile{File: lg.file.(*os.File), err: errShort}
+
+	err := lg.Append(internal.VehicleOperation{Type: internal.OperationDelete, Id: 2})
+
+	if !errors.Is(err, errShort) {
+		t.Fatalf("error %v, want %v", err, errShort)
+	}
+	// the fragment is cut, so the next append is a line of its own
+	lg.file = lg.file.(shortFile).File
+	if err = lg.Append(internal.VehicleOperation{Type: internal.OperationDelete, Id: 3}); err != nil {
+		t.Fatal(err)
+	}
+	lg.Close()
+	v := map[int]internal.Vehicle{1: {Id: 1}, 2: {Id: 2}, 3: {Id: 3}}
+	if err = NewVehicleLogFile(path).Replay(v); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := v[2]; len(v) != 1 || !ok {
+		t.Errorf("got %v, want only vehicle 2", v)
+	}
+}
@@ -0,0 +1,191 @@
+package loader
+
+import (
+	"app/internal"
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// progressEvery is the number of vehicles between two progress reports
+const progressEvery = 10000
+
+// Progress is a struct that represents the progress of a streaming load
+type Progress struct {
+	// Vehicles is the number of vehicles loaded so far
+	Vehicles int
+	// Bytes is the number of bytes read so far
+	Bytes int64
+	// Total is the size of the file in bytes
+	Total int64
+	// Done is true on the last report
+	Done bool
+}
+
+// String is a method that describes the progress
+func (p Progress) String() string {
+	s := fmt.Sprintf("%d vehicles, %d of %d bytes read", p.Vehicles, p.Bytes, p.Total)
+	if p.Done {
+		s += ", done"
+	}
+	return s
+}
+
+// ProgressReporter is an interface that represents a loader that reports the progress of its loads
+type ProgressReporter interface {
+	// OnProgress is a method that sets the function that receives the progress of the following loads
+	OnProgress(progress func(p Progress))
+}
+
+// NewVehicleStreamFile is a function that returns a new instance of VehicleStreamFile
+// progress is optional and is called every few thousand vehicles and once the load is done
+func NewVehicleStreamFile(path string, progress func(p Progress)) *VehicleStreamFile {
+	return &VehicleStreamFile{
+		path:     path,
+		progress: progress,
+	}
+}
+
+// VehicleStreamFile is a struct that implements the LoaderVehicle, VehicleStreamer and ProgressReporter interfaces
+// the file contains either newline-delimited JSON vehicles or a JSON array of vehicles,
+// which are decoded one at a time
+type VehicleStreamFile struct {
+	// path is the path to the file that contains the vehicles
+	path string
+	// progress is the function that receives the progress of the load
+	progress func(p Progress)
+}
+
+// Load is a method that loads the vehicles
+func (l *VehicleStreamFile) Load() (v map[int]internal.Vehicle, err error) {
+	v = make(map[int]internal.Vehicle)
+	err = l.Stream(func(vh internal.Vehicle) error {
+		v[vh.Id] = vh
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+// OnProgress is a method that sets the function that receives the progress of the following loads
+func (l *VehicleStreamFile) OnProgress(progress func(p Progress)) {
+	l.progress = progress
+}
+
+// Stream is a method that calls fn for each vehicle of the file
+func (l *VehicleStreamFile) Stream(fn func(v internal.Vehicle) (err error)) (err error) {
+	// open file
+	file, err := os.Open(l.path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return
+	}
+
+	// decode file
+	// - progress is measured on the file as read from disk, before decompression
+	cr := &countingReader{r: file}
+	rd, err := decompress(l.path, io.NopCloser(cr))
+	if err != nil {
+		return
+	}
+	defer rd.Close()
+
+	p := Progress{Total: info.Size()}
+	err = decodeJSONStream(rd, func(vh VehicleJSON) error {
+		p.Vehicles++
+		if l.progress != nil && p.Vehicles%progressEvery == 0 {
+			p.Bytes = cr.n
+			l.progress(p)
+		}
+		return fn(fromVehicleJSON(vh))
+	})
+	if err != nil {
+		return
+	}
+
+	if l.progress != nil {
+		p.Bytes = cr.n
+		p.Done = true
+		l.progress(p)
+	}
+	return
+}
+
+// decodeJSONStream is a function that decodes the vehicles in JSON format from rd, calling fn for each of them
+// rd contains either newline-delimited JSON vehicles or a JSON array of vehicles
+func decodeJSONStream(rd io.Reader, fn func(vh VehicleJSON) error) (err error) {
+	br := bufio.NewReader(rd)
+
+	// detect format
+	isArray := false
+	for {
+		var b byte
+		b, err = br.ReadByte()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return
+		}
+		if b == ' ' || b == '\t' || b == '\r' || b == '\n' {
+			continue
+		}
+		isArray = b == '['
+		err = br.UnreadByte()
+		if err != nil {
+			return
+		}
+		break
+	}
+
+	dec := json.NewDecoder(br)
+	if isArray {
+		// opening bracket
+		_, err = dec.Token()
+		if err != nil {
+			return
+		}
+	}
+
+	for n := 1; dec.More(); n++ {
+		var vh VehicleJSON
+		err = dec.Decode(&vh)
+		if err != nil {
+			return fmt.Errorf("vehicle %d: %w", n, err)
+		}
+		err = fn(vh)
+		if err != nil {
+			return
+		}
+	}
+
+	if isArray {
+		// closing bracket
+		_, err = dec.Token()
+	}
+	return
+}
+
+// countingReader is a struct that counts the bytes read from r
+type countingReader struct {
+	// r is the underlying reader
+	r io.Reader
+	// n is the number of bytes read so far
+	n int64
+}
+
+// Read is a method that reads from the underlying reader
+func (c *countingReader) Read(p []byte) (n int, err error) {
+	n, err = c.r.Read(p)
+	c.n += int64(n)
+	return
+}
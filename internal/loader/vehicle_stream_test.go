@@ -0,0 +1,146 @@
+package loader
+
+import (
+	"app/internal"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fixturePath is the path to the fleet the server loads by default
+const fixturePath = "../../docs/db/vehicles_100.json"
+
+func TestVehicleStreamFile_Fixture(t *testing.T) {
+	want, err := NewVehicleJSONFile(fixturePath).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := NewVehicleStreamFile(fixturePath, nil).Load()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(v) != 100 || len(v) != len(want) {
+		t.Fatalf("%d vehicles, want 100", len(v))
+	}
+	for id, vh := range want {
+		if v[id] != vh {
+			t.Errorf("vehicle %d: %+v, want %+v", id, v[id], vh)
+		}
+	}
+	if v[1].Brand != "Hummer" || v[1].FabricationYear != 2008 || v[1].Capacity != 3 {
+		t.Errorf("vehicle 1: %+v", v[1])
+	}
+}
+
+func TestVehicleStreamFile_Formats(t *testing.T) {
+	tests := []struct {
+		name, data string
+	}{
+		{"newline delimited", `{"id":1,"brand":"Ford"}` + "\n" + `{"id":2,"brand":"Fiat"}` + "\n"},
+		{"newline delimited with blank lines", "\n" + `{"id":1,"brand":"Ford"}` + "\r\n\r\n" + `{"id":2,"brand":"Fiat"}`},
+		{"array", `[{"id":1,"brand":"Ford"},{"id":2,"brand":"Fiat"}]`},
+		{"indented array", "  [\n  {\"id\": 1, \"brand\": \"Ford\"},\n  {\"id\": 2, \"brand\": \"Fiat\"}\n]\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var order []int
+			err := NewVehicleStreamFile(writeFile(t, "vehicles.ndjson", tt.data), nil).Stream(func(vh internal.Vehicle) error {
+				order = append(order, vh.Id)
+				return nil
+			})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(order) != "[1 2]" {
+				t.Errorf("ids %v, want [1 2] in file order", order)
+			}
+		})
+	}
+
+	t.Run("empty file", func(t *testing.T) {
+		v, err := NewVehicleStreamFile(writeFile(t, "vehicles.ndjson", " \n"), nil).Load()
+
+		if err != nil || len(v) != 0 {
+			t.Errorf("got %v, %v; want no vehicles", v, err)
+		}
+	})
+}
+
+func TestVehicleStreamFile_Errors(t *testing.T) {
+	t.Run("malformed vehicle", func(t *testing.T) {
+		path := writeFile(t, "vehicles.ndjson", `{"id":1}`+"\n"+`{"id":"two"}`+"\n")
+
+		_, err := NewVehicleStreamFile(path, nil).Load()
+
+		if err == nil || !strings.Contains(err.Error(), "vehicle 2") {
+			t.Errorf("error %v, want one about vehicle 2", err)
+		}
+	})
+
+	t.Run("error of the caller stops the stream", func(t *testing.T) {
+		path := writeFile(t, "vehicles.ndjson", `{"id":1}`+"\n"+`{"id":2}`+"\n")
+		errStop := errors.New("stop")
+		calls := 0
+
+		err := NewVehicleStreamFile(path, nil).Stream(func(vh internal.Vehicle) error {
+			calls++
+			return errStop
+		})
+
+		if !errors.Is(err, errStop) || calls != 1 {
+			t.Errorf("error %v after %d calls, want %v after 1", err, calls, errStop)
+		}
+	})
+}
+
+func TestVehicleStreamFile_Progress(t *testing.T) {
+	var sb strings.Builder
+	for id := 1; id <= progressEvery+1; id++ {
+		fmt.Fprintf(&sb, `{"id":%d}`+"\n", id)
+	}
+
+	for _, name := range []string{"vehicles.ndjson", "vehicles.ndjson.gz"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			if strings.HasSuffix(name, gzipExtension) {
+				writeGzip(t, path, []byte(sb.String()))
+			} else if err := os.WriteFile(path, []byte(sb.String()), 0644); err != nil {
+				t.Fatal(err)
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var reports []Progress
+			ld := NewVehicleStreamFile(path, nil)
+			ld.OnProgress(func(p Progress) {
+				reports = append(reports, p)
+			})
+			v, err := ld.Load()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(v) != progressEvery+1 {
+				t.Errorf("%d vehicles, want %d", len(v), progressEvery+1)
+			}
+			// one report every progressEvery vehicles and the last one; the bytes are the ones of the file on disk
+			if len(reports) != 2 {
+				t.Fatalf("reports %v, want 2", reports)
+			}
+			if p := reports[0]; p.Vehicles != progressEvery || p.Done || p.Bytes <= 0 || p.Total != info.Size() {
+				t.Errorf("first report %+v", p)
+			}
+			if p := reports[1]; p.Vehicles != progressEvery+1 || !p.Done || p.Bytes != info.Size() || p.Total != info.Size() {
+				t.Errorf("last report %+v, want every byte of %d read", p, info.Size())
+			}
+		})
+	}
+}
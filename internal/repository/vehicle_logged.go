@@ -0,0 +1,240 @@
+package repository
+
+import (
+	"app/internal"
+	"errors"
+	"log"
+	"sync"
+)
+
+// NewVehicleLogged is a function that returns a new instance of VehicleLogged
+func NewVehicleLogged(rp internal.VehicleRepository, lg internal.VehicleLogger, st internal.VehicleStorer, maxSize int64) *VehicleLogged {
+	return &VehicleLogged{VehicleRepository: rp, lg: lg, st: st, maxSize: maxSize}
+}
+
+// VehicleLogged is a struct that represents a vehicle repository that appends every write to an operation log
+// a write is only kept once its operation is in the log: when the append fails the write is undone;
+// once the log grows beyond maxSize it is compacted into a fresh snapshot
+type VehicleLogged struct {
+	// VehicleRepository is the underlying repository
+	internal.VehicleRepository
+	// mu serializes writes so the log follows the order of the writes
+	mu sync.Mutex
+	// lg is the log the operations are appended to
+	lg internal.VehicleLogger
+	// st is the storer the snapshot is written to on compaction
+	st internal.VehicleStorer
+	// maxSize is the size in bytes of the log that triggers a compaction, zero disables compaction
+	maxSize int64
+}
+
+// append is a method that appends the operation of a write to the log, undoing the write with u when it fails
+// the log is compacted afterwards if needed; the write is already safe in the log, so a failed compaction
+// is only reported and retried on the next write
+func (r *VehicleLogged) append(op internal.VehicleOperation, u undo) (err error) {
+	err = r.lg.Append(op)
+	if err != nil {
+		return errors.Join(err, u.rollback(r.VehicleRepository))
+	}
+	if r.maxSize <= 0 {
+		return
+	}
+
+	size, errSize := r.lg.Size()
+	if errSize != nil || size <= r.maxSize {
+		return
+	}
+	if errCompact := r.compact(); errCompact != nil {
+		log.Println("compact log:", errCompact)
+	}
+	return
+}
+
+// compact is a method that stores a snapshot of the underlying repository and truncates the log
+// it must be called with mu held, so no write happens between the snapshot and the truncation
+func (r *VehicleLogged) compact() (err error) {
+	v, err := r.VehicleRepository.FindAll()
+	if err != nil {
+		return
+	}
+	err = r.st.Store(v)
+	if err != nil {
+		return
+	}
+	err = r.lg.Truncate()
+	return
+}
+
+// Create is a method that stores a new vehicle and logs it
+func (r *VehicleLogged) Create(v internal.VehicleAttributes) (vh internal.Vehicle, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	vh, err = r.VehicleRepository.Create(v)
+	if err != nil {
+		return
+	}
+	err = r.append(internal.VehicleOperation{Type: internal.OperationCreate, Vehicles: []internal.Vehicle{vh}}, created(vh))
+	if err != nil {
+		return internal.Vehicle{}, err
+	}
+	return
+}
+
+// CreateSome is a method that stores a list of new vehicles and logs them
+func (r *VehicleLogged) CreateSome(vs []internal.VehicleAttributes) (vhs []internal.Vehicle, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	vhs, err = r.VehicleRepository.CreateSome(vs)
+	if err != nil {
+		return
+	}
+	err = r.append(internal.VehicleOperation{Type: internal.OperationCreate, Vehicles: vhs}, created(vhs...))
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+// UpdateSpeed is a method that updates the max speed of a vehicle and logs it
+func (r *VehicleLogged) UpdateSpeed(v internal.UpdateSpeed) (vh internal.Vehicle, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u := before(r.VehicleRepository, v.Id)
+	vh, err = r.VehicleRepository.UpdateSpeed(v)
+	if err != nil {
+		return
+	}
+	err = r.append(internal.VehicleOperation{Type: internal.OperationUpdate, Vehicles: []internal.Vehicle{vh}}, u)
+	if err != nil {
+		return internal.Vehicle{}, err
+	}
+	return
+}
+
+// Replace is a method that replaces every attribute of a vehicle and logs it
+func (r *VehicleLogged) Replace(v internal.Vehicle) (vh internal.Vehicle, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u := before(r.VehicleRepository, v.Id)
+	vh, err = r.VehicleRepository.Replace(v)
+	if err != nil {
+		return
+	}
+	err = r.append(internal.VehicleOperation{Type: internal.OperationUpdate, Vehicles: []internal.Vehicle{vh}}, u)
+	if err != nil {
+		return internal.Vehicle{}, err
+	}
+	return
+}
+
+// Update is a method that updates a vehicle with fn and logs the result
+func (r *VehicleLogged) Update(id, version int, fn func(v internal.Vehicle) (internal.Vehicle, error)) (vh internal.Vehicle, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u := before(r.VehicleRepository, id)
+	vh, err = r.VehicleRepository.Update(id, version, fn)
+	if err != nil {
+		return
+	}
+	err = r.append(internal.VehicleOperation{Type: internal.OperationUpdate, Vehicles: []internal.Vehicle{vh}}, u)
+	if err != nil {
+		return internal.Vehicle{}, err
+	}
+	return
+}
+
+// UpdateFuelType is a method that updates the fuel type of a vehicle and logs it
+func (r *VehicleLogged) UpdateFuelType(uf internal.UpdateFuel) (vh internal.Vehicle, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u := before(r.VehicleRepository, uf.Id)
+	vh, err = r.VehicleRepository.UpdateFuelType(uf)
+	if err != nil {
+		return
+	}
+	err = r.append(internal.VehicleOperation{Type: internal.OperationUpdate, Vehicles: []internal.Vehicle{vh}}, u)
+	if err != nil {
+		return internal.Vehicle{}, err
+	}
+	return
+}
+
+// DeleteById is a method that deletes a vehicle and logs it
+func (r *VehicleLogged) DeleteById(id, version int) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u := before(r.VehicleRepository, id)
+	err = r.VehicleRepository.DeleteById(id, version)
+	if err != nil {
+		return
+	}
+	err = r.append(internal.VehicleOperation{Type: internal.OperationDelete, Id: id}, u)
+	return
+}
+
+// Bulk is a method that applies the operations of a bulk and logs their outcome as a single operation,
+// so that a crash never leaves part of a bulk applied
+func (r *VehicleLogged) Bulk(ops []internal.BulkOperation) (rs internal.BulkResult, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rs, err = r.VehicleRepository.Bulk(ops)
+	if err != nil {
+		return
+	}
+	err = r.append(internal.VehicleOperation{Type: internal.OperationBulk, Vehicles: rs.Vehicles, Ids: rs.Deleted}, bulked(rs))
+	if err != nil {
+		return internal.BulkResult{}, err
+	}
+	return
+}
+
+// Reset is a method that replaces every vehicle of the repository with v and discards the log
+// the logged operations keyed by id applied to the previous vehicles, replayed over another fleet they would
+// overwrite or delete unrelated vehicles; v already is the snapshot of its source, so the log starts over from it
+func (r *VehicleLogged) Reset(v map[int]internal.Vehicle) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// the log is truncated first, so a failure leaves both the vehicles and their log as they were
+	err = r.lg.Truncate()
+	if err != nil {
+		return
+	}
+	err = r.VehicleRepository.Reset(v)
+	return
+}
+
+// Restore is a method that puts back some vehicles and deletes others, and logs it as a bulk
+func (r *VehicleLogged) Restore(vs []internal.Vehicle, ids []int) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	touched := append([]int{}, ids...)
+	for _, vh := range vs {
+		touched = append(touched, vh.Id)
+	}
+	u := before(r.VehicleRepository, touched...)
+	err = r.VehicleRepository.Restore(vs, ids)
+	if err != nil {
+		return
+	}
+	err = r.append(internal.VehicleOperation{Type: internal.OperationBulk, Vehicles: vs, Ids: ids}, u)
+	return
+}
+
+// Snapshot is a method that stores the current vehicles and truncates the log, which they already hold
+// it takes the lock of the writes, so the log is never truncated past a write missing from the snapshot
+func (r *VehicleLogged) Snapshot() (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.compact()
+}
@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"app/internal"
+	"errors"
+	"testing"
+)
+
+// loggerStub is a logger that records the operations appended, or fails with err
+type loggerStub struct {
+	ops       []internal.VehicleOperation
+	err       error
+	truncated int
+}
+
+func (l *loggerStub) Append(op internal.VehicleOperation) (err error) {
+	if l.err != nil {
+		return l.err
+	}
+	l.ops = append(l.ops, op)
+	return nil
+}
+
+func (l *loggerStub) Replay(v map[int]internal.Vehicle) (err error) {
+	return nil
+}
+
+func (l *loggerStub) Size() (n int64, err error) {
+	return int64(len(l.ops)), nil
+}
+
+func (l *loggerStub) Truncate() (err error) {
+	l.ops = nil
+	l.truncated++
+	return nil
+}
+
+func TestVehicleLogged_AppendFailure(t *testing.T) {
+	errAppend := errors.New("disk full")
+	tests := []struct {
+		name  string
+		write func(rp internal.VehicleRepository) error
+	}{
+		{"create", func(rp internal.VehicleRepository) error {
+			_, err := rp.Create(internal.VehicleAttributes{Brand: "Honda"})
+			return err
+		}},
+		{"create some", func(rp internal.VehicleRepository) error {
+			_, err := rp.CreateSome([]internal.VehicleAttributes{{Brand: "Honda"}, {Brand: "Honda"}})
+			return err
+		}},
+		{"update speed", func(rp internal.VehicleRepository) error {
+			_, err := rp.UpdateSpeed(internal.UpdateSpeed{Id: 1, Speed: 1})
+			return err
+		}},
+		{"update fuel type", func(rp internal.VehicleRepository) error {
+			_, err := rp.UpdateFuelType(internal.UpdateFuel{Id: 1, FuelType: "diesel"})
+			return err
+		}},
+		{"replace", func(rp internal.VehicleRepository) error {
+			_, err := rp.Replace(internal.Vehicle{Id: 1})
+			return err
+		}},
+		{"update", func(rp internal.VehicleRepository) error {
+			_, err := rp.Update(1, 0, func(v internal.Vehicle) (internal.Vehicle, error) {
+				v.Color = "Red"
+				return v, nil
+			})
+			return err
+		}},
+		{"delete", func(rp internal.VehicleRepository) error {
+			return rp.DeleteById(1, 0)
+		}},
+		{"bulk", func(rp internal.VehicleRepository) error {
+			_, err := rp.Bulk([]internal.BulkOperation{
+				{Type: internal.OperationCreate, Attributes: internal.VehicleAttributes{Brand: "Honda"}},
+				{Type: internal.OperationUpdate, Id: 1, Update: func(v internal.Vehicle) (internal.Vehicle, error) {
+					v.MaxSpeed = 1
+					return v, nil
+				}},
+				{Type: internal.OperationDelete, Id: 2},
+			})
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mp := newTestMap(3)
+			before, _ := mp.FindAll()
+			rp := NewVehicleLogged(mp, &loggerStub{err: errAppend}, &storerStub{}, 0)
+
+			err := tt.write(rp)
+
+			if !errors.Is(err, errAppend) {
+				t.Fatalf("error %v, want %v", err, errAppend)
+			}
+			after, _ := mp.FindAll()
+			if len(after) != len(before) {
+				t.Fatalf("%d vehicles, want %d", len(after), len(before))
+			}
+			for id, vh := range before {
+				if after[id] != vh {
+					t.Errorf("vehicle %d changed: %+v", id, after[id])
+				}
+			}
+		})
+	}
+}
+
+func TestVehicleLogged_Compaction(t *testing.T) {
+	lg, st := &loggerStub{}, &storerStub{}
+	rp := NewVehicleLogged(newTestMap(1), lg, st, 2)
+
+	for i := 0; i < 3; i++ {
+		if _, err := rp.UpdateSpeed(internal.UpdateSpeed{Id: 1, Speed: float64(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the third write grows the log beyond 2 operations
+	if lg.truncated != 1 || len(lg.ops) != 0 {
+		t.Fatalf("truncated %d times with %d operations left, want once with none", lg.truncated, len(lg.ops))
+	}
+	if vh := st.v[1]; vh.Version != 4 || vh.MaxSpeed != 2 {
+		t.Errorf("snapshot %+v, want the vehicle at version 4", vh)
+	}
+}
+
+func TestVehicleLogged_Snapshot(t *testing.T) {
+	t.Run("stores and truncates", func(t *testing.T) {
+		lg, st := &loggerStub{}, &storerStub{}
+		rp := NewVehicleLogged(newTestMap(2), lg, st, 0)
+		if _, err := rp.Create(internal.VehicleAttributes{}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := rp.Snapshot(); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(st.v) != 3 || lg.truncated != 1 {
+			t.Errorf("stored %d vehicles and truncated %d times, want 3 and once", len(st.v), lg.truncated)
+		}
+	})
+
+	t.Run("keeps the log when the store fails", func(t *testing.T) {
+		errStore := errors.New("read-only")
+		lg := &loggerStub{}
+		rp := NewVehicleLogged(newTestMap(2), lg, &storerStub{err: errStore}, 0)
+		if _, err := rp.Create(internal.VehicleAttributes{}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := rp.Snapshot(); !errors.Is(err, errStore) {
+			t.Fatalf("error %v, want %v", err, errStore)
+		}
+
+		if lg.truncated != 0 || len(lg.ops) != 1 {
+			t.Errorf("truncated %d times with %d operations left, want none and 1", lg.truncated, len(lg.ops))
+		}
+	})
+}
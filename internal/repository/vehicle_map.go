@@ -2,7 +2,9 @@ package repository
 
 import (
 	"app/internal"
-	"fmt"
+	"slices"
+	"sort"
+	"sync"
 )
 
 // NewVehicleMap is a function that returns a new instance of VehicleMap
@@ -12,17 +14,48 @@ func NewVehicleMap(db map[int]internal.Vehicle) *VehicleMap {
 	if db != nil {
 		defaultDb = db
 	}
-	return &VehicleMap{db: defaultDb}
+
+	// last id assigned
+	lastId := 0
+	for key, value := range defaultDb {
+		if key > lastId {
+			lastId = key
+		}
+		// vehicles loaded without a version start at the first one
+		if value.Version == 0 {
+			value.Version = 1
+			defaultDb[key] = value
+		}
+	}
+
+	return &VehicleMap{db: defaultDb, lastId: lastId}
+}
+
+// checkVersion is a function that returns an error of kind ErrPreconditionFailed
+// when the vehicle is not at the version expected, any version is expected when it is 0
+func checkVersion(vh internal.Vehicle, version int) (err error) {
+	if version != 0 && vh.Version != version {
+		return internal.PreconditionFailed("version_mismatch", vh.Version)
+	}
+	return nil
 }
 
 // VehicleMap is a struct that represents a vehicle repository
+// it is safe for concurrent use by multiple goroutines
 type VehicleMap struct {
+	// mu guards db: reads take the read lock, writes take the write lock
+	mu sync.RWMutex
 	// db is a map of vehicles
 	db map[int]internal.Vehicle
+	// lastId is the last id assigned to a vehicle
+	lastId int
 }
 
 // FindAll is a method that returns a map of all vehicles
 func (r *VehicleMap) FindAll() (v map[int]internal.Vehicle, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	v = make(map[int]internal.Vehicle)
 
 	// copy db
@@ -33,41 +66,68 @@ func (r *VehicleMap) FindAll() (v map[int]internal.Vehicle, err error) {
 	return
 }
 
-func (r *VehicleMap) FindByColorAndYear(vehicle internal.VehicleAttributes) (v map[int]internal.Vehicle, err error) {
-	v = make(map[int]internal.Vehicle)
+// FindById is a method that returns the vehicle with the given id
+func (r *VehicleMap) FindById(id int) (vh internal.Vehicle, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 
-	for key, value := range r.db {
-		if vehicle.Color == value.Color && vehicle.FabricationYear == value.FabricationYear {
-			v[key] = value
-		}
+	vh, ok := r.db[id]
+	if !ok {
+		return internal.Vehicle{}, internal.NotFound("vehicle_not_found")
 	}
 
-	if len(v) == 0 {
-		return v, err
+	return vh, nil
+}
+
+// Find is a method that returns the page of the vehicles that match the query, sorted as it asks
+func (r *VehicleMap) Find(q internal.VehicleQuery) (p internal.VehiclePage, err error) {
+	r.mu.RLock()
+	vs := make([]internal.Vehicle, 0, len(r.db))
+	for _, value := range r.db {
+		if q.Filter == nil || q.Filter.Match(value) {
+			vs = append(vs, value)
+		}
 	}
+	r.mu.RUnlock()
 
-	return v, nil
+	return internal.Paginate(vs, q), nil
 }
 
-func (r *VehicleMap) FindByBrandAndYearInterval(req internal.BrandYearRangeSearchType) (v map[int]internal.Vehicle, err error) {
-	v = make(map[int]internal.Vehicle)
-
+// Stream is a method that calls fn for each vehicle that matches q.Filter, sorted as q asks, the page aside
+// only the ids are sorted up front, each vehicle is read when its turn comes so fn runs without the lock held
+func (r *VehicleMap) Stream(q internal.VehicleQuery, fn func(v internal.Vehicle) (err error)) (err error) {
+	r.mu.RLock()
+	ids := make([]int, 0, len(r.db))
 	for key, value := range r.db {
-		if value.Brand == req.Brand {
-			if value.FabricationYear >= req.StartYear && value.FabricationYear <= req.EndYear {
-				v[key] = value
-			}
+		if q.Filter == nil || q.Filter.Match(value) {
+			ids = append(ids, key)
 		}
 	}
+	sort.Slice(ids, func(i, j int) bool {
+		return q.Less(r.db[ids[i]], r.db[ids[j]])
+	})
+	r.mu.RUnlock()
+
+	for _, id := range ids {
+		r.mu.RLock()
+		vh, ok := r.db[id]
+		r.mu.RUnlock()
+		// skip the vehicles deleted, or changed so they no longer match, since the ids were sorted
+		if !ok || (q.Filter != nil && !q.Filter.Match(vh)) {
+			continue
+		}
 
-	if len(v) == 0 {
-		return v, err
+		if err = fn(vh); err != nil {
+			return
+		}
 	}
-
-	return v, nil
+	return
 }
 
 func (r *VehicleMap) GetAverageSpeedByBrand(b string) (v float64, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	var brandList []internal.Vehicle
 	for _, i := range r.db {
 		if b == i.Brand {
@@ -76,7 +136,7 @@ func (r *VehicleMap) GetAverageSpeedByBrand(b string) (v float64, err error) {
 	}
 
 	if len(brandList) == 0 {
-		return 0, err
+		return 0, internal.NotFound("brand_not_found")
 	}
 
 	var sumSpeed float64
@@ -88,144 +148,165 @@ func (r *VehicleMap) GetAverageSpeedByBrand(b string) (v float64, err error) {
 	return sumSpeed / float64(len(brandList)), nil
 }
 
-func (r *VehicleMap) Create(v internal.VehicleAttributes) (err error) {
-	vehicleList := make(map[int]internal.Vehicle)
+// Create is a method that stores a new vehicle with the next available id
+func (r *VehicleMap) Create(v internal.VehicleAttributes) (vh internal.Vehicle, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	maxKey := 0
-	for _, v := range r.db {
-		if v.Id > maxKey {
-			maxKey = v.Id
-		}
+	vh = internal.Vehicle{
+		Id:                r.lastId + 1,
+		Version:           1,
+		VehicleAttributes: v,
 	}
 
-	newID := maxKey + 1
-
-	if _, exists := r.db[newID]; exists {
-		return err
+	if _, exists := r.db[vh.Id]; exists {
+		return internal.Vehicle{}, internal.Conflict("vehicle_id_conflict")
 	}
 
-	vehicleList[newID] = internal.Vehicle{
-		Id:                newID,
-		VehicleAttributes: v,
-	}
+	r.db[vh.Id] = vh
+	r.lastId = vh.Id
 
-	return nil
+	return vh, nil
 }
 
-func (r *VehicleMap) CreateSome(vs []internal.VehicleAttributes) (err error) {
-	vehicleList := make(map[int]internal.Vehicle)
-
-	maxKey := 0
-	for k := range r.db {
-		if k > maxKey {
-			maxKey = k
-		}
-	}
+// CreateSome is a method that stores a list of new vehicles with consecutive ids
+// either every vehicle is stored or none is
+func (r *VehicleMap) CreateSome(vs []internal.VehicleAttributes) (vhs []internal.Vehicle, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
+	vhs = make([]internal.Vehicle, 0, len(vs))
 	for i, v := range vs {
-		newID := maxKey + 1 + i
+		newID := r.lastId + 1 + i
 
 		if _, exists := r.db[newID]; exists {
-			return err
+			return nil, internal.Conflict("vehicles_id_conflict")
 		}
 
-		vehicleList[i] = internal.Vehicle{
+		vhs = append(vhs, internal.Vehicle{
 			Id:                newID,
+			Version:           1,
 			VehicleAttributes: v,
-		}
+		})
 	}
 
-	return nil
+	for _, vh := range vhs {
+		r.db[vh.Id] = vh
+		r.lastId = vh.Id
+	}
+
+	return vhs, nil
 }
 
-func (r *VehicleMap) UpdateSpeed(v internal.UpdateSpeed) (err error) {
-	var vehicle internal.Vehicle
+// UpdateSpeed is a method that updates the max speed of a vehicle
+// when v.Version is not 0 the vehicle must be at that version
+func (r *VehicleMap) UpdateSpeed(v internal.UpdateSpeed) (vh internal.Vehicle, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	for i := 0; i <= len(r.db); i++ {
-		if r.db[i].Id == v.Id {
-			vehicle = r.db[i]
-			vehicle.MaxSpeed = v.Speed
-			break
-		}
+	vh, ok := r.db[v.Id]
+	if !ok {
+		return internal.Vehicle{}, internal.NotFound("vehicle_not_found")
 	}
-
-	if vehicle.Id == 0 {
-		return err
+	if err = checkVersion(vh, v.Version); err != nil {
+		return internal.Vehicle{}, err
 	}
 
-	return nil
+	vh.MaxSpeed = v.Speed
+	vh.Version++
+	r.db[vh.Id] = vh
+
+	return vh, nil
 }
 
-func (r *VehicleMap) GetByFuelType(t string) (v map[int]internal.Vehicle, err error) {
-	v = make(map[int]internal.Vehicle)
-	for key, i := range r.db {
-		if i.FuelType == t {
-			v[key] = i
-		}
-	}
+// Replace is a method that replaces every attribute of an existing vehicle
+// when v.Version is not 0 the vehicle must be at that version
+func (r *VehicleMap) Replace(v internal.Vehicle) (vh internal.Vehicle, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	if len(v) == 0 {
-		return v, err
+	old, ok := r.db[v.Id]
+	if !ok {
+		return internal.Vehicle{}, internal.NotFound("vehicle_not_found")
+	}
+	if err = checkVersion(old, v.Version); err != nil {
+		return internal.Vehicle{}, err
 	}
 
-	fmt.Println("len", len(v))
+	v.Version = old.Version + 1
+	r.db[v.Id] = v
 
 	return v, nil
 }
 
-func (r *VehicleMap) DeleteById(id int) (err error) {
-	found := false
-	db := r.db
-	for key := range r.db {
-		if key == id {
-			delete(db, key)
-			found = true
-		}
+// Update is a method that replaces a vehicle by the result of fn on it, atomically
+// the vehicle keeps its id whatever fn returns; when version is not 0 the vehicle must be at that version
+func (r *VehicleMap) Update(id, version int, fn func(v internal.Vehicle) (internal.Vehicle, error)) (vh internal.Vehicle, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old, ok := r.db[id]
+	if !ok {
+		return internal.Vehicle{}, internal.NotFound("vehicle_not_found")
+	}
+	if err = checkVersion(old, version); err != nil {
+		return internal.Vehicle{}, err
 	}
 
-	if !found {
-		return fmt.Errorf("404 Not Found: Veículo não encontrado.")
+	vh, err = fn(old)
+	if err != nil {
+		return internal.Vehicle{}, err
 	}
 
-	return nil
+	vh.Id = id
+	vh.Version = old.Version + 1
+	r.db[id] = vh
+
+	return vh, nil
 }
 
-func (r *VehicleMap) GetByTransmissionType(t string) (v map[int]internal.Vehicle, err error) {
-	v = make(map[int]internal.Vehicle)
+func (r *VehicleMap) DeleteById(id, version int) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	for key, vs := range r.db {
-		if vs.Transmission == t {
-			v[key] = vs
-		}
+	vh, ok := r.db[id]
+	if !ok {
+		return internal.NotFound("vehicle_not_found")
 	}
-
-	if len(v) == 0 {
-		return v, fmt.Errorf("404 Not Found: Não foram encontrados veículos com esse tipo de transmissão.")
+	if err = checkVersion(vh, version); err != nil {
+		return err
 	}
 
-	return v, err
+	delete(r.db, id)
+
+	return nil
 }
 
-func (r *VehicleMap) UpdateFuelType(u internal.UpdateFuel) (err error) {
-	found := false
+// UpdateFuelType is a method that updates the fuel type of a vehicle
+// when u.Version is not 0 the vehicle must be at that version
+func (r *VehicleMap) UpdateFuelType(u internal.UpdateFuel) (vh internal.Vehicle, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	for _, vs := range r.db {
-		if vs.Id == u.Id {
-			found = true
-			temp := vs
-			temp.FuelType = u.FuelType
-			fmt.Println("vehicle", temp)
-		}
+	vh, ok := r.db[u.Id]
+	if !ok {
+		return internal.Vehicle{}, internal.NotFound("vehicle_not_found")
 	}
-
-	if !found {
-		return fmt.Errorf("404 Not Found: Veículo não encontrado")
+	if err = checkVersion(vh, u.Version); err != nil {
+		return internal.Vehicle{}, err
 	}
 
-	return nil
+	vh.FuelType = u.FuelType
+	vh.Version++
+	r.db[vh.Id] = vh
+
+	return vh, nil
 }
 
 func (r *VehicleMap) GetAverageCapacityByBrand(b string) (v float64, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	var sum int
 	var list []internal.Vehicle
 	for _, i := range r.db {
@@ -236,7 +317,7 @@ func (r *VehicleMap) GetAverageCapacityByBrand(b string) (v float64, err error)
 	}
 
 	if len(list) == 0 {
-		return 0, fmt.Errorf("404 Not Found: Não foram encontrados veículos dessa marca.")
+		return 0, internal.NotFound("brand_not_found")
 	}
 
 	v = float64(sum) / float64(len(list))
@@ -244,35 +325,146 @@ func (r *VehicleMap) GetAverageCapacityByBrand(b string) (v float64, err error)
 	return v, err
 }
 
-func (r *VehicleMap) GetByDimensions(minLength, maxLength, minWidth, maxWidth float64) (v map[int]internal.Vehicle, err error) {
-	v = make(map[int]internal.Vehicle)
+// Bulk is a method that applies the operations in order and atomically: either all of them or none
+// the operations are applied to copies of the vehicles they change, which are stored once all of them succeed
+func (r *VehicleMap) Bulk(ops []internal.BulkOperation) (rs internal.BulkResult, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// staged are the vehicles changed by the operations, nil for the deleted ones
+	staged := make(map[int]*internal.Vehicle)
+	get := func(id int) (internal.Vehicle, bool) {
+		if vh, ok := staged[id]; ok {
+			if vh == nil {
+				return internal.Vehicle{}, false
+			}
+			return *vh, true
+		}
+		vh, ok := r.db[id]
+		return vh, ok
+	}
+	lastId := r.lastId
+
+	for i, op := range ops {
+		// vehicles of the operation
+		var ids []int
+		switch {
+		case op.Type == internal.OperationCreate:
+		case op.Filter != nil:
+			for id := range r.db {
+				if vh, ok := get(id); ok && op.Filter.Match(vh) {
+					ids = append(ids, id)
+				}
+			}
+			for id, vh := range staged {
+				if _, ok := r.db[id]; !ok && vh != nil && op.Filter.Match(*vh) {
+					ids = append(ids, id)
+				}
+			}
+			slices.Sort(ids)
+		default:
+			vh, ok := get(op.Id)
+			if !ok {
+				return internal.BulkResult{}, &internal.OperationError{Index: i, Err: internal.NotFound("vehicle_not_found")}
+			}
+			if err = checkVersion(vh, op.Version); err != nil {
+				return internal.BulkResult{}, &internal.OperationError{Index: i, Err: err}
+			}
+			ids = []int{op.Id}
+		}
 
-	for key, i := range r.db {
-		if i.Dimensions.Length >= minLength && i.Dimensions.Length <= maxLength {
-			if i.Dimensions.Width >= minWidth && i.Dimensions.Width <= maxWidth {
-				v[key] = i
+		// apply operation
+		switch op.Type {
+		case internal.OperationCreate:
+			lastId++
+			if _, exists := get(lastId); exists {
+				return internal.BulkResult{}, &internal.OperationError{Index: i, Err: internal.Conflict("vehicle_id_conflict")}
+			}
+			staged[lastId] = &internal.Vehicle{Id: lastId, Version: 1, VehicleAttributes: op.Attributes}
+			rs.Created = append(rs.Created, lastId)
+		case internal.OperationUpdate:
+			for _, id := range ids {
+				old, _ := get(id)
+				vh, err := op.Update(old)
+				if err != nil {
+					return internal.BulkResult{}, &internal.OperationError{Index: i, Err: err}
+				}
+				vh.Id = id
+				vh.Version = old.Version + 1
+				staged[id] = &vh
+				if !slices.Contains(rs.Updated, id) {
+					rs.Updated = append(rs.Updated, id)
+				}
+			}
+		case internal.OperationDelete:
+			for _, id := range ids {
+				staged[id] = nil
+				rs.Deleted = append(rs.Deleted, id)
 			}
+		default:
+			return internal.BulkResult{}, &internal.OperationError{Index: i, Err: internal.Invalid("unknown_operation", op.Type)}
 		}
 	}
 
-	if len(v) == 0 {
-		return v, fmt.Errorf("404 Not Found: Não foram encontrados veículos com essas dimensões.")
+	// commit
+	for id, vh := range staged {
+		if old, ok := r.db[id]; ok {
+			rs.Previous = append(rs.Previous, old)
+		}
+		if vh == nil {
+			delete(r.db, id)
+			continue
+		}
+		r.db[id] = *vh
+		rs.Vehicles = append(rs.Vehicles, *vh)
+	}
+	byId := func(a, b internal.Vehicle) int {
+		return a.Id - b.Id
 	}
+	slices.SortFunc(rs.Vehicles, byId)
+	slices.SortFunc(rs.Previous, byId)
+	r.lastId = lastId
 
-	return v, nil
+	return rs, nil
 }
 
-func (r *VehicleMap) GetByWeight(minW, maxW float64) (v map[int]internal.Vehicle, err error) {
-	v = make(map[int]internal.Vehicle)
-	for key, i := range r.db {
-		if i.Weight >= minW && i.Weight <= maxW {
-			v[key] = i
-		}
+// Reset is a method that replaces every vehicle of the repository at once
+// readers see either the old or the new vehicles, never a mix of both;
+// ids keep growing from the last id assigned so that ids of removed vehicles are not reused
+func (r *VehicleMap) Reset(v map[int]internal.Vehicle) (err error) {
+	db := make(map[int]internal.Vehicle, len(v))
+	for key, value := range v {
+		db[key] = value
 	}
 
-	if len(v) == 0 {
-		return v, fmt.Errorf("404 Not Found: Não foram encontrados veículos nessa faixa de peso.")
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.db = db
+	for key, value := range db {
+		if key > r.lastId {
+			r.lastId = key
+		}
+		if value.Version == 0 {
+			value.Version = 1
+			db[key] = value
+		}
 	}
 
-	return v, nil
+	return nil
+}
+
+// Restore is a method that puts back the vehicles exactly as given, versions included, and deletes the ones of ids
+// ids keep growing from the last id assigned, as in Reset
+func (r *VehicleMap) Restore(vs []internal.Vehicle, ids []int) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, v := range vs {
+		r.db[v.Id] = v
+	}
+	for _, id := range ids {
+		delete(r.db, id)
+	}
+	return nil
 }
@@ -0,0 +1,289 @@
+package repository
+
+import (
+	"app/internal"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// newTestMap is a function that returns a repository with n vehicles of ids 1 to n
+func newTestMap(n int) *VehicleMap {
+	db := make(map[int]internal.Vehicle)
+	for id := 1; id <= n; id++ {
+		db[id] = internal.Vehicle{Id: id, VehicleAttributes: internal.VehicleAttributes{
+			Brand:        []string{"Ford", "Fiat"}[id%2],
+			Registration: fmt.Sprintf("REG%d", id),
+			MaxSpeed:     float64(100 + id),
+			FuelType:     "gasoline",
+		}}
+	}
+	return NewVehicleMap(db)
+}
+
+func TestVehicleMap_Stream(t *testing.T) {
+	rp := newTestMap(6)
+	ford, err := internal.NewCondition("brand", internal.OperatorEq, "Ford")
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := internal.VehicleQuery{
+		Filter: internal.And{ford},
+		Sort:   []internal.SortKey{{Field: "max_speed", Descending: true}},
+		Limit:  1,
+	}
+
+	// fn runs without the lock held, so it can write: the vehicle it deletes is skipped
+	var ids []int
+	err = rp.Stream(q, func(v internal.Vehicle) error {
+		ids = append(ids, v.Id)
+		if v.Id == 6 {
+			return rp.DeleteById(4, 0)
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(ids) != "[6 2]" {
+		t.Errorf("ids %v, want [6 2]", ids)
+	}
+}
+
+// TestVehicleMap_Concurrent runs every method of the repository from many goroutines at once; run it with -race
+func TestVehicleMap_Concurrent(t *testing.T) {
+	rp := newTestMap(100)
+	gasoline, err := internal.NewCondition("fuel_type", internal.OperatorEq, "gasoline")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const workers, rounds = 8, 50
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var createdIds []int
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				id := 1 + (w*rounds+i)%100
+
+				vh, err := rp.Create(internal.VehicleAttributes{Brand: "Honda", FuelType: "diesel"})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				vhs, err := rp.CreateSome([]internal.VehicleAttributes{{Brand: "Honda"}, {Brand: "Honda"}})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				mu.Lock()
+				createdIds = append(createdIds, vh.Id, vhs[0].Id, vhs[1].Id)
+				mu.Unlock()
+
+				// the shared vehicles may be deleted by the other goroutines, so the errors are not checked
+				rp.UpdateSpeed(internal.UpdateSpeed{Id: id, Speed: 150})
+				rp.UpdateFuelType(internal.UpdateFuel{Id: id, FuelType: "diesel"})
+				rp.Update(id, 0, func(v internal.Vehicle) (internal.Vehicle, error) {
+					v.Color = "Red"
+					return v, nil
+				})
+				rp.Replace(internal.Vehicle{Id: id, VehicleAttributes: internal.VehicleAttributes{Brand: "Ford"}})
+				rp.FindById(id)
+				rp.Bulk([]internal.BulkOperation{
+					{Type: internal.OperationCreate, Attributes: internal.VehicleAttributes{Brand: "Honda"}},
+					{Type: internal.OperationUpdate, Filter: gasoline, Update: func(v internal.Vehicle) (internal.Vehicle, error) {
+						v.MaxSpeed++
+						return v, nil
+					}},
+				})
+				if i%10 == 0 {
+					rp.DeleteById(vh.Id, 0)
+				}
+
+				if _, err := rp.FindAll(); err != nil {
+					t.Error(err)
+				}
+				if _, err := rp.Find(internal.VehicleQuery{Filter: gasoline, Sort: []internal.SortKey{{Field: "max_speed"}}, Limit: 10}); err != nil {
+					t.Error(err)
+				}
+				rp.GetAverageSpeedByBrand("Ford")
+				rp.GetAverageCapacityByBrand("Fiat")
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	// every id created is unique
+	slices.Sort(createdIds)
+	if len(slices.Compact(createdIds)) != workers*rounds*3 {
+		t.Errorf("ids assigned more than once")
+	}
+	v, err := rp.FindAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, vh := range v {
+		if vh.Id != id {
+			t.Errorf("vehicle %d stored under %d", vh.Id, id)
+		}
+	}
+}
+
+// TestVehicleMap_ConcurrentVersions checks that conditional writes at the same version never both succeed
+func TestVehicleMap_ConcurrentVersions(t *testing.T) {
+	rp := newTestMap(1)
+
+	const writers = 50
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			_, err := rp.UpdateSpeed(internal.UpdateSpeed{Id: 1, Speed: float64(w), Version: 1})
+			errs <- err
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, internal.ErrPreconditionFailed):
+			t.Error(err)
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("%d writes succeeded at version 1, want 1", succeeded)
+	}
+}
+
+func TestVehicleMap_Bulk(t *testing.T) {
+	speed := func(s float64) func(v internal.Vehicle) (internal.Vehicle, error) {
+		return func(v internal.Vehicle) (internal.Vehicle, error) {
+			v.MaxSpeed = s
+			return v, nil
+		}
+	}
+
+	t.Run("operations see the vehicles staged by the previous ones", func(t *testing.T) {
+		rp := newTestMap(3)
+		before, _ := rp.FindAll()
+		fast, err := internal.NewCondition("max_speed", internal.OperatorGte, "200")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rs, err := rp.Bulk([]internal.BulkOperation{
+			{Type: internal.OperationCreate, Attributes: internal.VehicleAttributes{Brand: "Honda", MaxSpeed: 250}},
+			{Type: internal.OperationUpdate, Id: 1, Version: 1, Update: speed(220)},
+			{Type: internal.OperationUpdate, Id: 1, Version: 2, Update: speed(230)},
+			{Type: internal.OperationDelete, Id: 2},
+			// matches the created vehicle and vehicle 1, both only staged
+			{Type: internal.OperationUpdate, Filter: fast, Update: speed(300)},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !slices.Equal(rs.Created, []int{4}) || !slices.Equal(rs.Updated, []int{1, 4}) || !slices.Equal(rs.Deleted, []int{2}) {
+			t.Errorf("result %+v", rs)
+		}
+		v, _ := rp.FindAll()
+		if _, ok := v[2]; ok {
+			t.Errorf("vehicle 2 not deleted")
+		}
+		if v[1].MaxSpeed != 300 || v[1].Version != 4 {
+			t.Errorf("vehicle 1: speed %g version %d, want 300 and 4", v[1].MaxSpeed, v[1].Version)
+		}
+		if v[4].MaxSpeed != 300 || v[4].Version != 2 {
+			t.Errorf("vehicle 4: speed %g version %d, want 300 and 2", v[4].MaxSpeed, v[4].Version)
+		}
+		if len(rs.Vehicles) != 2 || rs.Vehicles[0].Id != 1 || rs.Vehicles[1].Id != 4 {
+			t.Errorf("vehicles %+v", rs.Vehicles)
+		}
+		// the created vehicle had no previous one
+		if !slices.Equal(rs.Previous, []internal.Vehicle{before[1], before[2]}) {
+			t.Errorf("previous %+v, want vehicles 1 and 2 as they were", rs.Previous)
+		}
+	})
+
+	t.Run("a failed operation changes nothing", func(t *testing.T) {
+		tests := []struct {
+			name string
+			op   internal.BulkOperation
+			kind error
+		}{
+			{"not found", internal.BulkOperation{Type: internal.OperationDelete, Id: 99}, internal.ErrVehicleNotFound},
+			{"version mismatch", internal.BulkOperation{Type: internal.OperationUpdate, Id: 3, Version: 7, Update: speed(1)}, internal.ErrPreconditionFailed},
+			{"deleted by a previous operation", internal.BulkOperation{Type: internal.OperationUpdate, Id: 2, Update: speed(1)}, internal.ErrVehicleNotFound},
+			{"update error", internal.BulkOperation{Type: internal.OperationUpdate, Id: 3, Update: func(v internal.Vehicle) (internal.Vehicle, error) {
+				return v, internal.Invalid("invalid")
+			}}, internal.ErrVehicleInvalid},
+			{"unknown operation", internal.BulkOperation{Type: "upsert", Id: 3}, internal.ErrVehicleInvalid},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				rp := newTestMap(3)
+				before, _ := rp.FindAll()
+
+				_, err := rp.Bulk([]internal.BulkOperation{
+					{Type: internal.OperationCreate, Attributes: internal.VehicleAttributes{Brand: "Honda"}},
+					{Type: internal.OperationUpdate, Id: 1, Update: speed(220)},
+					{Type: internal.OperationDelete, Id: 2},
+					tt.op,
+				})
+
+				var opErr *internal.OperationError
+				if !errors.As(err, &opErr) || opErr.Index != 3 || !errors.Is(err, tt.kind) {
+					t.Fatalf("error %v, want the one of operation 3 of kind %v", err, tt.kind)
+				}
+				after, _ := rp.FindAll()
+				if len(after) != len(before) {
+					t.Fatalf("%d vehicles, want %d", len(after), len(before))
+				}
+				for id, vh := range before {
+					if after[id] != vh {
+						t.Errorf("vehicle %d changed: %+v", id, after[id])
+					}
+				}
+
+				// the ids staged by the failed bulk are not consumed
+				vh, err := rp.Create(internal.VehicleAttributes{})
+				if err != nil || vh.Id != 4 {
+					t.Errorf("created %d, %v; want 4", vh.Id, err)
+				}
+			})
+		}
+	})
+}
+
+func TestVehicleMap_Restore(t *testing.T) {
+	rp := newTestMap(2)
+	old, _ := rp.FindById(1)
+	if _, err := rp.UpdateSpeed(internal.UpdateSpeed{Id: 1, Speed: 1}); err != nil {
+		t.Fatal(err)
+	}
+	vh, _ := rp.Create(internal.VehicleAttributes{})
+
+	if err := rp.Restore([]internal.Vehicle{old}, []int{vh.Id}); err != nil {
+		t.Fatal(err)
+	}
+
+	v, _ := rp.FindAll()
+	if v[1] != old {
+		t.Errorf("vehicle 1 %+v, want %+v", v[1], old)
+	}
+	if _, ok := v[vh.Id]; ok {
+		t.Errorf("vehicle %d not deleted", vh.Id)
+	}
+}
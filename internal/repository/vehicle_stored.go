@@ -0,0 +1,205 @@
+package repository
+
+import (
+	"app/internal"
+	"errors"
+	"sync"
+)
+
+// NewVehicleStored is a function that returns a new instance of VehicleStored
+func NewVehicleStored(rp internal.VehicleRepository, st internal.VehicleStorer) *VehicleStored {
+	return &VehicleStored{VehicleRepository: rp, st: st}
+}
+
+// VehicleStored is a struct that represents a vehicle repository that flushes its state on every write
+// reads are served by the underlying repository; a write is only kept once it is flushed,
+// when the flush fails the write is undone
+type VehicleStored struct {
+	// VehicleRepository is the underlying repository
+	internal.VehicleRepository
+	// mu serializes writes so the stored snapshots follow the order of the writes
+	mu sync.Mutex
+	// st is the storer the vehicles are flushed to
+	st internal.VehicleStorer
+}
+
+// flush is a method that stores the current state of the underlying repository, undoing the write with u when it fails
+func (r *VehicleStored) flush(u undo) (err error) {
+	v, err := r.VehicleRepository.FindAll()
+	if err == nil {
+		err = r.st.Store(v)
+	}
+	if err != nil {
+		return errors.Join(err, u.rollback(r.VehicleRepository))
+	}
+	return
+}
+
+// Create is a method that stores a new vehicle and flushes the repository
+func (r *VehicleStored) Create(v internal.VehicleAttributes) (vh internal.Vehicle, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	vh, err = r.VehicleRepository.Create(v)
+	if err != nil {
+		return
+	}
+	err = r.flush(created(vh))
+	if err != nil {
+		return internal.Vehicle{}, err
+	}
+	return
+}
+
+// CreateSome is a method that stores a list of new vehicles and flushes the repository
+func (r *VehicleStored) CreateSome(vs []internal.VehicleAttributes) (vhs []internal.Vehicle, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	vhs, err = r.VehicleRepository.CreateSome(vs)
+	if err != nil {
+		return
+	}
+	err = r.flush(created(vhs...))
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+// UpdateSpeed is a method that updates the max speed of a vehicle and flushes the repository
+func (r *VehicleStored) UpdateSpeed(v internal.UpdateSpeed) (vh internal.Vehicle, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u := before(r.VehicleRepository, v.Id)
+	vh, err = r.VehicleRepository.UpdateSpeed(v)
+	if err != nil {
+		return
+	}
+	err = r.flush(u)
+	if err != nil {
+		return internal.Vehicle{}, err
+	}
+	return
+}
+
+// Replace is a method that replaces every attribute of a vehicle and flushes the repository
+func (r *VehicleStored) Replace(v internal.Vehicle) (vh internal.Vehicle, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u := before(r.VehicleRepository, v.Id)
+	vh, err = r.VehicleRepository.Replace(v)
+	if err != nil {
+		return
+	}
+	err = r.flush(u)
+	if err != nil {
+		return internal.Vehicle{}, err
+	}
+	return
+}
+
+// Update is a method that updates a vehicle with fn and flushes the repository
+func (r *VehicleStored) Update(id, version int, fn func(v internal.Vehicle) (internal.Vehicle, error)) (vh internal.Vehicle, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u := before(r.VehicleRepository, id)
+	vh, err = r.VehicleRepository.Update(id, version, fn)
+	if err != nil {
+		return
+	}
+	err = r.flush(u)
+	if err != nil {
+		return internal.Vehicle{}, err
+	}
+	return
+}
+
+// UpdateFuelType is a method that updates the fuel type of a vehicle and flushes the repository
+func (r *VehicleStored) UpdateFuelType(uf internal.UpdateFuel) (vh internal.Vehicle, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u := before(r.VehicleRepository, uf.Id)
+	vh, err = r.VehicleRepository.UpdateFuelType(uf)
+	if err != nil {
+		return
+	}
+	err = r.flush(u)
+	if err != nil {
+		return internal.Vehicle{}, err
+	}
+	return
+}
+
+// DeleteById is a method that deletes a vehicle and flushes the repository
+func (r *VehicleStored) DeleteById(id, version int) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u := before(r.VehicleRepository, id)
+	err = r.VehicleRepository.DeleteById(id, version)
+	if err != nil {
+		return
+	}
+	err = r.flush(u)
+	return
+}
+
+// Bulk is a method that applies the operations of a bulk and flushes the repository
+func (r *VehicleStored) Bulk(ops []internal.BulkOperation) (rs internal.BulkResult, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rs, err = r.VehicleRepository.Bulk(ops)
+	if err != nil {
+		return
+	}
+	err = r.flush(bulked(rs))
+	if err != nil {
+		return internal.BulkResult{}, err
+	}
+	return
+}
+
+// Reset is a method that replaces every vehicle of the repository
+// the repository is not flushed, the new vehicles already come from a source
+func (r *VehicleStored) Reset(v map[int]internal.Vehicle) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err = r.VehicleRepository.Reset(v)
+	return
+}
+
+// Restore is a method that puts back some vehicles and deletes others, and flushes the repository
+func (r *VehicleStored) Restore(vs []internal.Vehicle, ids []int) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	touched := append([]int{}, ids...)
+	for _, vh := range vs {
+		touched = append(touched, vh.Id)
+	}
+	u := before(r.VehicleRepository, touched...)
+	err = r.VehicleRepository.Restore(vs, ids)
+	if err != nil {
+		return
+	}
+	err = r.flush(u)
+	return
+}
+
+// Snapshot is a method that stores the current vehicles, through the underlying repository when it stores its own
+func (r *VehicleStored) Snapshot() (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if sn, ok := r.VehicleRepository.(internal.VehicleSnapshotter); ok {
+		return sn.Snapshot()
+	}
+	return r.flush(undo{})
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"app/internal"
+	"errors"
+	"testing"
+)
+
+// storerStub is a storer that keeps the vehicles stored, or fails with err
+type storerStub struct {
+	v   map[int]internal.Vehicle
+	err error
+}
+
+func (s *storerStub) Store(v map[int]internal.Vehicle) (err error) {
+	if s.err != nil {
+		return s.err
+	}
+	s.v = v
+	return nil
+}
+
+func TestVehicleStored_Flush(t *testing.T) {
+	st := &storerStub{}
+	rp := NewVehicleStored(newTestMap(2), st)
+
+	vh, err := rp.Create(internal.VehicleAttributes{Brand: "Honda"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(st.v) != 3 || st.v[vh.Id].Brand != "Honda" {
+		t.Fatalf("stored %v, want the created vehicle", st.v)
+	}
+	if err = rp.DeleteById(1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := st.v[1]; len(st.v) != 2 || ok {
+		t.Errorf("stored %v, want vehicle 1 deleted", st.v)
+	}
+}
+
+func TestVehicleStored_StoreFailure(t *testing.T) {
+	errStore := errors.New("read-only")
+	mp := newTestMap(2)
+	before, _ := mp.FindAll()
+	rp := NewVehicleStored(mp, &storerStub{err: errStore})
+
+	_, err := rp.Create(internal.VehicleAttributes{Brand: "Honda"})
+	if !errors.Is(err, errStore) {
+		t.Fatalf("error %v, want %v", err, errStore)
+	}
+	_, err = rp.UpdateSpeed(internal.UpdateSpeed{Id: 1, Speed: 1})
+	if !errors.Is(err, errStore) {
+		t.Fatalf("error %v, want %v", err, errStore)
+	}
+	if err = rp.DeleteById(2, 0); !errors.Is(err, errStore) {
+		t.Fatalf("error %v, want %v", err, errStore)
+	}
+
+	v, _ := mp.FindAll()
+	if len(v) != 2 || v[1] != before[1] || v[2] != before[2] {
+		t.Errorf("vehicles changed: %+v", v)
+	}
+}
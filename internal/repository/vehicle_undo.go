@@ -0,0 +1,47 @@
+package repository
+
+import "app/internal"
+
+// undo is a struct that represents how a write is undone when its outcome cannot be persisted
+type undo struct {
+	// vehicles are the vehicles the write changed or deleted, as they were before it
+	vehicles []internal.Vehicle
+	// ids are the ids of the vehicles the write created
+	ids []int
+}
+
+// before is a function that returns the undo of a write on the vehicles of ids, taken before the write:
+// the vehicles that exist are put back as they are and the others are deleted
+func before(rp internal.VehicleRepository, ids ...int) (u undo) {
+	for _, id := range ids {
+		vh, err := rp.FindById(id)
+		if err != nil {
+			u.ids = append(u.ids, id)
+			continue
+		}
+		u.vehicles = append(u.vehicles, vh)
+	}
+	return
+}
+
+// bulked is a function that returns the undo of a bulk from its result:
+// the vehicles it changed or deleted are put back as they were and the ones it created are deleted
+func bulked(rs internal.BulkResult) (u undo) {
+	return undo{vehicles: rs.Previous, ids: rs.Created}
+}
+
+// created is a function that returns the undo of a write that created vhs
+func created(vhs ...internal.Vehicle) (u undo) {
+	for _, vh := range vhs {
+		u.ids = append(u.ids, vh.Id)
+	}
+	return
+}
+
+// rollback is a method that undoes the write on rp
+func (u undo) rollback(rp internal.VehicleRepository) (err error) {
+	if len(u.vehicles) == 0 && len(u.ids) == 0 {
+		return nil
+	}
+	return rp.Restore(u.vehicles, u.ids)
+}
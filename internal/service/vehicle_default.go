@@ -2,17 +2,20 @@ package service
 
 import (
 	"app/internal"
+	"slices"
 )
 
 // NewVehicleDefault is a function that returns a new instance of VehicleDefault
-func NewVehicleDefault(rp internal.VehicleRepository) *VehicleDefault {
-	return &VehicleDefault{rp: rp}
+func NewVehicleDefault(rp internal.VehicleRepository, vl internal.VehicleValidator) *VehicleDefault {
+	return &VehicleDefault{rp: rp, vl: vl}
 }
 
 // VehicleDefault is a struct that represents the default service for vehicles
 type VehicleDefault struct {
 	// rp is the repository that will be used by the service
 	rp internal.VehicleRepository
+	// vl is the validator the new and updated vehicles are checked with
+	vl internal.VehicleValidator
 }
 
 // FindAll is a method that returns a map of all vehicles
@@ -21,33 +24,71 @@ func (s *VehicleDefault) FindAll() (v map[int]internal.Vehicle, err error) {
 	return
 }
 
-func (s *VehicleDefault) Create(new internal.VehicleAttributes) (err error) {
-	err = s.rp.Create(new)
+// FindById is a method that returns the vehicle with the given id
+func (s *VehicleDefault) FindById(id int) (vh internal.Vehicle, err error) {
+	vh, err = s.rp.FindById(id)
+	return
+}
 
-	if err != nil {
-		return err
-	}
-	return nil
+// Find is a method that returns the page of the vehicles that match the query, sorted as it asks
+func (s *VehicleDefault) Find(q internal.VehicleQuery) (p internal.VehiclePage, err error) {
+	p, err = s.rp.Find(q)
+	return
 }
 
-func (s *VehicleDefault) FindByColorAndYear(vehicle internal.VehicleAttributes) (v map[int]internal.Vehicle, err error) {
-	v, err = s.rp.FindByColorAndYear(vehicle)
+// Stream is a method that calls fn for each vehicle that matches q.Filter, sorted as q asks, the page aside
+func (s *VehicleDefault) Stream(q internal.VehicleQuery, fn func(v internal.Vehicle) (err error)) (err error) {
+	err = s.rp.Stream(q, fn)
+	return
+}
 
+// findSome is a method that returns the page q asks for of the vehicles that match the filter,
+// or an error of kind ErrVehicleNotFound with the given code when none does
+func (s *VehicleDefault) findSome(f internal.Filter, q internal.VehicleQuery, code string) (p internal.VehiclePage, err error) {
+	q.Filter = f
+	p, err = s.rp.Find(q)
 	if err != nil {
-		return v, err
+		return internal.VehiclePage{}, err
 	}
 
-	return v, nil
+	if p.Total == 0 {
+		return p, internal.NotFound(code)
+	}
+
+	return p, nil
 }
 
-func (s *VehicleDefault) FindByBrandAndYearInterval(r internal.BrandYearRangeSearchType) (v map[int]internal.Vehicle, err error) {
-	v, err = s.rp.FindByBrandAndYearInterval(r)
+func (s *VehicleDefault) Create(new internal.VehicleAttributes) (vh internal.Vehicle, err error) {
+	err = internal.Validate(s.vl, internal.Vehicle{VehicleAttributes: new})
+	if err != nil {
+		return internal.Vehicle{}, err
+	}
+
+	vh, err = s.rp.Create(new)
 
 	if err != nil {
-		return nil, err
+		return internal.Vehicle{}, err
 	}
+	return vh, nil
+}
 
-	return v, nil
+func (s *VehicleDefault) FindByColorAndYear(vehicle internal.VehicleAttributes, q internal.VehicleQuery) (p internal.VehiclePage, err error) {
+	return s.findSome(internal.And{
+		internal.Condition{Field: "color", Operator: internal.OperatorEq, Values: []any{vehicle.Color}},
+		internal.Condition{Field: "year", Operator: internal.OperatorEq, Values: []any{vehicle.FabricationYear}},
+	}, q, "vehicles_not_found")
+}
+
+func (s *VehicleDefault) FindByBrandAndYearInterval(r internal.BrandYearRangeSearchType, q internal.VehicleQuery) (p internal.VehiclePage, err error) {
+	if r.StartYear > r.EndYear {
+		return internal.VehiclePage{}, internal.InvalidRange("invalid_year_range")
+	}
+
+	return s.findSome(internal.And{
+		internal.Condition{Field: "brand", Operator: internal.OperatorEq, Values: []any{r.Brand}},
+		internal.Condition{Field: "year", Operator: internal.OperatorGte, Values: []any{r.StartYear}},
+		internal.Condition{Field: "year", Operator: internal.OperatorLte, Values: []any{r.EndYear}},
+	}, q, "vehicles_not_found")
 }
 
 func (s *VehicleDefault) GetAverageSpeedByBrand(b string) (v float64, err error) {
@@ -60,38 +101,94 @@ func (s *VehicleDefault) GetAverageSpeedByBrand(b string) (v float64, err error)
 	return v, nil
 }
 
-func (s *VehicleDefault) CreateSome(vs []internal.VehicleAttributes) (err error) {
-	err = s.rp.CreateSome(vs)
+// CreateSome is a method that validates every vehicle of a batch and stores the valid ones in a single write
+// with partial false nothing is stored unless every vehicle is valid
+func (s *VehicleDefault) CreateSome(vs []internal.VehicleAttributes, partial bool) (results []internal.BatchResult, err error) {
+	results = make([]internal.BatchResult, len(vs))
+	valid := make([]internal.VehicleAttributes, 0, len(vs))
+	indexes := make([]int, 0, len(vs))
+	for i, v := range vs {
+		results[i].Index = i
+		results[i].Err = internal.Validate(s.vl, internal.Vehicle{VehicleAttributes: v})
+		if results[i].Err != nil {
+			continue
+		}
+		valid = append(valid, v)
+		indexes = append(indexes, i)
+	}
+
+	if len(valid) < len(vs) && !partial {
+		return results, internal.Invalid("batch_invalid", len(vs)-len(valid))
+	}
+	if len(valid) == 0 {
+		return results, nil
+	}
 
+	vhs, err := s.rp.CreateSome(valid)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	for j, vh := range vhs {
+		results[indexes[j]].Created = true
+		results[indexes[j]].Vehicle = vh
+	}
+
+	return results, nil
 }
 
-func (s *VehicleDefault) UpdateSpeed(v internal.UpdateSpeed) (err error) {
-	err = s.rp.UpdateSpeed(v)
+func (s *VehicleDefault) UpdateSpeed(v internal.UpdateSpeed) (vh internal.Vehicle, err error) {
+	err = internal.Validate(s.vl, internal.Vehicle{VehicleAttributes: internal.VehicleAttributes{MaxSpeed: v.Speed}}, "max_speed")
+	if err != nil {
+		return internal.Vehicle{}, err
+	}
+
+	vh, err = s.rp.UpdateSpeed(v)
 
 	if err != nil {
-		return err
+		return internal.Vehicle{}, err
 	}
 
-	return nil
+	return vh, nil
 }
 
-func (s *VehicleDefault) GetByFuelType(t string) (v map[int]internal.Vehicle, err error) {
-	v, err = s.rp.GetByFuelType(t)
-
+// Replace is a method that replaces every attribute of an existing vehicle, once validated
+func (s *VehicleDefault) Replace(v internal.Vehicle) (vh internal.Vehicle, err error) {
+	err = internal.Validate(s.vl, v)
 	if err != nil {
-		return v, err
+		return internal.Vehicle{}, err
 	}
 
-	return v, nil
+	vh, err = s.rp.Replace(v)
+	return vh, err
 }
 
-func (s *VehicleDefault) DeleteById(id int) (err error) {
-	err = s.rp.DeleteById(id)
+// Update is a method that replaces a vehicle by the result of fn on it, atomically and once validated
+func (s *VehicleDefault) Update(id, version int, fn func(v internal.Vehicle) (internal.Vehicle, error)) (vh internal.Vehicle, err error) {
+	vh, err = s.rp.Update(id, version, func(v internal.Vehicle) (internal.Vehicle, error) {
+		v, err := fn(v)
+		if err != nil {
+			return internal.Vehicle{}, err
+		}
+
+		err = internal.Validate(s.vl, v)
+		if err != nil {
+			return internal.Vehicle{}, err
+		}
+
+		return v, nil
+	})
+	return vh, err
+}
+
+func (s *VehicleDefault) GetByFuelType(t string, q internal.VehicleQuery) (p internal.VehiclePage, err error) {
+	return s.findSome(internal.And{
+		internal.Condition{Field: "fuel_type", Operator: internal.OperatorEq, Values: []any{t}},
+	}, q, "fuel_type_not_found")
+}
+
+func (s *VehicleDefault) DeleteById(id, version int) (err error) {
+	err = s.rp.DeleteById(id, version)
 
 	if err != nil {
 		return err
@@ -100,15 +197,53 @@ func (s *VehicleDefault) DeleteById(id int) (err error) {
 	return nil
 }
 
-func (s *VehicleDefault) GetByTransmissionType(t string) (v map[int]internal.Vehicle, err error) {
-	v, err = s.rp.GetByTransmissionType(t)
+func (s *VehicleDefault) GetByTransmissionType(t string, q internal.VehicleQuery) (p internal.VehiclePage, err error) {
+	return s.findSome(internal.And{
+		internal.Condition{Field: "transmission", Operator: internal.OperatorEq, Values: []any{t}},
+	}, q, "transmission_not_found")
+}
 
-	return v, err
+func (s *VehicleDefault) UpdateFuelType(u internal.UpdateFuel) (vh internal.Vehicle, err error) {
+	err = internal.Validate(s.vl, internal.Vehicle{VehicleAttributes: internal.VehicleAttributes{FuelType: u.FuelType}}, "fuel_type")
+	if err != nil {
+		return internal.Vehicle{}, err
+	}
+
+	vh, err = s.rp.UpdateFuelType(u)
+	return vh, err
 }
 
-func (s *VehicleDefault) UpdateFuelType(u internal.UpdateFuel) (err error) {
-	err = s.rp.UpdateFuelType(u)
-	return err
+// Bulk is a method that applies the operations in order and atomically
+// the vehicles created and the ones left by every update are validated
+func (s *VehicleDefault) Bulk(ops []internal.BulkOperation) (rs internal.BulkResult, err error) {
+	ops = slices.Clone(ops)
+	for i, op := range ops {
+		switch op.Type {
+		case internal.OperationCreate:
+			err = internal.Validate(s.vl, internal.Vehicle{VehicleAttributes: op.Attributes})
+			if err != nil {
+				return internal.BulkResult{}, &internal.OperationError{Index: i, Err: err}
+			}
+		case internal.OperationUpdate:
+			update := op.Update
+			ops[i].Update = func(v internal.Vehicle) (internal.Vehicle, error) {
+				v, err := update(v)
+				if err != nil {
+					return internal.Vehicle{}, err
+				}
+
+				err = internal.Validate(s.vl, v)
+				if err != nil {
+					return internal.Vehicle{}, err
+				}
+
+				return v, nil
+			}
+		}
+	}
+
+	rs, err = s.rp.Bulk(ops)
+	return
 }
 
 func (s *VehicleDefault) GetAverageCapacityByBrand(b string) (v float64, err error) {
@@ -117,14 +252,26 @@ func (s *VehicleDefault) GetAverageCapacityByBrand(b string) (v float64, err err
 	return v, err
 }
 
-func (s *VehicleDefault) GetByDimensions(minLength, maxLength, minWidth, maxWidth float64) (v map[int]internal.Vehicle, err error) {
-	v, err = s.rp.GetByDimensions(minLength, maxLength, minWidth, maxWidth)
+func (s *VehicleDefault) GetByDimensions(minLength, maxLength, minWidth, maxWidth float64, q internal.VehicleQuery) (p internal.VehiclePage, err error) {
+	if minLength > maxLength || minWidth > maxWidth {
+		return internal.VehiclePage{}, internal.InvalidRange("invalid_dimensions_range")
+	}
 
-	return v, err
+	return s.findSome(internal.And{
+		internal.Condition{Field: "length", Operator: internal.OperatorGte, Values: []any{minLength}},
+		internal.Condition{Field: "length", Operator: internal.OperatorLte, Values: []any{maxLength}},
+		internal.Condition{Field: "width", Operator: internal.OperatorGte, Values: []any{minWidth}},
+		internal.Condition{Field: "width", Operator: internal.OperatorLte, Values: []any{maxWidth}},
+	}, q, "dimensions_not_found")
 }
 
-func (s *VehicleDefault) GetByWeight(minW, maxW float64) (v map[int]internal.Vehicle, err error) {
-	v, err = s.rp.GetByWeight(minW, maxW)
+func (s *VehicleDefault) GetByWeight(minW, maxW float64, q internal.VehicleQuery) (p internal.VehiclePage, err error) {
+	if minW > maxW {
+		return internal.VehiclePage{}, internal.InvalidRange("invalid_weight_range")
+	}
 
-	return v, err
+	return s.findSome(internal.And{
+		internal.Condition{Field: "weight", Operator: internal.OperatorGte, Values: []any{minW}},
+		internal.Condition{Field: "weight", Operator: internal.OperatorLte, Values: []any{maxW}},
+	}, q, "weight_not_found")
 }
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"app/internal"
+	"app/internal/repository"
+	"app/internal/validator"
+	"errors"
+	"testing"
+)
+
+// newTestService is a function that returns a service over a repository with a valid Ford of id 1
+func newTestService() *VehicleDefault {
+	rp := repository.NewVehicleMap(map[int]internal.Vehicle{
+		1: {Id: 1, VehicleAttributes: validAttributes()},
+	})
+	return NewVehicleDefault(rp, validator.NewVehicleRules(nil))
+}
+
+// validAttributes is a function that returns the attributes of a valid vehicle
+func validAttributes() internal.VehicleAttributes {
+	return internal.VehicleAttributes{
+		Brand: "Ford", Model: "Ka", Registration: "ABC123", Color: "Red", FabricationYear: 2010, Capacity: 5,
+		MaxSpeed: 160, FuelType: "gasoline", Transmission: "manual", Weight: 900,
+		Dimensions: internal.Dimensions{Height: 150, Length: 380, Width: 170},
+	}
+}
+
+func TestVehicleDefault_Errors(t *testing.T) {
+	invalid := validAttributes()
+	invalid.Brand = ""
+	q := internal.VehicleQuery{}
+
+	tests := []struct {
+		name string
+		call func(sv *VehicleDefault) error
+		kind error
+		code string
+	}{
+		{"find a missing vehicle", func(sv *VehicleDefault) error {
+			_, err := sv.FindById(9)
+			return err
+		}, internal.ErrVehicleNotFound, "vehicle_not_found"},
+		{"create an invalid vehicle", func(sv *VehicleDefault) error {
+			_, err := sv.Create(invalid)
+			return err
+		}, internal.ErrVehicleInvalid, ""},
+		{"update to an invalid speed", func(sv *VehicleDefault) error {
+			_, err := sv.UpdateSpeed(internal.UpdateSpeed{Id: 1, Speed: 900})
+			return err
+		}, internal.ErrVehicleInvalid, ""},
+		{"update at another version", func(sv *VehicleDefault) error {
+			_, err := sv.UpdateFuelType(internal.UpdateFuel{Id: 1, FuelType: "diesel", Version: 5})
+			return err
+		}, internal.ErrPreconditionFailed, "version_mismatch"},
+		{"delete a missing vehicle", func(sv *VehicleDefault) error {
+			return sv.DeleteById(9, 0)
+		}, internal.ErrVehicleNotFound, "vehicle_not_found"},
+		{"search an inverted year range", func(sv *VehicleDefault) error {
+			_, err := sv.FindByBrandAndYearInterval(internal.BrandYearRangeSearchType{Brand: "Ford", StartYear: 2020, EndYear: 2000}, q)
+			return err
+		}, internal.ErrInvalidRange, "invalid_year_range"},
+		{"search an inverted weight range", func(sv *VehicleDefault) error {
+			_, err := sv.GetByWeight(2000, 1000, q)
+			return err
+		}, internal.ErrInvalidRange, "invalid_weight_range"},
+		{"search without results", func(sv *VehicleDefault) error {
+			_, err := sv.GetByFuelType("electric", q)
+			return err
+		}, internal.ErrVehicleNotFound, "fuel_type_not_found"},
+		{"average of a missing brand", func(sv *VehicleDefault) error {
+			_, err := sv.GetAverageSpeedByBrand("Kia")
+			return err
+		}, internal.ErrVehicleNotFound, "brand_not_found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call(newTestService())
+
+			if !errors.Is(err, tt.kind) {
+				t.Fatalf("error %v, want one of kind %v", err, tt.kind)
+			}
+			var e *internal.Error
+			if tt.code != "" && (!errors.As(err, &e) || e.Code != tt.code) {
+				t.Errorf("error %v, want code %s", err, tt.code)
+			}
+		})
+	}
+}
+
+func TestVehicleDefault_Validation(t *testing.T) {
+	sv := newTestService()
+	invalid := validAttributes()
+	invalid.Brand, invalid.MaxSpeed = "", 900
+
+	_, err := sv.Create(invalid)
+
+	// every field in error is reported, and the vehicle is not stored
+	var ve *internal.ValidationError
+	if !errors.As(err, &ve) || len(ve.Issues) != 2 {
+		t.Fatalf("error %v, want the issues of brand and max_speed", err)
+	}
+	if v, _ := sv.FindAll(); len(v) != 1 {
+		t.Errorf("%d vehicles, want 1", len(v))
+	}
+
+	// a partial update only validates the fields it changes
+	if _, err = sv.UpdateSpeed(internal.UpdateSpeed{Id: 1, Speed: 200}); err != nil {
+		t.Errorf("valid speed: %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package validator
+
+import (
+	"app/internal"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+const (
+	// ModeAccept keeps every vehicle and reports the issues found
+	ModeAccept = "accept"
+	// ModeQuarantine sets the vehicles with errors apart from the others
+	ModeQuarantine = "quarantine"
+	// ModeReject fails the whole load when any vehicle has errors
+	ModeReject = "reject"
+)
+
+var (
+	// ErrInvalidDataset is returned by Check in ModeReject when any vehicle has errors
+	ErrInvalidDataset = errors.New("validator: invalid vehicle dataset")
+	// ErrUnknownMode is returned when a validation mode does not exist
+	ErrUnknownMode = errors.New("validator: unknown validation mode")
+)
+
+// ValidateMode is a function that returns ErrUnknownMode when mode is not one of the validation modes
+func ValidateMode(mode string) (err error) {
+	switch mode {
+	case ModeAccept, ModeQuarantine, ModeReject:
+		return nil
+	}
+	return fmt.Errorf("%w: %q", ErrUnknownMode, mode)
+}
+
+// VehicleIssues is a struct that represents the issues found in a vehicle
+type VehicleIssues struct {
+	// Id is the id of the vehicle
+	Id int `json:"id"`
+	// Issues are the issues found in the vehicle
+	Issues []IssueJSON `json:"issues"`
+}
+
+// IssueJSON is a struct that represents a validation issue in JSON format
+type IssueJSON struct {
+	Field    string `json:"field"`
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+}
+
+// Report is a struct that represents the result of the validation of a dataset
+type Report struct {
+	// Mode is the mode the dataset was validated with
+	Mode string `json:"mode"`
+	// Vehicles is the number of vehicles validated
+	Vehicles int `json:"vehicles"`
+	// Errors is the number of issues with error severity
+	Errors int `json:"errors"`
+	// Warnings is the number of issues with warning severity
+	Warnings int `json:"warnings"`
+	// Quarantined are the ids of the vehicles set apart, in ModeQuarantine
+	Quarantined []int `json:"quarantined"`
+	// Issues are the issues found, by vehicle, ordered by id
+	Issues []VehicleIssues `json:"issues"`
+}
+
+// String is a method that returns a one line summary of the report
+func (r Report) String() string {
+	return fmt.Sprintf("%d vehicles validated: %d errors, %d warnings, %d quarantined",
+		r.Vehicles, r.Errors, r.Warnings, len(r.Quarantined))
+}
+
+// Check is a function that validates every vehicle of v according to mode
+// it returns the vehicles to keep, the quarantined ones and a report of the issues found
+func Check(vl internal.VehicleValidator, v map[int]internal.Vehicle, mode string) (valid, quarantined map[int]internal.Vehicle, rp Report, err error) {
+	rp = Report{Mode: mode, Vehicles: len(v), Quarantined: []int{}, Issues: []VehicleIssues{}}
+	valid = make(map[int]internal.Vehicle, len(v))
+	quarantined = make(map[int]internal.Vehicle)
+
+	for id, vh := range v {
+		issues := vl.Validate(vh)
+
+		invalid := false
+		vi := VehicleIssues{Id: id}
+		for _, is := range issues {
+			switch is.Severity {
+			case internal.SeverityError:
+				rp.Errors++
+				invalid = true
+			default:
+				rp.Warnings++
+			}
+			vi.Issues = append(vi.Issues, IssueJSON{
+				Field:    is.Field,
+				Severity: is.Severity,
+				Code:     is.Code,
+				Message:  is.Message,
+			})
+		}
+		if len(vi.Issues) > 0 {
+			rp.Issues = append(rp.Issues, vi)
+		}
+
+		if invalid && mode == ModeQuarantine {
+			quarantined[id] = vh
+			rp.Quarantined = append(rp.Quarantined, id)
+			continue
+		}
+		valid[id] = vh
+	}
+
+	sort.Slice(rp.Issues, func(i, j int) bool {
+		return rp.Issues[i].Id < rp.Issues[j].Id
+	})
+	sort.Ints(rp.Quarantined)
+
+	if mode == ModeReject && rp.Errors > 0 {
+		return nil, nil, rp, fmt.Errorf("%w: %s", ErrInvalidDataset, rp)
+	}
+	return
+}
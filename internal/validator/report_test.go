@@ -0,0 +1,104 @@
+package validator
+
+import (
+	"app/internal"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// testFleet is a function that returns a valid vehicle, one with an error and one with a warning only
+func testFleet() map[int]internal.Vehicle {
+	valid := internal.VehicleAttributes{
+		Brand: "Ford", Model: "Ka", Registration: "ABC123", FabricationYear: 2010, Capacity: 5,
+		MaxSpeed: 160, FuelType: "gasoline", Transmission: "manual", Weight: 900,
+		Dimensions: internal.Dimensions{Height: 150, Length: 380, Width: 170},
+	}
+	noBrand, light := valid, valid
+	noBrand.Brand = ""
+	light.Weight = 10
+	return map[int]internal.Vehicle{
+		1: {Id: 1, VehicleAttributes: valid},
+		2: {Id: 2, VehicleAttributes: noBrand},
+		3: {Id: 3, VehicleAttributes: light},
+	}
+}
+
+// keys is a function that returns the ids of the vehicles, in order
+func keys(v map[int]internal.Vehicle) (ids []int) {
+	for id := range v {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		mode        string
+		valid       []int
+		quarantined []int
+		err         error
+	}{
+		{ModeAccept, []int{1, 2, 3}, nil, nil},
+		{ModeQuarantine, []int{1, 3}, []int{2}, nil},
+		{ModeReject, nil, nil, ErrInvalidDataset},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			valid, quarantined, rp, err := Check(NewVehicleRules(nil), testFleet(), tt.mode)
+
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error %v, want %v", err, tt.err)
+			}
+			if got := keys(valid); !slices.Equal(got, tt.valid) {
+				t.Errorf("valid %v, want %v", got, tt.valid)
+			}
+			if got := keys(quarantined); !slices.Equal(got, tt.quarantined) {
+				t.Errorf("quarantined %v, want %v", got, tt.quarantined)
+			}
+
+			// the report is the same whatever the mode, but for the vehicles set apart
+			if rp.Mode != tt.mode || rp.Vehicles != 3 || rp.Errors != 1 || rp.Warnings != 1 {
+				t.Errorf("report %+v", rp)
+			}
+			if len(rp.Issues) != 2 || rp.Issues[0].Id != 2 || rp.Issues[1].Id != 3 {
+				t.Fatalf("issues %+v, want the ones of 2 and 3", rp.Issues)
+			}
+			if is := rp.Issues[0].Issues; len(is) != 1 || is[0].Field != "brand" || is[0].Code != "required" || is[0].Severity != internal.SeverityError {
+				t.Errorf("issues of 2 %+v", is)
+			}
+			if is := rp.Issues[1].Issues; len(is) != 1 || is[0].Field != "weight" || is[0].Severity != internal.SeverityWarning {
+				t.Errorf("issues of 3 %+v", is)
+			}
+			if !slices.Equal(rp.Quarantined, tt.quarantined) {
+				t.Errorf("report quarantined %v, want %v", rp.Quarantined, tt.quarantined)
+			}
+		})
+	}
+}
+
+func TestCheck_RejectWarnings(t *testing.T) {
+	v := testFleet()
+	delete(v, 2)
+
+	// warnings alone never reject a dataset
+	valid, _, rp, err := Check(NewVehicleRules(nil), v, ModeReject)
+
+	if err != nil || len(valid) != 2 || rp.Warnings != 1 {
+		t.Errorf("%d valid, %d warnings, %v; want 2, 1 and no error", len(valid), rp.Warnings, err)
+	}
+}
+
+func TestValidateMode(t *testing.T) {
+	for _, mode := range []string{ModeAccept, ModeQuarantine, ModeReject} {
+		if err := ValidateMode(mode); err != nil {
+			t.Errorf("mode %s: %v", mode, err)
+		}
+	}
+	for _, mode := range []string{"", "strict", "Accept"} {
+		if err := ValidateMode(mode); !errors.Is(err, ErrUnknownMode) {
+			t.Errorf("mode %q: error %v, want %v", mode, err, ErrUnknownMode)
+		}
+	}
+}
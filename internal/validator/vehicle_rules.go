@@ -0,0 +1,174 @@
+package validator
+
+import (
+	"app/internal"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Rule is a struct that represents a check applied to a field of a vehicle
+type Rule struct {
+	// Field is the name of the field, as in internal.VehicleFields
+	Field string
+	// Severity is the severity of the issue reported when the check fails
+	Severity string
+	// Code identifies the rule
+	Code string
+	// Args are the values the message of the code is formatted with, starting with the field
+	Args []any
+	// Message describes the issue reported when the check fails, in english
+	Message string
+	// Check returns true when the value of the field is acceptable
+	Check func(value any) bool
+}
+
+// Required is a function that returns a rule that fails when the field is empty or zero
+func Required(field, severity string) Rule {
+	return Rule{
+		Field:    field,
+		Severity: severity,
+		Code:     "required",
+		Args:     []any{field},
+		Message:  fmt.Sprintf("%s is required", field),
+		Check: func(value any) bool {
+			switch v := value.(type) {
+			case string:
+				return strings.TrimSpace(v) != ""
+			case int:
+				return v != 0
+			case float64:
+				return v != 0
+			}
+			return true
+		},
+	}
+}
+
+// Range is a function that returns a rule that fails when the numeric field is outside [min, max]
+// empty values are left to Required
+func Range(field string, min, max float64, severity string) Rule {
+	return Rule{
+		Field:    field,
+		Severity: severity,
+		Code:     "range",
+		Args:     []any{field, min, max},
+		Message:  fmt.Sprintf("%s must be between %g and %g", field, min, max),
+		Check: func(value any) bool {
+			var f float64
+			switch v := value.(type) {
+			case int:
+				f = float64(v)
+			case float64:
+				f = v
+			default:
+				return true
+			}
+			return f == 0 || (f >= min && f <= max)
+		},
+	}
+}
+
+// OneOf is a function that returns a rule that fails when the text field is not one of values
+// empty values are left to Required
+func OneOf(field string, values []string, severity string) Rule {
+	return Rule{
+		Field:    field,
+		Severity: severity,
+		Code:     "one_of",
+		Args:     []any{field, strings.Join(values, ", ")},
+		Message:  fmt.Sprintf("%s must be one of %s", field, strings.Join(values, ", ")),
+		Check: func(value any) bool {
+			s, ok := value.(string)
+			if !ok || s == "" {
+				return true
+			}
+			for _, v := range values {
+				if s == v {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// NotZeros is a function that returns a rule that fails when the text field only contains zeros, e.g. "000"
+func NotZeros(field, severity string) Rule {
+	return Rule{
+		Field:    field,
+		Severity: severity,
+		Code:     "not_zeros",
+		Args:     []any{field},
+		Message:  fmt.Sprintf("%s must not be made of zeros only", field),
+		Check: func(value any) bool {
+			s, ok := value.(string)
+			if !ok || s == "" {
+				return true
+			}
+			return strings.Trim(s, "0") != ""
+		},
+	}
+}
+
+// DefaultRules is a function that returns the rules applied to the fleet by default
+// - the identifying fields are required
+// - figures outside of what a road vehicle can have are errors
+// - figures that are possible but unlikely, like a weight of 10 kg, are warnings
+func DefaultRules() []Rule {
+	return []Rule{
+		Required("brand", internal.SeverityError),
+		Required("model", internal.SeverityError),
+		Required("registration", internal.SeverityError),
+		NotZeros("registration", internal.SeverityWarning),
+		Required("year", internal.SeverityError),
+		Range("year", 1886, float64(time.Now().Year()+1), internal.SeverityError),
+		Range("passengers", 1, 100, internal.SeverityError),
+		Required("max_speed", internal.SeverityError),
+		Range("max_speed", 1, 500, internal.SeverityError),
+		Required("fuel_type", internal.SeverityError),
+		OneOf("fuel_type", []string{"gas", "gasoline", "diesel", "biodiesel", "electric", "hybrid"}, internal.SeverityWarning),
+		OneOf("transmission", []string{"manual", "automatic", "semi-automatic"}, internal.SeverityWarning),
+		Required("weight", internal.SeverityWarning),
+		Range("weight", 100, 100000, internal.SeverityWarning),
+		Required("height", internal.SeverityWarning),
+		Range("height", 50, 500, internal.SeverityWarning),
+		Required("length", internal.SeverityWarning),
+		Range("length", 100, 3000, internal.SeverityWarning),
+		Required("width", internal.SeverityWarning),
+		Range("width", 50, 500, internal.SeverityWarning),
+	}
+}
+
+// NewVehicleRules is a function that returns a new instance of VehicleRules
+// it uses DefaultRules when rules is nil
+func NewVehicleRules(rules []Rule) *VehicleRules {
+	if rules == nil {
+		rules = DefaultRules()
+	}
+	return &VehicleRules{rules: rules}
+}
+
+// VehicleRules is a struct that implements the VehicleValidator interface by applying a list of rules
+type VehicleRules struct {
+	// rules is the list of rules applied to each vehicle
+	rules []Rule
+}
+
+// Validate is a method that returns the issues found in a vehicle
+func (vl *VehicleRules) Validate(v internal.Vehicle) (issues []internal.ValidationIssue) {
+	for _, rule := range vl.rules {
+		value, ok := v.Field(rule.Field)
+		if !ok || rule.Check(value) {
+			continue
+		}
+		issues = append(issues, internal.ValidationIssue{
+			Field:    rule.Field,
+			Severity: rule.Severity,
+			Code:     rule.Code,
+			Args:     rule.Args,
+			Message:  rule.Message,
+		})
+	}
+	return
+}
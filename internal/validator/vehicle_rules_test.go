@@ -0,0 +1,58 @@
+package validator
+
+import (
+	"app/internal"
+	"testing"
+)
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  Rule
+		value any
+		ok    bool
+	}{
+		{"required text", Required("brand", internal.SeverityError), "Ford", true},
+		{"required blank text", Required("brand", internal.SeverityError), "  ", false},
+		{"required int", Required("year", internal.SeverityError), 0, false},
+		{"required float", Required("max_speed", internal.SeverityError), 1.5, true},
+		{"range inside", Range("year", 1886, 2030, internal.SeverityError), 1886, true},
+		{"range below", Range("year", 1886, 2030, internal.SeverityError), 1885, false},
+		{"range above", Range("max_speed", 1, 500, internal.SeverityError), 500.5, false},
+		{"range empty left to required", Range("max_speed", 1, 500, internal.SeverityError), 0.0, true},
+		{"one of", OneOf("fuel_type", []string{"gas", "diesel"}, internal.SeverityWarning), "diesel", true},
+		{"one of another", OneOf("fuel_type", []string{"gas", "diesel"}, internal.SeverityWarning), "coal", false},
+		{"one of empty left to required", OneOf("fuel_type", []string{"gas"}, internal.SeverityWarning), "", true},
+		{"not zeros", NotZeros("registration", internal.SeverityWarning), "0102", true},
+		{"zeros", NotZeros("registration", internal.SeverityWarning), "000", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.Check(tt.value); got != tt.ok {
+				t.Errorf("check of %v %v, want %v", tt.value, got, tt.ok)
+			}
+		})
+	}
+}
+
+func TestVehicleRules_Validate(t *testing.T) {
+	v := testFleet()
+
+	if issues := NewVehicleRules(nil).Validate(v[1]); len(issues) != 0 {
+		t.Errorf("issues of a valid vehicle %+v", issues)
+	}
+
+	// the rules given replace the default ones
+	vl := NewVehicleRules([]Rule{Range("max_speed", 1, 100, internal.SeverityWarning)})
+	issues := vl.Validate(v[1])
+	if len(issues) != 1 {
+		t.Fatalf("issues %+v, want 1", issues)
+	}
+	is := issues[0]
+	if is.Field != "max_speed" || is.Code != "range" || is.Severity != internal.SeverityWarning || is.Message != "max_speed must be between 1 and 100" {
+		t.Errorf("issue %+v", is)
+	}
+	if len(is.Args) != 3 || is.Args[0] != "max_speed" {
+		t.Errorf("args %v, want the field first", is.Args)
+	}
+}
@@ -40,6 +40,8 @@ type VehicleAttributes struct {
 type Vehicle struct {
 	// Id is the unique identifier of the vehicle
 	Id int
+	// Version is the number of the last write of the vehicle, it starts at 1 and grows with every write
+	Version int
 
 	// VehicleAttribue is the attributes of a vehicle
 	VehicleAttributes
@@ -54,9 +56,13 @@ type BrandYearRangeSearchType struct {
 type UpdateSpeed struct {
 	Id    int
 	Speed float64
+	// Version is the version the vehicle must be at, any version when 0
+	Version int
 }
 
 type UpdateFuel struct {
 	Id       int
 	FuelType string
+	// Version is the version the vehicle must be at, any version when 0
+	Version int
 }
@@ -0,0 +1,13 @@
+package internal
+
+// BatchResult is a struct that represents the outcome of an item of a batch of vehicles
+type BatchResult struct {
+	// Index is the position of the item in the batch
+	Index int
+	// Created is true when the vehicle of the item was stored
+	Created bool
+	// Vehicle is the vehicle stored, with its id, when Created is true
+	Vehicle Vehicle
+	// Err is the reason the item is invalid, nil for valid items even when they were not stored
+	Err error
+}
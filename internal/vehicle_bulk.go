@@ -0,0 +1,52 @@
+package internal
+
+import "fmt"
+
+// BulkOperation is a struct that represents an operation of a bulk, applied to one vehicle or to the ones of a filter
+type BulkOperation struct {
+	// Type is the type of the operation: OperationCreate, OperationUpdate or OperationDelete
+	Type string
+	// Attributes are the attributes of the vehicle created by a create operation
+	Attributes VehicleAttributes
+	// Id is the id of the vehicle of an update or delete operation without a filter
+	Id int
+	// Version is the version the vehicle of Id must be at, any version when 0
+	Version int
+	// Filter selects the vehicles of an update or delete operation, instead of Id
+	Filter Filter
+	// Update returns the vehicle changed by an update operation
+	Update func(v Vehicle) (Vehicle, error)
+}
+
+// BulkResult is a struct that represents the outcome of a bulk
+type BulkResult struct {
+	// Created are the ids of the vehicles created, in order
+	Created []int
+	// Updated are the ids of the vehicles updated, in order
+	Updated []int
+	// Deleted are the ids of the vehicles deleted, in order
+	Deleted []int
+	// Vehicles are the created and updated vehicles as stored once the bulk is applied
+	Vehicles []Vehicle
+	// Previous are the updated and deleted vehicles as they were before the bulk, so that it can be undone
+	Previous []Vehicle
+}
+
+// OperationError is a struct that represents the error of an operation of a bulk
+// it matches the error of the operation with errors.Is and errors.As
+type OperationError struct {
+	// Index is the position of the operation in the bulk
+	Index int
+	// Err is the error of the operation
+	Err error
+}
+
+// Error is a method that returns the error message
+func (e *OperationError) Error() string {
+	return fmt.Sprintf("operation %d: %v", e.Index, e.Err)
+}
+
+// Unwrap is a method that returns the error of the operation
+func (e *OperationError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,112 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var (
+	// ErrVehicleNotFound is the kind of the errors returned when no vehicle matches a request
+	ErrVehicleNotFound = errors.New("vehicle not found")
+	// ErrVehicleConflict is the kind of the errors returned when a vehicle conflicts with a stored one
+	ErrVehicleConflict = errors.New("vehicle conflict")
+	// ErrVehicleInvalid is the kind of the errors returned when a vehicle has invalid fields, see ValidationError
+	ErrVehicleInvalid = errors.New("vehicle invalid")
+	// ErrInvalidRange is the kind of the errors returned when the lower bound of a range is above the upper bound
+	ErrInvalidRange = errors.New("invalid range")
+	// ErrPreconditionFailed is the kind of the errors returned when a vehicle is not at the version a write expects
+	ErrPreconditionFailed = errors.New("precondition failed")
+)
+
+// Error is a struct that represents an error of the vehicle domain
+// it matches its kind with errors.Is; the text shown to the clients is looked up by code
+// in the message catalog of the handler package, in their language
+type Error struct {
+	// Kind is the sentinel error that classifies the error
+	Kind error
+	// Code identifies the message that describes the error, e.g. "vehicle_not_found"
+	Code string
+	// Args are the values the message is formatted with
+	Args []any
+}
+
+// Error is a method that returns the error message
+func (e *Error) Error() string {
+	if len(e.Args) == 0 {
+		return fmt.Sprintf("%v: %s", e.Kind, e.Code)
+	}
+	return fmt.Sprintf("%v: %s %v", e.Kind, e.Code, e.Args)
+}
+
+// Unwrap is a method that returns the kind of the error
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// NotFound is a function that returns an error of kind ErrVehicleNotFound
+func NotFound(code string, args ...any) error {
+	return &Error{Kind: ErrVehicleNotFound, Code: code, Args: args}
+}
+
+// Conflict is a function that returns an error of kind ErrVehicleConflict
+func Conflict(code string, args ...any) error {
+	return &Error{Kind: ErrVehicleConflict, Code: code, Args: args}
+}
+
+// InvalidRange is a function that returns an error of kind ErrInvalidRange
+func InvalidRange(code string, args ...any) error {
+	return &Error{Kind: ErrInvalidRange, Code: code, Args: args}
+}
+
+// Invalid is a function that returns an error of kind ErrVehicleInvalid, for invalid sets of vehicles
+// the invalid fields of a single vehicle are reported with a ValidationError
+func Invalid(code string, args ...any) error {
+	return &Error{Kind: ErrVehicleInvalid, Code: code, Args: args}
+}
+
+// PreconditionFailed is a function that returns an error of kind ErrPreconditionFailed
+func PreconditionFailed(code string, args ...any) error {
+	return &Error{Kind: ErrPreconditionFailed, Code: code, Args: args}
+}
+
+// ValidationError is a struct that represents an error of kind ErrVehicleInvalid with the details of each field
+type ValidationError struct {
+	// Issues are the issues found in the fields
+	Issues []ValidationIssue
+}
+
+// Error is a method that returns the error message
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Issues))
+	for _, is := range e.Issues {
+		messages = append(messages, is.Message)
+	}
+	return "invalid vehicle: " + strings.Join(messages, "; ")
+}
+
+// Unwrap is a method that returns the kind of the error
+func (e *ValidationError) Unwrap() error {
+	return ErrVehicleInvalid
+}
+
+// Validate is a function that validates a vehicle, returning a ValidationError with the issues
+// of error severity in the given fields, or in every field when none is given
+func Validate(vl VehicleValidator, v Vehicle, fields ...string) (err error) {
+	var issues []ValidationIssue
+	for _, is := range vl.Validate(v) {
+		if is.Severity != SeverityError {
+			continue
+		}
+		if len(fields) > 0 && !slices.Contains(fields, is.Field) {
+			continue
+		}
+		issues = append(issues, is)
+	}
+
+	if len(issues) > 0 {
+		err = &ValidationError{Issues: issues}
+	}
+	return
+}
@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestError_Is(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		kind error
+	}{
+		{"not found", NotFound("vehicle_not_found"), ErrVehicleNotFound},
+		{"conflict", Conflict("vehicle_id_conflict"), ErrVehicleConflict},
+		{"invalid", Invalid("batch_invalid", 2), ErrVehicleInvalid},
+		{"invalid range", InvalidRange("invalid_weight_range"), ErrInvalidRange},
+		{"precondition failed", PreconditionFailed("version_mismatch", 3), ErrPreconditionFailed},
+		{"validation", &ValidationError{}, ErrVehicleInvalid},
+		{"wrapped", fmt.Errorf("create: %w", NotFound("vehicle_not_found")), ErrVehicleNotFound},
+		{"of an operation", &OperationError{Index: 1, Err: Conflict("vehicle_id_conflict")}, ErrVehicleConflict},
+	}
+	kinds := []error{ErrVehicleNotFound, ErrVehicleConflict, ErrVehicleInvalid, ErrInvalidRange, ErrPreconditionFailed}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// an error has a single kind
+			for _, kind := range kinds {
+				if got := errors.Is(tt.err, kind); got != (kind == tt.kind) {
+					t.Errorf("errors.Is(%v, %v) = %v", tt.err, kind, got)
+				}
+			}
+		})
+	}
+}
+
+func TestError_Error(t *testing.T) {
+	if got := NotFound("vehicle_not_found").Error(); got != "vehicle not found: vehicle_not_found" {
+		t.Errorf("got %q", got)
+	}
+	if got := PreconditionFailed("version_mismatch", 3).Error(); got != "precondition failed: version_mismatch [3]" {
+		t.Errorf("got %q", got)
+	}
+
+	var e *Error
+	if !errors.As(fmt.Errorf("wrapped: %w", Invalid("batch_invalid", 2)), &e) || e.Code != "batch_invalid" || e.Args[0] != 2 {
+		t.Errorf("code and args lost: %+v", e)
+	}
+}
+
+// validatorFunc is a validator that returns the issues of a function
+type validatorFunc func(v Vehicle) []ValidationIssue
+
+func (f validatorFunc) Validate(v Vehicle) []ValidationIssue {
+	return f(v)
+}
+
+func TestValidate(t *testing.T) {
+	vl := validatorFunc(func(v Vehicle) []ValidationIssue {
+		return []ValidationIssue{
+			{Field: "brand", Severity: SeverityError, Code: "required", Message: "brand is required"},
+			{Field: "weight", Severity: SeverityWarning, Code: "range", Message: "weight must be between 100 and 100000"},
+			{Field: "max_speed", Severity: SeverityError, Code: "range", Message: "max_speed must be between 1 and 500"},
+		}
+	})
+
+	tests := []struct {
+		name   string
+		fields []string
+		want   []string
+	}{
+		{"every field", nil, []string{"brand", "max_speed"}},
+		{"some fields", []string{"max_speed", "weight"}, []string{"max_speed"}},
+		{"fields without errors", []string{"weight", "color"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(vl, Vehicle{}, tt.fields...)
+
+			// warnings never fail a validation
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("error %v, want none", err)
+				}
+				return
+			}
+			var ve *ValidationError
+			if !errors.As(err, &ve) || !errors.Is(err, ErrVehicleInvalid) {
+				t.Fatalf("error %v, want a ValidationError", err)
+			}
+			var got []string
+			for _, is := range ve.Issues {
+				got = append(got, is.Field)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("fields %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
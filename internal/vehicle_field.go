@@ -0,0 +1,43 @@
+package internal
+
+// VehicleFields are the names of the fields of a vehicle, as in its JSON representation
+var VehicleFields = []string{
+	"id", "brand", "model", "registration", "color", "year", "passengers",
+	"max_speed", "fuel_type", "transmission", "weight", "height", "length", "width",
+}
+
+// Field is a method that returns the value of the field of the vehicle with the given name,
+// as a string, an int or a float64; ok is false when there is no field with that name
+func (v Vehicle) Field(name string) (value any, ok bool) {
+	switch name {
+	case "id":
+		return v.Id, true
+	case "brand":
+		return v.Brand, true
+	case "model":
+		return v.Model, true
+	case "registration":
+		return v.Registration, true
+	case "color":
+		return v.Color, true
+	case "year":
+		return v.FabricationYear, true
+	case "passengers":
+		return v.Capacity, true
+	case "max_speed":
+		return v.MaxSpeed, true
+	case "fuel_type":
+		return v.FuelType, true
+	case "transmission":
+		return v.Transmission, true
+	case "weight":
+		return v.Weight, true
+	case "height":
+		return v.Height, true
+	case "length":
+		return v.Length, true
+	case "width":
+		return v.Width, true
+	}
+	return nil, false
+}
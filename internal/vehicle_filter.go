@@ -0,0 +1,186 @@
+package internal
+
+import (
+	"errors"
+	"strconv"
+)
+
+const (
+	// OperatorEq matches the vehicles whose field is equal to the value
+	OperatorEq = "eq"
+	// OperatorNe matches the vehicles whose field is not equal to the value
+	OperatorNe = "ne"
+	// OperatorLt matches the vehicles whose field is less than the value
+	OperatorLt = "lt"
+	// OperatorLte matches the vehicles whose field is less than or equal to the value
+	OperatorLte = "lte"
+	// OperatorGt matches the vehicles whose field is greater than the value
+	OperatorGt = "gt"
+	// OperatorGte matches the vehicles whose field is greater than or equal to the value
+	OperatorGte = "gte"
+	// OperatorIn matches the vehicles whose field is equal to any of the values
+	OperatorIn = "in"
+)
+
+var (
+	// ErrUnknownField is the error returned when a filter refers to a field a vehicle does not have
+	ErrUnknownField = errors.New("unknown field")
+	// ErrUnknownOperator is the error returned when a filter uses an operator that does not exist
+	ErrUnknownOperator = errors.New("unknown operator")
+	// ErrInvalidValue is the error returned when the value of a filter does not suit the type of its field
+	ErrInvalidValue = errors.New("invalid value")
+)
+
+// Filter is an interface that represents a node of a filter over vehicles
+type Filter interface {
+	// Match is a method that returns true when the vehicle satisfies the filter
+	Match(v Vehicle) bool
+}
+
+// And is a filter that matches the vehicles that satisfy every one of its filters
+// an empty And matches every vehicle
+type And []Filter
+
+// Match is a method that returns true when the vehicle satisfies every filter
+func (f And) Match(v Vehicle) bool {
+	for _, sub := range f {
+		if !sub.Match(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// Condition is a filter that compares a field of the vehicles with one or more values
+type Condition struct {
+	// Field is the name of the field, as in VehicleFields
+	Field string
+	// Operator is the comparison, one of the Operator constants
+	Operator string
+	// Values are the values the field is compared with, only OperatorIn takes more than one
+	// strings are compared with strings and numbers with numbers
+	Values []any
+}
+
+// NewCondition is a function that returns a condition on a field, with its values parsed from text
+// according to the type of the field
+func NewCondition(field, operator string, values ...string) (c Condition, err error) {
+	kind, ok := Vehicle{}.Field(field)
+	if !ok {
+		return Condition{}, ErrUnknownField
+	}
+
+	switch operator {
+	case OperatorEq, OperatorNe, OperatorLt, OperatorLte, OperatorGt, OperatorGte:
+		if len(values) != 1 {
+			return Condition{}, ErrInvalidValue
+		}
+	case OperatorIn:
+		if len(values) == 0 {
+			return Condition{}, ErrInvalidValue
+		}
+	default:
+		return Condition{}, ErrUnknownOperator
+	}
+
+	c = Condition{Field: field, Operator: operator}
+	for _, value := range values {
+		switch kind.(type) {
+		case string:
+			c.Values = append(c.Values, value)
+		default:
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return Condition{}, ErrInvalidValue
+			}
+			c.Values = append(c.Values, f)
+		}
+	}
+	return c, nil
+}
+
+// Match is a method that returns true when the field of the vehicle satisfies the condition
+func (c Condition) Match(v Vehicle) bool {
+	field, ok := v.Field(c.Field)
+	if !ok {
+		return false
+	}
+
+	switch c.Operator {
+	case OperatorIn:
+		for _, value := range c.Values {
+			if cmp, ok := compare(field, value); ok && cmp == 0 {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(c.Values) != 1 {
+		return false
+	}
+	cmp, ok := compare(field, c.Values[0])
+	if !ok {
+		return false
+	}
+	switch c.Operator {
+	case OperatorEq:
+		return cmp == 0
+	case OperatorNe:
+		return cmp != 0
+	case OperatorLt:
+		return cmp < 0
+	case OperatorLte:
+		return cmp <= 0
+	case OperatorGt:
+		return cmp > 0
+	case OperatorGte:
+		return cmp >= 0
+	}
+	return false
+}
+
+// compare is a function that returns -1, 0 or 1 as a is less than, equal to or greater than b
+// ok is false when a and b are not both strings or both numbers
+func compare(a, b any) (cmp int, ok bool) {
+	if sa, isString := a.(string); isString {
+		sb, isString := b.(string)
+		if !isString {
+			return 0, false
+		}
+		switch {
+		case sa < sb:
+			return -1, true
+		case sa > sb:
+			return 1, true
+		}
+		return 0, true
+	}
+
+	fa, ok := number(a)
+	if !ok {
+		return 0, false
+	}
+	fb, ok := number(b)
+	if !ok {
+		return 0, false
+	}
+	switch {
+	case fa < fb:
+		return -1, true
+	case fa > fb:
+		return 1, true
+	}
+	return 0, true
+}
+
+// number is a function that returns the value of an int or a float64 as a float64
+func number(v any) (f float64, ok bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
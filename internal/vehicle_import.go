@@ -0,0 +1,64 @@
+package internal
+
+import (
+	"io"
+	"time"
+)
+
+const (
+	// ImportPending is the status of an import job not started yet
+	ImportPending = "pending"
+	// ImportRunning is the status of an import job creating its vehicles
+	ImportRunning = "running"
+	// ImportCompleted is the status of an import job that went through all its vehicles
+	ImportCompleted = "completed"
+	// ImportFailed is the status of an import job stopped by an error, see ImportJob.Err
+	ImportFailed = "failed"
+	// ImportCanceled is the status of an import job canceled before it completed
+	ImportCanceled = "canceled"
+)
+
+// ImportError is a struct that represents a vehicle of an import that could not be created
+type ImportError struct {
+	// Row is the position of the vehicle in the file, starting at 1; 0 when it could not be decoded
+	Row int
+	// Line is the line of the file that could not be decoded, when known
+	Line int
+	// Err is the reason, a ValidationError for invalid vehicles
+	Err error
+}
+
+// ImportJob is a struct that represents an import of vehicles from a file, run in the background
+type ImportJob struct {
+	// Id is the unique identifier of the job
+	Id int
+	// Format is the format of the file, its extension without the dot, e.g. csv
+	Format string
+	// Status is the status of the job, one of the Import constants
+	Status string
+	// Processed is the number of vehicles of the file gone through so far
+	Processed int
+	// Created is the number of vehicles created so far
+	Created int
+	// Failed is the number of vehicles that could not be created so far
+	Failed int
+	// Errors are the vehicles that could not be created, only the first ones are kept
+	Errors []ImportError
+	// Err is the error that stopped a failed job
+	Err error
+	// CreatedAt is the moment the job was created
+	CreatedAt time.Time
+	// FinishedAt is the moment the job completed, failed or was canceled
+	FinishedAt time.Time
+}
+
+// VehicleImporter is an interface that represents the runner of import jobs
+type VehicleImporter interface {
+	// Start is a method that reads a file in the given format from rd and imports its vehicles in the background
+	// the file is fully read before it returns
+	Start(rd io.Reader, format string) (job ImportJob, err error)
+	// FindById is a method that returns the current state of a job
+	FindById(id int) (job ImportJob, err error)
+	// Cancel is a method that cancels a job not finished yet; the vehicles already created are kept
+	Cancel(id int) (job ImportJob, err error)
+}
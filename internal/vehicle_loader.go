@@ -4,4 +4,11 @@ package internal
 type VehicleLoader interface {
 	// Load is a method that loads the vehicles
 	Load() (v map[int]Vehicle, err error)
-}
\ No newline at end of file
+}
+
+// VehicleStreamer is an interface that represents a loader that streams the vehicles one at a time
+// so they never need to be held in memory all together
+type VehicleStreamer interface {
+	// Stream is a method that calls fn for each vehicle loaded, stopping at the first error returned by fn
+	Stream(fn func(v Vehicle) (err error)) (err error)
+}
@@ -0,0 +1,36 @@
+package internal
+
+const (
+	// OperationCreate is the operation logged by Create and CreateSome
+	OperationCreate = "create"
+	// OperationUpdate is the operation logged by UpdateSpeed and UpdateFuelType
+	OperationUpdate = "update"
+	// OperationDelete is the operation logged by DeleteById
+	OperationDelete = "delete"
+	// OperationBulk is the operation logged by Bulk, it both updates and deletes vehicles
+	OperationBulk = "bulk"
+)
+
+// VehicleOperation is a struct that represents a mutation applied to the vehicles
+type VehicleOperation struct {
+	// Type is the type of the operation
+	Type string
+	// Vehicles are the vehicles as stored after a create, update or bulk operation
+	Vehicles []Vehicle
+	// Id is the id of the vehicle removed by a delete operation
+	Id int
+	// Ids are the ids of the vehicles removed by a bulk operation
+	Ids []int
+}
+
+// VehicleLogger is an interface that represents an append-only log of vehicle operations
+type VehicleLogger interface {
+	// Append is a method that durably appends an operation to the log
+	Append(op VehicleOperation) (err error)
+	// Replay is a method that applies the logged operations on top of the vehicles
+	Replay(v map[int]Vehicle) (err error)
+	// Size is a method that returns the size of the log in bytes
+	Size() (n int64, err error)
+	// Truncate is a method that discards every logged operation
+	Truncate() (err error)
+}
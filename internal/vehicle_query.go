@@ -0,0 +1,136 @@
+package internal
+
+import "sort"
+
+// SortKey is a struct that represents a field the vehicles are sorted by
+type SortKey struct {
+	// Field is the name of the field, as in VehicleFields
+	Field string
+	// Descending sorts from the greatest to the least value
+	Descending bool
+}
+
+// Cursor is a struct that represents a position in a sorted list of vehicles, the one of a vehicle
+type Cursor struct {
+	// Values are the values of the sort keys of the vehicle, in order
+	Values []any
+	// Id is the id of the vehicle, which breaks the ties between equal values
+	Id int
+	// Backward asks for the vehicles before the position instead of the ones after it
+	Backward bool
+}
+
+// VehicleQuery is a struct that represents a request for a page of vehicles
+type VehicleQuery struct {
+	// Filter selects the vehicles, all of them when nil
+	Filter Filter
+	// Sort are the keys the vehicles are sorted by, the id always breaks the ties
+	Sort []SortKey
+	// Limit is the maximum number of vehicles of the page, no limit when 0
+	Limit int
+	// Offset is the number of vehicles skipped, ignored when there is a cursor
+	Offset int
+	// Cursor is the position the page starts after, or ends before when backward
+	Cursor *Cursor
+}
+
+// VehiclePage is a struct that represents a page of vehicles
+type VehiclePage struct {
+	// Vehicles are the vehicles of the page, sorted
+	Vehicles []Vehicle
+	// Total is the number of vehicles that match the filter, in every page
+	Total int
+	// Offset is the position of the first vehicle of the page among all of them
+	Offset int
+	// Next is the cursor of the following page, nil on the last page
+	Next *Cursor
+	// Prev is the cursor of the preceding page, nil on the first page
+	Prev *Cursor
+}
+
+// Paginate is a function that sorts the vehicles that match a query and returns the page it asks for
+// it sorts vs in place
+func Paginate(vs []Vehicle, q VehicleQuery) (p VehiclePage) {
+	sort.SliceStable(vs, func(i, j int) bool {
+		return q.Less(vs[i], vs[j])
+	})
+
+	total := len(vs)
+	start, end := q.Offset, total
+	backward := q.Cursor != nil && q.Cursor.Backward
+	switch {
+	case q.Cursor != nil && !backward:
+		start = sort.Search(total, func(i int) bool {
+			return q.compare(vs[i], *q.Cursor) > 0
+		})
+	case backward:
+		// the page ends right before the cursor
+		end = sort.Search(total, func(i int) bool {
+			return q.compare(vs[i], *q.Cursor) >= 0
+		})
+		start = 0
+		if q.Limit > 0 && end > q.Limit {
+			start = end - q.Limit
+		}
+	}
+	start = min(max(start, 0), total)
+	if !backward && q.Limit > 0 && start+q.Limit < end {
+		end = start + q.Limit
+	}
+
+	p = VehiclePage{
+		Vehicles: vs[start:end],
+		Total:    total,
+		Offset:   start,
+	}
+	if end < total && end > start {
+		next := q.key(vs[end-1])
+		p.Next = &next
+	}
+	if start > 0 && start < total {
+		prev := q.key(vs[start])
+		prev.Backward = true
+		p.Prev = &prev
+	}
+	return
+}
+
+// Less is a method that reports whether v sorts before w in the order the query asks for
+func (q VehicleQuery) Less(v, w Vehicle) bool {
+	return q.compare(v, q.key(w)) < 0
+}
+
+// key is a method that returns the cursor at the position of a vehicle
+func (q VehicleQuery) key(v Vehicle) (c Cursor) {
+	c = Cursor{Id: v.Id}
+	for _, sk := range q.Sort {
+		value, _ := v.Field(sk.Field)
+		c.Values = append(c.Values, value)
+	}
+	return
+}
+
+// compare is a method that returns -1, 0 or 1 as the vehicle sorts before, at or after the cursor
+func (q VehicleQuery) compare(v Vehicle, c Cursor) int {
+	for i, sk := range q.Sort {
+		if i >= len(c.Values) {
+			break
+		}
+		value, _ := v.Field(sk.Field)
+		cmp, _ := compare(value, c.Values[i])
+		if sk.Descending {
+			cmp = -cmp
+		}
+		if cmp != 0 {
+			return cmp
+		}
+	}
+
+	switch {
+	case v.Id < c.Id:
+		return -1
+	case v.Id > c.Id:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"slices"
+	"testing"
+)
+
+// testVehicles is a function that returns vehicles of ids 1 to 10 whose speeds repeat every 3 ids
+func testVehicles() []Vehicle {
+	var vs []Vehicle
+	for id := 10; id >= 1; id-- {
+		vs = append(vs, Vehicle{Id: id, VehicleAttributes: VehicleAttributes{MaxSpeed: float64(100 + id%3)}})
+	}
+	return vs
+}
+
+// ids is a function that returns the ids of the vehicles of a page, in order
+func ids(p VehiclePage) (v []int) {
+	for _, vh := range p.Vehicles {
+		v = append(v, vh.Id)
+	}
+	return
+}
+
+func TestPaginate(t *testing.T) {
+	bySpeed := []SortKey{{Field: "max_speed", Descending: true}}
+	// sorted by descending speed, ties broken by id: 2 5 8 | 1 4 7 10 | 3 6 9
+
+	tests := []struct {
+		name       string
+		q          VehicleQuery
+		want       []int
+		offset     int
+		next, prev bool
+	}{
+		{"every vehicle by id", VehicleQuery{}, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 0, false, false},
+		{"first page", VehicleQuery{Sort: bySpeed, Limit: 4}, []int{2, 5, 8, 1}, 0, true, false},
+		{"offset", VehicleQuery{Sort: bySpeed, Limit: 4, Offset: 4}, []int{4, 7, 10, 3}, 4, true, true},
+		{"last page by offset", VehicleQuery{Sort: bySpeed, Limit: 4, Offset: 8}, []int{6, 9}, 8, false, true},
+		{"offset beyond the end", VehicleQuery{Limit: 4, Offset: 20}, nil, 10, false, false},
+		{"after a cursor", VehicleQuery{Sort: bySpeed, Limit: 3, Cursor: &Cursor{Values: []any{101.0}, Id: 1}}, []int{4, 7, 10}, 4, true, true},
+		{"after a cursor between two vehicles", VehicleQuery{Sort: bySpeed, Limit: 3, Cursor: &Cursor{Values: []any{101.0}, Id: 5}}, []int{7, 10, 3}, 5, true, true},
+		{"after the last vehicle", VehicleQuery{Sort: bySpeed, Cursor: &Cursor{Values: []any{100.0}, Id: 9}}, nil, 10, false, false},
+		{"before a cursor", VehicleQuery{Sort: bySpeed, Limit: 3, Cursor: &Cursor{Values: []any{100.0}, Id: 3, Backward: true}}, []int{4, 7, 10}, 4, true, true},
+		{"before a cursor near the start", VehicleQuery{Sort: bySpeed, Limit: 3, Cursor: &Cursor{Values: []any{102.0}, Id: 8, Backward: true}}, []int{2, 5}, 0, true, false},
+		{"before the first vehicle", VehicleQuery{Sort: bySpeed, Limit: 3, Cursor: &Cursor{Values: []any{102.0}, Id: 2, Backward: true}}, nil, 0, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Paginate(testVehicles(), tt.q)
+
+			if got := ids(p); !slices.Equal(got, tt.want) {
+				t.Errorf("ids %v, want %v", got, tt.want)
+			}
+			if p.Total != 10 || p.Offset != tt.offset {
+				t.Errorf("total %d offset %d, want 10 and %d", p.Total, p.Offset, tt.offset)
+			}
+			if (p.Next != nil) != tt.next || (p.Prev != nil) != tt.prev {
+				t.Errorf("next %v prev %v, want %v and %v", p.Next != nil, p.Prev != nil, tt.next, tt.prev)
+			}
+		})
+	}
+}
+
+// TestPaginate_Cursors walks the vehicles forward and back through the cursors of the pages
+func TestPaginate_Cursors(t *testing.T) {
+	q := VehicleQuery{Sort: []SortKey{{Field: "max_speed", Descending: true}}, Limit: 3}
+
+	var forward []int
+	var pages []VehiclePage
+	for cursor := (*Cursor)(nil); ; {
+		q.Cursor = cursor
+		p := Paginate(testVehicles(), q)
+		pages = append(pages, p)
+		forward = append(forward, ids(p)...)
+		if p.Next == nil {
+			break
+		}
+		cursor = p.Next
+	}
+	if want := []int{2, 5, 8, 1, 4, 7, 10, 3, 6, 9}; !slices.Equal(forward, want) {
+		t.Fatalf("forward %v, want %v", forward, want)
+	}
+
+	// the previous cursor of each page leads back to the page before it
+	for i := len(pages) - 1; i > 0; i-- {
+		if pages[i].Prev == nil {
+			t.Fatalf("page %d has no previous cursor", i)
+		}
+		q.Cursor = pages[i].Prev
+		p := Paginate(testVehicles(), q)
+		if got, want := ids(p), ids(pages[i-1]); !slices.Equal(got, want) {
+			t.Errorf("page before %d: %v, want %v", i, got, want)
+		}
+	}
+	if pages[0].Prev != nil {
+		t.Errorf("first page has a previous cursor")
+	}
+}
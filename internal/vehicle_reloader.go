@@ -0,0 +1,7 @@
+package internal
+
+// VehicleReloader is an interface that represents a component that reloads the vehicles from their source
+type VehicleReloader interface {
+	// Reload is a method that reloads the vehicles, returning how many were loaded
+	Reload() (n int, err error)
+}
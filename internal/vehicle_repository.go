@@ -4,17 +4,34 @@ package internal
 type VehicleRepository interface {
 	// FindAll is a method that returns a map of all vehicles
 	FindAll() (v map[int]Vehicle, err error)
-	FindByColorAndYear(vehicle VehicleAttributes) (v map[int]Vehicle, err error)
-	FindByBrandAndYearInterval(r BrandYearRangeSearchType) (v map[int]Vehicle, err error)
+	// FindById is a method that returns the vehicle with the given id
+	FindById(id int) (vh Vehicle, err error)
+	// Find is a method that returns the page of the vehicles that match the query, sorted as it asks
+	Find(q VehicleQuery) (p VehiclePage, err error)
+	// Stream is a method that calls fn for each vehicle that matches q.Filter, sorted as q asks, the page aside
+	// the vehicles are read one at a time, so one changed meanwhile is seen as it is then and one deleted is skipped
+	Stream(q VehicleQuery, fn func(v Vehicle) (err error)) (err error)
 	GetAverageSpeedByBrand(b string) (v float64, err error)
-	Create(v VehicleAttributes) (err error)
-	CreateSome(vs []VehicleAttributes) (err error)
-	UpdateSpeed(v UpdateSpeed) (err error)
-	GetByFuelType(t string) (v map[int]Vehicle, err error)
-	DeleteById(id int) (err error)
-	GetByTransmissionType(t string) (v map[int]Vehicle, err error)
-	UpdateFuelType(u UpdateFuel) (err error)
+	Create(v VehicleAttributes) (vh Vehicle, err error)
+	CreateSome(vs []VehicleAttributes) (vhs []Vehicle, err error)
+	UpdateSpeed(v UpdateSpeed) (vh Vehicle, err error)
+	// Replace is a method that replaces every attribute of an existing vehicle
+	// when v.Version is not 0 the vehicle must be at that version, see ErrPreconditionFailed
+	Replace(v Vehicle) (vh Vehicle, err error)
+	// Update is a method that replaces a vehicle by the result of fn on it, atomically
+	// nothing changes when fn returns an error, which is returned as is;
+	// when version is not 0 the vehicle must be at that version
+	Update(id, version int, fn func(v Vehicle) (Vehicle, error)) (vh Vehicle, err error)
+	// DeleteById is a method that deletes a vehicle, when version is not 0 the vehicle must be at that version
+	DeleteById(id, version int) (err error)
+	UpdateFuelType(u UpdateFuel) (vh Vehicle, err error)
 	GetAverageCapacityByBrand(b string) (v float64, err error)
-	GetByDimensions(minLength, maxLength, minWidth, maxWidth float64) (v map[int]Vehicle, err error)
-	GetByWeight(minW, maxW float64) (v map[int]Vehicle, err error)
+	// Bulk is a method that applies the operations in order and atomically: either all of them or none
+	// the error of an operation is an OperationError
+	Bulk(ops []BulkOperation) (rs BulkResult, err error)
+	// Reset is a method that replaces every vehicle of the repository at once
+	Reset(v map[int]Vehicle) (err error)
+	// Restore is a method that puts back the vehicles exactly as given, versions included, and deletes the ones of ids
+	// it undoes a write whose outcome could not be persisted
+	Restore(vs []Vehicle, ids []int) (err error)
 }
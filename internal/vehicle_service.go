@@ -4,17 +4,36 @@ package internal
 type VehicleService interface {
 	// FindAll is a method that returns a map of all vehicles
 	FindAll() (v map[int]Vehicle, err error)
-	Create(newVehicle VehicleAttributes) (err error)
-	FindByColorAndYear(vehicle VehicleAttributes) (v map[int]Vehicle, err error)
-	FindByBrandAndYearInterval(r BrandYearRangeSearchType) (v map[int]Vehicle, err error)
+	// FindById is a method that returns the vehicle with the given id
+	FindById(id int) (vh Vehicle, err error)
+	// Find is a method that returns the page of the vehicles that match the query, sorted as it asks
+	Find(q VehicleQuery) (p VehiclePage, err error)
+	// Stream is a method that calls fn for each vehicle that matches q.Filter, sorted as q asks, the page aside
+	Stream(q VehicleQuery, fn func(v Vehicle) (err error)) (err error)
+	Create(newVehicle VehicleAttributes) (vh Vehicle, err error)
+	// the searches below return the page of their vehicles that q asks for, q.Filter is replaced
+	FindByColorAndYear(vehicle VehicleAttributes, q VehicleQuery) (p VehiclePage, err error)
+	FindByBrandAndYearInterval(r BrandYearRangeSearchType, q VehicleQuery) (p VehiclePage, err error)
 	GetAverageSpeedByBrand(b string) (v float64, err error)
-	CreateSome(vs []VehicleAttributes) (err error)
-	UpdateSpeed(v UpdateSpeed) (err error)
-	GetByFuelType(t string) (v map[int]Vehicle, err error)
-	DeleteById(id int) (err error)
-	GetByTransmissionType(t string) (v map[int]Vehicle, err error)
-	UpdateFuelType(u UpdateFuel) (err error)
+	// CreateSome is a method that validates every vehicle of a batch and stores the valid ones in a single write
+	// with partial false nothing is stored unless every vehicle is valid, and the error is of kind ErrVehicleInvalid;
+	// the results tell the outcome of every vehicle, in order, in both cases
+	CreateSome(vs []VehicleAttributes, partial bool) (results []BatchResult, err error)
+	UpdateSpeed(v UpdateSpeed) (vh Vehicle, err error)
+	// Replace is a method that replaces every attribute of an existing vehicle, once validated
+	// when v.Version is not 0 the vehicle must be at that version
+	Replace(v Vehicle) (vh Vehicle, err error)
+	// Update is a method that replaces a vehicle by the result of fn on it, atomically and once validated
+	// when version is not 0 the vehicle must be at that version
+	Update(id, version int, fn func(v Vehicle) (Vehicle, error)) (vh Vehicle, err error)
+	GetByFuelType(t string, q VehicleQuery) (p VehiclePage, err error)
+	// DeleteById is a method that deletes a vehicle, when version is not 0 the vehicle must be at that version
+	DeleteById(id, version int) (err error)
+	GetByTransmissionType(t string, q VehicleQuery) (p VehiclePage, err error)
+	UpdateFuelType(u UpdateFuel) (vh Vehicle, err error)
+	// Bulk is a method that applies the operations in order and atomically, once the vehicles they leave are validated
+	Bulk(ops []BulkOperation) (rs BulkResult, err error)
 	GetAverageCapacityByBrand(b string) (v float64, err error)
-	GetByDimensions(minLength, maxLength, minWidth, maxWidth float64) (v map[int]Vehicle, err error)
-	GetByWeight(minW, maxW float64) (v map[int]Vehicle, err error)
+	GetByDimensions(minLength, maxLength, minWidth, maxWidth float64, q VehicleQuery) (p VehiclePage, err error)
+	GetByWeight(minW, maxW float64, q VehicleQuery) (p VehiclePage, err error)
 }
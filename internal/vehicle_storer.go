@@ -0,0 +1,15 @@
+package internal
+
+// VehicleStorer is an interface that represents the storer for vehicles
+// it is the counterpart of VehicleLoader
+type VehicleStorer interface {
+	// Store is a method that stores the vehicles
+	Store(v map[int]Vehicle) (err error)
+}
+
+// VehicleSnapshotter is an interface that represents a repository that stores its own vehicles
+// the snapshot is taken with no write in progress, so it never misses a write it reports as done
+type VehicleSnapshotter interface {
+	// Snapshot is a method that stores the current vehicles
+	Snapshot() (err error)
+}
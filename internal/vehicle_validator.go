@@ -0,0 +1,28 @@
+package internal
+
+const (
+	// SeverityError marks an issue that makes a vehicle invalid
+	SeverityError = "error"
+	// SeverityWarning marks an issue that makes a vehicle suspicious but still valid
+	SeverityWarning = "warning"
+)
+
+// ValidationIssue is a struct that represents a problem found in a field of a vehicle
+type ValidationIssue struct {
+	// Field is the name of the field, as in VehicleFields
+	Field string
+	// Severity is the severity of the issue
+	Severity string
+	// Code identifies the rule that was broken, e.g. "required"
+	Code string
+	// Args are the values the message of the code is formatted with, starting with the field
+	Args []any
+	// Message describes the issue, in english
+	Message string
+}
+
+// VehicleValidator is an interface that represents a validator for vehicles
+type VehicleValidator interface {
+	// Validate is a method that returns the issues found in a vehicle
+	Validate(v Vehicle) (issues []ValidationIssue)
+}